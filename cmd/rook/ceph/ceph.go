@@ -58,7 +58,8 @@ func init() {
 	Cmd.AddCommand(operatorCmd,
 		agentCmd,
 		osdCmd,
-		configCmd)
+		configCmd,
+		monCmd)
 }
 
 func createContext() *clusterd.Context {
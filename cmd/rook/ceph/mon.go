@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var monCmd = &cobra.Command{
+	Use:   "mon",
+	Short: "Commands to inspect the Ceph mons of a cluster",
+}
+
+var monPlacementCmd = &cobra.Command{
+	Use:   "placement",
+	Short: "Prints the node, zone, address and quorum state of each mon",
+	Long: `Prints a table with the node, zone, address and quorum state of each mon of the
+cluster in the namespace. The mons are read from the mon config saved by the operator,
+so the command can be run from the operator pod or from outside of the cluster.`,
+}
+
+var monClusterNamespace string
+
+func init() {
+	monPlacementCmd.Flags().StringVar(&monClusterNamespace, "namespace", "", "the namespace of the cluster")
+	monPlacementCmd.MarkFlagRequired("namespace")
+	monPlacementCmd.Flags().StringVar(&cfg.dataDir, "config-dir", "/var/lib/rook", "directory for storing configuration")
+	flags.SetFlagsFromEnv(monPlacementCmd.Flags(), rook.RookEnvVarPrefix)
+	monPlacementCmd.RunE = printMonPlacement
+
+	monCmd.AddCommand(monPlacementCmd)
+}
+
+func printMonPlacement(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+
+	report, err := mon.LoadMonPlacementReport(createContext(), monClusterNamespace)
+	if err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to get the mon placement. %+v", err))
+	}
+	fmt.Print(report)
+	return nil
+}
@@ -45,6 +45,7 @@ func init() {
 
 	operatorCmd.Flags().BoolVar(&operator.EnableFlexDriver, "enable-flex-driver", true, "enable the rook flex driver")
 	operatorCmd.Flags().BoolVar(&operator.EnableDiscoveryDaemon, "enable-discovery-daemon", true, "enable the rook discovery daemon")
+	operatorCmd.Flags().IntVar(&operator.MetricsPort, "metrics-port", operator.MetricsPort, "port to serve the prometheus metrics of the operator on, or 0 to not serve the metrics")
 
 	operatorCmd.Flags().BoolVar(&csi.EnableRBD, "csi-enable-rbd", true, "enable ceph-csi rbd support")
 	operatorCmd.Flags().BoolVar(&csi.EnableCephFS, "csi-enable-cephfs", true, "enable ceph-csi cephfs support")
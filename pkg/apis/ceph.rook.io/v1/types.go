@@ -72,6 +72,10 @@ type ClusterSpec struct {
 	// A spec for mon related options
 	Mon MonSpec `json:"mon,omitempty"`
 
+	// The maximum number of PGs per OSD that the mons allow (mon_max_pg_per_osd). If not set, the
+	// ceph default is used.
+	MaxPGPerOSD int `json:"maxPGPerOSD,omitempty"`
+
 	// A spec for rbd mirroring
 	RBDMirroring RBDMirroringSpec `json:"rbdMirroring"`
 
@@ -122,6 +126,10 @@ type ClusterStatus struct {
 	State      ClusterState `json:"state,omitempty"`
 	Message    string       `json:"message,omitempty"`
 	CephStatus *CephStatus  `json:"ceph,omitempty"`
+	// MonHealth summarizes the health of the mons as seen by the operator
+	MonHealth *MonHealthCondition `json:"monHealth,omitempty"`
+	// MonZoneCountSufficient reports whether the mons span enough zones to survive a zone outage
+	MonZoneCountSufficient *MonZoneCountCondition `json:"monZoneCountSufficient,omitempty"`
 }
 
 type CephStatus struct {
@@ -148,11 +156,117 @@ const (
 	ClusterStateError      ClusterState = "Error"
 )
 
+// MonHealthCondition is the health of the mons as seen by the operator
+type MonHealthCondition struct {
+	Status      MonHealthStatus `json:"status"`
+	Message     string          `json:"message,omitempty"`
+	LastChanged string          `json:"lastChanged,omitempty"`
+}
+
+// MonZoneCountCondition is the number of failure domain zones the mons are spread across. The zone
+// count is sufficient when the mons are in at least two zones.
+type MonZoneCountCondition struct {
+	Sufficient  bool   `json:"sufficient"`
+	Zones       int    `json:"zones"`
+	Message     string `json:"message,omitempty"`
+	LastChanged string `json:"lastChanged,omitempty"`
+}
+
+// MonHealthStatus is the overall state of the mons
+type MonHealthStatus string
+
+const (
+	// MonHealthy means all the desired mons are in quorum
+	MonHealthy MonHealthStatus = "Healthy"
+	// MonDegraded means the mons have quorum but some mons are missing, out of quorum or degraded
+	MonDegraded MonHealthStatus = "Degraded"
+	// MonUnavailable means the mons have no quorum
+	MonUnavailable MonHealthStatus = "Unavailable"
+)
+
 type MonSpec struct {
 	Count                int                       `json:"count,omitempty"`
 	PreferredCount       int                       `json:"preferredCount,omitempty"`
 	AllowMultiplePerNode bool                      `json:"allowMultiplePerNode,omitempty"`
 	VolumeClaimTemplate  *v1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+	// ShuffleEndpoints randomizes the order of the mon endpoints saved in the endpoint configmap so
+	// that clients do not all connect to the same mon first
+	ShuffleEndpoints bool `json:"shuffleEndpoints,omitempty"`
+	// PaxosLatencyThresholdMs is the paxos proposal latency above which a mon is reported as
+	// degraded. The latency is not checked if zero.
+	PaxosLatencyThresholdMs int `json:"paxosLatencyThresholdMs,omitempty"`
+	// CPUSet is the list of CPUs the CPU affinity of the mon daemons is set to in the Linux cpuset list
+	// format, for example "0-3,8-11". The affinity is best effort, since the kubelet chooses the CPUs of
+	// the cgroup cpuset of the mon containers.
+	CPUSet string `json:"cpuSet,omitempty"`
+	// BackupBeforeUpgrade saves the monmap and the mon keyring to a secret before the mons are updated
+	// to a new ceph version
+	BackupBeforeUpgrade bool `json:"backupBeforeUpgrade,omitempty"`
+	// RecoverFSIDMismatch wipes the store of a mon when it starts if the store belongs to a different
+	// cluster than the cluster fsid
+	RecoverFSIDMismatch bool `json:"recoverFSIDMismatch,omitempty"`
+	// Autopilot increases the mon count in the cluster CR as the number of OSDs grows
+	Autopilot bool `json:"autopilot,omitempty"`
+	// DNSPolicy is the DNS policy of the mon pods. The Kubernetes default is used if not set.
+	DNSPolicy *v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig is the custom DNS configuration of the mon pods, such as the nameservers and the
+	// search domains
+	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// VolumeExpansionMinFreePercent is the free space below which the PVC of a mon is expanded. The
+	// expansion is disabled if zero.
+	VolumeExpansionMinFreePercent int `json:"volumeExpansionMinFreePercent,omitempty"`
+	// HardwareClassWeights are the weights of the values of the rook.io/hardware-class node label. When
+	// nodes have the same number of mons, the node with the highest weight is chosen for a new mon.
+	HardwareClassWeights map[string]int `json:"hardwareClassWeights,omitempty"`
+	// CheckStoreOnStart verifies the consistency of the mon store before the mon daemon starts. A mon
+	// with a corrupted store is not started.
+	CheckStoreOnStart bool `json:"checkStoreOnStart,omitempty"`
+	// FailoverTimeoutMinutes is the time a mon may be out of quorum before it is failed over. If zero,
+	// the default timeout of the operator is used.
+	FailoverTimeoutMinutes int `json:"failoverTimeoutMinutes,omitempty"`
+	// RebalanceOnZoneRecovery moves a mon that was failed over out of a zone back into the zone once
+	// the zone has a valid node again and has fewer mons than the zone the mon was moved to.
+	RebalanceOnZoneRecovery bool `json:"rebalanceOnZoneRecovery,omitempty"`
+	// MaxMonChangeRate is the maximum number of mons added or removed at once when the mon count
+	// changes. If zero, one mon is added or removed at a time.
+	MaxMonChangeRate int `json:"maxMonChangeRate,omitempty"`
+	// ArbiterZone is the zone of a stretch cluster that hosts a single tiebreaker mon. The other mons
+	// are spread across the remaining zones.
+	ArbiterZone string `json:"arbiterZone,omitempty"`
+	// FailoverHeadroomNodes is the number of valid nodes kept without mons so that a failed mon can be
+	// replaced on a free node. Only applies if multiple mons are allowed per node.
+	FailoverHeadroomNodes int `json:"failoverHeadroomNodes,omitempty"`
+	// FailoverHeadroomPercent is the percentage of the valid nodes kept without mons for failovers. The
+	// larger of FailoverHeadroomNodes and this percentage applies.
+	FailoverHeadroomPercent int `json:"failoverHeadroomPercent,omitempty"`
+	// AllowEvenCount accepts an even mon count. An even count tolerates as many mon failures as the
+	// odd count below it.
+	AllowEvenCount bool `json:"allowEvenCount,omitempty"`
+	// QuorumLivenessTimeoutMinutes enables a liveness probe that restarts a mon that has been out of
+	// quorum for this many minutes, even if the mon daemon still responds. If zero, there is no probe.
+	QuorumLivenessTimeoutMinutes int `json:"quorumLivenessTimeoutMinutes,omitempty"`
+	// DisableReplicaCorrection stops the operator from scaling a mon deployment back to one replica
+	// when its replicas were changed outside of the operator
+	DisableReplicaCorrection bool `json:"disableReplicaCorrection,omitempty"`
+	// PodSecurityStandard generates mon pods that comply with the "baseline" or "restricted" Pod
+	// Security Standard. If empty, the mon pods are generated as before.
+	PodSecurityStandard string `json:"podSecurityStandard,omitempty"`
+	// SeedMonHosts are the addresses of trusted existing mons that a new mon contacts directly to
+	// join the quorum when mon discovery is blocked on the network
+	SeedMonHosts []string `json:"seedMonHosts,omitempty"`
+	// DebugSections are the debug log levels of ceph subsystems of the mons, keyed by subsystem
+	// (e.g. "paxos"). Each is set as the debug_<subsystem> option of the mons.
+	DebugSections map[string]int `json:"debugSections,omitempty"`
+	// RocksDBCacheSizeMB is the size of the rocksdb cache of the mon store in MB. If zero, the ceph
+	// default is used.
+	RocksDBCacheSizeMB int `json:"rocksDBCacheSizeMB,omitempty"`
+	// ProbeTimeoutMs is how long a mon waits for a response from its peers when probing them before
+	// it considers them failed, in milliseconds. If zero, the ceph default is used.
+	ProbeTimeoutMs int `json:"probeTimeoutMs,omitempty"`
+	// RunAsNonRoot runs the mon containers as the ceph user. The data dir is chowned to the ceph user
+	// by a root init container before the mon starts. If not set, it is enabled for a new cluster and
+	// disabled for an existing cluster.
+	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
 }
 
 // ExternalSpec represents the options supported by an external cluster
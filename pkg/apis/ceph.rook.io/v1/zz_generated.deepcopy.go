@@ -504,6 +504,16 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		*out = new(CephStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MonHealth != nil {
+		in, out := &in.MonHealth, &out.MonHealth
+		*out = new(MonHealthCondition)
+		**out = **in
+	}
+	if in.MonZoneCountSufficient != nil {
+		in, out := &in.MonZoneCountSufficient, &out.MonZoneCountSufficient
+		*out = new(MonZoneCountCondition)
+		**out = **in
+	}
 	return
 }
 
@@ -668,6 +678,22 @@ func (in *MetadataServerSpec) DeepCopy() *MetadataServerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonHealthCondition) DeepCopyInto(out *MonHealthCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonHealthCondition.
+func (in *MonHealthCondition) DeepCopy() *MonHealthCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MonHealthCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonSpec) DeepCopyInto(out *MonSpec) {
 	*out = *in
@@ -676,6 +702,40 @@ func (in *MonSpec) DeepCopyInto(out *MonSpec) {
 		*out = new(corev1.PersistentVolumeClaim)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DNSPolicy != nil {
+		in, out := &in.DNSPolicy, &out.DNSPolicy
+		*out = new(corev1.DNSPolicy)
+		**out = **in
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HardwareClassWeights != nil {
+		in, out := &in.HardwareClassWeights, &out.HardwareClassWeights
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SeedMonHosts != nil {
+		in, out := &in.SeedMonHosts, &out.SeedMonHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DebugSections != nil {
+		in, out := &in.DebugSections, &out.DebugSections
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RunAsNonRoot != nil {
+		in, out := &in.RunAsNonRoot, &out.RunAsNonRoot
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -689,6 +749,22 @@ func (in *MonSpec) DeepCopy() *MonSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonZoneCountCondition) DeepCopyInto(out *MonZoneCountCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonZoneCountCondition.
+func (in *MonZoneCountCondition) DeepCopy() *MonZoneCountCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MonZoneCountCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 	*out = *in
@@ -89,6 +89,9 @@ type NetworkSpec struct {
 
 	// Set of named ports that can be configured for this resource
 	Ports []PortSpec `json:"ports,omitempty"`
+
+	// PreferIPv6 lists the IPv6 endpoints of a dual-stack cluster before the IPv4 endpoints
+	PreferIPv6 bool `json:"preferIPv6,omitempty"`
 }
 
 type PortSpec struct {
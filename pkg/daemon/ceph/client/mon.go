@@ -18,6 +18,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/rook/rook/pkg/clusterd"
 )
@@ -27,6 +28,7 @@ import (
 type MonStatusResponse struct {
 	Quorum []int `json:"quorum"`
 	MonMap struct {
+		FSID string        `json:"fsid"`
 		Mons []MonMapEntry `json:"mons"`
 	} `json:"monmap"`
 }
@@ -107,3 +109,132 @@ func GetMonTimeStatus(context *clusterd.Context, clusterName string) (*MonTimeSt
 
 	return &timeStatus, nil
 }
+
+// MonPerfCounters is the subset of the perf counters of a mon that the operator checks
+type MonPerfCounters struct {
+	Paxos struct {
+		BeginLatency  PerfLatencyCounter `json:"begin_latency"`
+		CommitLatency PerfLatencyCounter `json:"commit_latency"`
+	} `json:"paxos"`
+}
+
+// PerfLatencyCounter is a latency perf counter. The latencies are in seconds.
+type PerfLatencyCounter struct {
+	AvgCount int64   `json:"avgcount"`
+	Sum      float64 `json:"sum"`
+	AvgTime  float64 `json:"avgtime"`
+}
+
+// GetMonPerfCounters dumps the perf counters of the given mon. The command fails if the mon does not
+// answer within the timeout.
+func GetMonPerfCounters(context *clusterd.Context, clusterName, monName string, timeout time.Duration) (*MonPerfCounters, error) {
+	args := []string{"tell", fmt.Sprintf("mon.%s", monName), "perf", "dump"}
+	buf, err := NewCephCommand(context, clusterName, args).RunWithTimeout(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get perf counters of mon %s. %+v", monName, err)
+	}
+
+	var counters MonPerfCounters
+	if err := json.Unmarshal(buf, &counters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal perf counters of mon %s. %+v", monName, err)
+	}
+
+	return &counters, nil
+}
+
+// MonRocksDBPerfCounters are the perf counters of the rocksdb store of a mon
+type MonRocksDBPerfCounters struct {
+	RocksDB struct {
+		Get                   int64              `json:"get"`
+		SubmitTransaction     int64              `json:"submit_transaction"`
+		SubmitTransactionSync int64              `json:"submit_transaction_sync"`
+		GetLatency            PerfLatencyCounter `json:"get_latency"`
+		SubmitLatency         PerfLatencyCounter `json:"submit_latency"`
+		SubmitSyncLatency     PerfLatencyCounter `json:"submit_sync_latency"`
+		Compact               int64              `json:"compact"`
+		CompactRange          int64              `json:"compact_range"`
+		CompactQueueLen       int64              `json:"compact_queue_len"`
+	} `json:"rocksdb"`
+}
+
+// GetMonRocksDBPerfCounters dumps the rocksdb perf counters of the given mon. The command fails if the
+// mon does not answer within the timeout.
+func GetMonRocksDBPerfCounters(context *clusterd.Context, clusterName, monName string, timeout time.Duration) (*MonRocksDBPerfCounters, error) {
+	args := []string{"tell", fmt.Sprintf("mon.%s", monName), "perf", "dump", "rocksdb"}
+	buf, err := NewCephCommand(context, clusterName, args).RunWithTimeout(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rocksdb perf counters of mon %s. %+v", monName, err)
+	}
+
+	var counters MonRocksDBPerfCounters
+	if err := json.Unmarshal(buf, &counters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rocksdb perf counters of mon %s. %+v", monName, err)
+	}
+
+	return &counters, nil
+}
+
+// GetMonMap returns the current monmap in the binary format used by monmaptool and ceph-mon
+func GetMonMap(context *clusterd.Context, clusterName string) ([]byte, error) {
+	args := []string{"mon", "getmap"}
+	cmd := NewCephCommand(context, clusterName, args)
+	cmd.JsonOutput = false
+	buf, err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monmap. %+v", err)
+	}
+	return buf, nil
+}
+
+// GetMonDump returns the monmap as it is known by the given mon
+func GetMonDump(context *clusterd.Context, clusterName, monName string) (*MonMap, error) {
+	args := []string{"tell", fmt.Sprintf("mon.%s", monName), "mon", "dump"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the monmap of mon %s. %+v", monName, err)
+	}
+
+	var monMap MonMap
+	if err := json.Unmarshal(buf, &monMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the monmap of mon %s. %+v", monName, err)
+	}
+
+	return &monMap, nil
+}
+
+// MonSetConfig applies a setting to all the mons in the centralized config database
+func MonSetConfig(context *clusterd.Context, clusterName, key, val string) error {
+	args := []string{"config", "set", "mon", key, val}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to set mon config key %s to \"%s\": %+v", key, val, err)
+	}
+	return nil
+}
+
+// MonDaemonSetConfig applies a setting to a single mon in the centralized config database
+func MonDaemonSetConfig(context *clusterd.Context, clusterName, monName, key, val string) error {
+	args := []string{"config", "set", fmt.Sprintf("mon.%s", monName), key, val}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to set config key %s of mon %s to \"%s\": %+v", key, monName, val, err)
+	}
+	return nil
+}
+
+// MonDaemonRemoveConfig removes the setting of a single mon from the centralized config database, so
+// that the mon uses the setting of all the mons again
+func MonDaemonRemoveConfig(context *clusterd.Context, clusterName, monName, key string) error {
+	args := []string{"config", "rm", fmt.Sprintf("mon.%s", monName), key}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to remove config key %s of mon %s: %+v", key, monName, err)
+	}
+	return nil
+}
+
+// GlobalSetConfig applies a setting to all the daemons in the centralized config database
+func GlobalSetConfig(context *clusterd.Context, clusterName, key, val string) error {
+	args := []string{"config", "set", "global", key, val}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to set global config key %s to \"%s\": %+v", key, val, err)
+	}
+	return nil
+}
@@ -57,6 +57,13 @@ type OSDPerfStats struct {
 	} `json:"osd_perf_infos"`
 }
 
+// OSDStat is the summary of the OSD counts in the osd map
+type OSDStat struct {
+	NumOSDs   int `json:"num_osds"`
+	NumUpOSDs int `json:"num_up_osds"`
+	NumInOSDs int `json:"num_in_osds"`
+}
+
 type OSDDump struct {
 	OSDs []struct {
 		OSD json.Number `json:"osd"`
@@ -176,6 +183,22 @@ func GetOSDDump(context *clusterd.Context, clusterName string) (*OSDDump, error)
 	return &osdDump, nil
 }
 
+// GetOSDStat returns the number of OSDs in the cluster and how many of them are up and in
+func GetOSDStat(context *clusterd.Context, clusterName string) (*OSDStat, error) {
+	args := []string{"osd", "stat"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get osd stat: %+v", err)
+	}
+
+	var osdStat OSDStat
+	if err := json.Unmarshal(buf, &osdStat); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal osd stat response: %+v", err)
+	}
+
+	return &osdStat, nil
+}
+
 func OSDOut(context *clusterd.Context, clusterName string, osdID int) (string, error) {
 	args := []string{"osd", "out", strconv.Itoa(osdID)}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -65,6 +65,9 @@ type CheckMessage struct {
 	Summary  struct {
 		Message string `json:"message"`
 	} `json:"summary"`
+	Detail []struct {
+		Message string `json:"message"`
+	} `json:"detail,omitempty"`
 }
 
 type MonMap struct {
@@ -158,6 +161,22 @@ func Status(context *clusterd.Context, clusterName string, debug bool) (CephStat
 	return status, nil
 }
 
+// HealthDetail returns the health checks of the cluster along with the detailed messages of each check
+func HealthDetail(context *clusterd.Context, clusterName string) (HealthStatus, error) {
+	args := []string{"health", "detail"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return HealthStatus{}, fmt.Errorf("failed to get health detail: %+v", err)
+	}
+
+	var health HealthStatus
+	if err := json.Unmarshal(buf, &health); err != nil {
+		return HealthStatus{}, fmt.Errorf("failed to unmarshal health detail response: %+v", err)
+	}
+
+	return health, nil
+}
+
 // IsClusterClean returns a value indicating if the cluster is fully clean yet (i.e., all placement
 // groups are in the active+clean state).
 func IsClusterClean(context *clusterd.Context, clusterName string) error {
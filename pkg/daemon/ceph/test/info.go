@@ -8,6 +8,6 @@ import (
 
 func CreateConfigDir(configDir string) {
 	os.MkdirAll(configDir, 0744)
-	ioutil.WriteFile(path.Join(configDir, "client.admin.keyring"), []byte("key = adminsecret"), 0644)
-	ioutil.WriteFile(path.Join(configDir, "mon.keyring"), []byte("key = monsecret"), 0644)
+	ioutil.WriteFile(path.Join(configDir, "client.admin.keyring"), []byte("key = AQCATJRdAAAAABAAw3sp0Ji3MfFf4XFez5WBrg=="), 0644)
+	ioutil.WriteFile(path.Join(configDir, "mon.keyring"), []byte("key = AQCATJRdAAAAABAAcmaKuuNct7MZyDXk/J7miQ=="), 0644)
 }
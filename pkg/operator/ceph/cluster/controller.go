@@ -117,6 +117,11 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 		DeleteFunc: c.onDelete,
 	}
 
+	// the legacy mon settings would fail to decode in the watcher
+	if err := migrateLegacyMonSpecs(c.context, namespace); err != nil {
+		logger.Warningf("failed to convert the legacy mon settings of the clusters. %+v", err)
+	}
+
 	logger.Infof("start watching clusters in all namespaces")
 	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
 	go watcher.Watch(&cephv1.CephCluster{}, stopCh)
@@ -332,8 +337,8 @@ func (c *ClusterController) configureLocalCephCluster(namespace, name string, cl
 		logger.Warningf("mon count should be at least 1, will use default value of %d", mon.DefaultMonCount)
 		cluster.Spec.Mon.Count = mon.DefaultMonCount
 	}
-	if cluster.Spec.Mon.Count%2 == 0 {
-		logger.Warningf("mon count is even (given: %d), should be uneven, continuing", cluster.Spec.Mon.Count)
+	if cluster.Spec.Mon.Count%2 == 0 && !cluster.Spec.Mon.AllowEvenCount {
+		logger.Warningf("mon count is even (given: %d), should be uneven. the mons are only started if the cluster already has %d mons or allowEvenCount is set", cluster.Spec.Mon.Count, cluster.Spec.Mon.Count)
 	}
 
 	if c.devicesInUse && cluster.Spec.Storage.AnyUseAllDevices() {
@@ -417,7 +422,7 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 	poolController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store CRD watcher
-	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath)
+	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, cluster.mons)
 	objectStoreController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store user CRD watcher
@@ -432,7 +437,7 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 	go bucketController.Run(cluster.stopCh)
 
 	// Start file system CRD watcher
-	fileController := file.NewFilesystemController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath)
+	fileController := file.NewFilesystemController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, cluster.mons)
 	fileController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start nfs ganesha CRD watcher
@@ -452,6 +457,9 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 		// Start the osd health checker only if running OSDs in the local ceph cluster
 		osdChecker := osd.NewMonitor(c.context, cluster.Namespace)
 		go osdChecker.Start(cluster.stopCh)
+
+		// Keep the mon tolerations in sync with the taints of the mon nodes
+		go cluster.mons.WatchNodeTaints(cluster.stopCh)
 	}
 
 	// Start the ceph status checker
@@ -575,6 +583,18 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	// changes to only the mon settings are reconciled by the mons without a full orchestration
+	if mon.OnlyMonSpecChanged(oldClust.Spec, newClust.Spec) {
+		logger.Infof("update event for cluster %s only changes the mon settings, reconciling the mons", newClust.Namespace)
+		if err := cluster.mons.ReconcileSpec(oldClust.Spec, newClust.Spec); err != nil {
+			// the cluster keeps the settings the mons were last reconciled with
+			logger.Errorf("failed to reconcile the mons in cluster %s. %+v", newClust.Namespace, err)
+			return
+		}
+		cluster.Spec = &newClust.Spec
+		return
+	}
+
 	logger.Infof("update event for cluster %s is supported, orchestrating update now", newClust.Namespace)
 
 	// if the image changed, we need to detect the new image version
@@ -18,9 +18,13 @@ limitations under the License.
 package cluster
 
 import (
+	"encoding/json"
 	"fmt"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func getClusterObject(obj interface{}) (cluster *cephv1.CephCluster, err error) {
@@ -34,3 +38,42 @@ func getClusterObject(obj interface{}) (cluster *cephv1.CephCluster, err error)
 
 	return nil, fmt.Errorf("not a known cluster object: %+v", obj)
 }
+
+// migrateLegacyMonSpecs converts the mon settings of the cluster CRs written for a rook release before
+// 1.0 to the current mon spec. The legacy settings cannot be decoded into a cluster object, so the
+// CRs are read as raw json and patched before the clusters are watched.
+func migrateLegacyMonSpecs(context *clusterd.Context, namespace string) error {
+	raw, err := context.RookClientset.CephV1().RESTClient().Get().Namespace(namespace).Resource(ClusterResource.Plural).Do().Raw()
+	if err != nil {
+		return fmt.Errorf("failed to list the cluster CRs. %+v", err)
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec map[string]interface{} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return fmt.Errorf("failed to decode the cluster CRs. %+v", err)
+	}
+
+	for _, item := range list.Items {
+		name, ns := item.Metadata.Name, item.Metadata.Namespace
+		patch, ok, err := mon.LegacyMonSpecPatch(item.Spec)
+		if err != nil {
+			logger.Errorf("failed to convert the legacy mon settings of cluster %s in namespace %s. %+v", name, ns, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		logger.Infof("converting the legacy mon settings of cluster %s in namespace %s", name, ns)
+		if _, err := context.RookClientset.CephV1().CephClusters(ns).Patch(name, types.JSONPatchType, patch); err != nil {
+			logger.Errorf("failed to update the legacy mon settings of cluster %s in namespace %s. %+v", name, ns, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// checkMonAdminSocket returns an error if the admin socket of the mon does not answer. The socket is
+// in the mon container where the operator cannot reach it, so the version command of the socket is
+// sent to the mon with ceph tell, which the mon answers from the same command handler. It is a
+// variable so the unit tests can simulate unresponsive mons.
+var checkMonAdminSocket = func(cluster *Cluster, name string) error {
+	args := []string{"tell", fmt.Sprintf("mon.%s", name), "version"}
+	if _, err := client.NewCephCommand(cluster.context, cluster.ClusterInfo.Name, args).Run(); err != nil {
+		return fmt.Errorf("admin socket %s of mon %s did not answer. %+v", fmt.Sprintf(monAdminSocketPathFormat, name), name, err)
+	}
+	return nil
+}
+
+// validateAdminSockets checks the admin socket of each mon and returns whether the socket of each mon
+// answered. An error is returned if none of the mons answered.
+func validateAdminSockets(ctx context.Context, cluster *Cluster) (map[string]bool, error) {
+	names := []string{}
+	for name := range cluster.ClusterInfo.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	responsive := map[string]bool{}
+	answered := 0
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return responsive, err
+		}
+		if err := checkMonAdminSocket(cluster, name); err != nil {
+			logger.Warningf("%+v", err)
+			responsive[name] = false
+			continue
+		}
+		responsive[name] = true
+		answered++
+	}
+	if len(names) > 0 && answered == 0 {
+		return responsive, fmt.Errorf("none of the %d mons answered on the admin socket", len(names))
+	}
+	return responsive, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateAdminSockets(t *testing.T) {
+	unresponsive := map[string]bool{}
+	checked := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "tell" && args[2] == "version" {
+				checked = append(checked, args[1])
+				if unresponsive[args[1]] {
+					return "", fmt.Errorf("%s timed out", args[1])
+				}
+				return `{"version":"ceph version 14.2.1"}`, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// all the mons answer
+	responsive, err := validateAdminSockets(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, responsive)
+	assert.Equal(t, []string{"mon.a", "mon.b", "mon.c"}, checked)
+
+	// a mon does not answer
+	unresponsive["mon.b"] = true
+	responsive, err = validateAdminSockets(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"a": true, "b": false, "c": true}, responsive)
+
+	// none of the mons answer
+	unresponsive["mon.a"] = true
+	unresponsive["mon.c"] = true
+	responsive, err = validateAdminSockets(context.Background(), c)
+	assert.Error(t, err)
+	assert.Equal(t, map[string]bool{"a": false, "b": false, "c": false}, responsive)
+
+	// the check stops when the context is canceled
+	checked = []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = validateAdminSockets(ctx, c)
+	assert.Error(t, err)
+	assert.Empty(t, checked)
+}
+
+func TestStartMonsSkipsConfigWithoutAdminSockets(t *testing.T) {
+	defer func(check func(*Cluster, string) error) { checkMonAdminSocket = check }(checkMonAdminSocket)
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	configSets := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				configSets++
+				return "", nil
+			}
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(1), Executor: executor, ConfigDir: configDir}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(1)
+	c.ClusterInfo.CephVersion = cephver.Mimic
+	c.spec.Mon.Count = 1
+	c.spec.MaxPGPerOSD = 300
+
+	// the config is not set if none of the mons answer
+	checkMonAdminSocket = func(cluster *Cluster, name string) error { return fmt.Errorf("mon %s did not answer", name) }
+	assert.NoError(t, c.startMons(1))
+	assert.Equal(t, 0, configSets)
+
+	// the config is set when a mon answers
+	checkMonAdminSocket = func(cluster *Cluster, name string) error { return nil }
+	assert.NoError(t, c.startMons(1))
+	assert.Equal(t, 1, configSets)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// autopilotOSDsPerMon is the number of OSDs recommended for each mon
+	autopilotOSDsPerMon = 50
+	// autopilotMaxMonCount is the highest mon count the autopilot sets
+	autopilotMaxMonCount = 5
+)
+
+// autopilotMonCount returns the recommended mon count for the number of OSDs: one mon per 50 OSDs,
+// rounded up to an odd count so that the quorum can tolerate as many failures as possible, and at
+// most five mons.
+func autopilotMonCount(osds int) int {
+	count := (osds + autopilotOSDsPerMon - 1) / autopilotOSDsPerMon
+	if count < 1 {
+		count = 1
+	}
+	if count%2 == 0 {
+		count++
+	}
+	if count > autopilotMaxMonCount {
+		count = autopilotMaxMonCount
+	}
+	return count
+}
+
+// runAutopilot increases the mon count in the cluster CR when the number of OSDs calls for more mons
+// than the current count. The mon count is never decreased by the autopilot.
+func (c *Cluster) runAutopilot() error {
+	if !c.spec.Mon.Autopilot {
+		return nil
+	}
+
+	osdStat, err := client.GetOSDStat(c.context, c.ClusterInfo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get the osd count. %+v", err)
+	}
+	count := autopilotMonCount(osdStat.NumOSDs)
+	if count <= c.spec.Mon.Count {
+		return nil
+	}
+
+	logger.Infof("autopilot increasing the mon count from %d to %d for %d osds", c.spec.Mon.Count, count, osdStat.NumOSDs)
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster %s to update the mon count. %+v", c.ownerRef.Name, err)
+	}
+	cluster.Spec.Mon.Count = count
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cluster); err != nil {
+		return fmt.Errorf("failed to update the mon count of cluster %s. %+v", c.ownerRef.Name, err)
+	}
+
+	// the new count applies to the next health check without waiting for the cluster update
+	c.spec.Mon.Count = count
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutopilotMonCount(t *testing.T) {
+	assert.Equal(t, 1, autopilotMonCount(0))
+	assert.Equal(t, 1, autopilotMonCount(10))
+	assert.Equal(t, 1, autopilotMonCount(50))
+	assert.Equal(t, 3, autopilotMonCount(51))
+	assert.Equal(t, 3, autopilotMonCount(150))
+	assert.Equal(t, 5, autopilotMonCount(151))
+	assert.Equal(t, 5, autopilotMonCount(200))
+	assert.Equal(t, 5, autopilotMonCount(1000))
+}
+
+func TestRunAutopilot(t *testing.T) {
+	osds := 10
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if len(args) > 1 && args[0] == "osd" && args[1] == "stat" {
+				return fmt.Sprintf(`{"epoch":10,"num_osds":%d,"num_up_osds":%d,"num_in_osds":%d}`, osds, osds, osds), nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	rookClientset := rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"},
+		Spec:       cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 1}},
+	}
+	_, err := rookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.NoError(t, err)
+
+	context := &clusterd.Context{Clientset: test.New(3), RookClientset: rookClientset, Executor: executor}
+	c := New(context, "ns", "", false, metav1.OwnerReference{Name: "rook-ceph"}, &sync.Mutex{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 1}, "myversion")
+	clusterMonCount := func() int {
+		cluster, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cluster.Spec.Mon.Count
+	}
+
+	// the autopilot is disabled by default
+	osds = 200
+	assert.NoError(t, c.runAutopilot())
+	assert.Equal(t, 1, c.spec.Mon.Count)
+	assert.Equal(t, 1, clusterMonCount())
+
+	// grow the osds from 10 to 200
+	c.spec.Mon.Autopilot = true
+	expected := map[int]int{10: 1, 50: 1, 100: 3, 150: 3, 200: 5}
+	for _, count := range []int{10, 50, 100, 150, 200} {
+		osds = count
+		assert.NoError(t, c.runAutopilot())
+		assert.Equal(t, expected[count], c.spec.Mon.Count, fmt.Sprintf("osds: %d", count))
+		assert.Equal(t, expected[count], clusterMonCount(), fmt.Sprintf("osds: %d", count))
+	}
+
+	// the mon count is not decreased when osds are removed
+	osds = 10
+	assert.NoError(t, c.runAutopilot())
+	assert.Equal(t, 5, c.spec.Mon.Count)
+	assert.Equal(t, 5, clusterMonCount())
+}
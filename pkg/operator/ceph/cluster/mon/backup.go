@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monStoreBackupName is the name of the secret with the backup of the monmap and the mon
+	// keyring taken before the mons are upgraded
+	monStoreBackupName         = "rook-ceph-mon-backup"
+	monStoreBackupMonmapKey    = "monmap"
+	monStoreBackupKeyringKey   = "keyring"
+	monStoreBackupTimestampKey = "timestamp"
+)
+
+// backupBeforeUpgrade backs up the monmap and the mon keyring if the mons are about to be updated
+// to a new ceph version
+func (c *Cluster) backupBeforeUpgrade() error {
+	if !c.spec.Mon.BackupBeforeUpgrade {
+		return nil
+	}
+
+	runningVersion, err := client.LeastUptodateDaemonVersion(c.context, c.ClusterInfo.Name, string(config.MonType))
+	if err != nil {
+		return fmt.Errorf("failed to get the ceph version of the mons. %+v", err)
+	}
+	// the version is empty when no mons are running yet
+	if runningVersion.Major == 0 || cephver.IsIdentical(runningVersion, c.ClusterInfo.CephVersion) {
+		return nil
+	}
+
+	logger.Infof("backing up the mon store before updating the mons from ceph version %s to %s",
+		runningVersion.String(), c.ClusterInfo.CephVersion.String())
+	return c.backupMonStore()
+}
+
+// backupMonStore saves the current monmap and the mon keyring with a timestamp in a secret. A
+// previous backup is replaced.
+func (c *Cluster) backupMonStore() error {
+	monmap, err := client.GetMonMap(c.context, c.ClusterInfo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to back up the monmap. %+v", err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      monStoreBackupName,
+			Namespace: c.Namespace,
+		},
+		Data: map[string][]byte{
+			monStoreBackupMonmapKey:    monmap,
+			monStoreBackupKeyringKey:   []byte(c.genMonSharedKeyring()),
+			monStoreBackupTimestampKey: []byte(time.Now().UTC().Format(time.RFC3339)),
+		},
+		Type: k8sutil.RookType,
+	}
+	k8sutil.SetOwnerRef(&secret.ObjectMeta, &c.ownerRef)
+
+	if _, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create mon store backup secret. %+v", err)
+		}
+		if _, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update mon store backup secret. %+v", err)
+		}
+	}
+
+	logger.Infof("backed up the monmap and the mon keyring to secret %s", monStoreBackupName)
+	return nil
+}
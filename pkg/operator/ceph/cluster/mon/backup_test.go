@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackupBeforeUpgrade(t *testing.T) {
+	namespace := "ns"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if len(args) > 0 && args[0] == "versions" {
+				return `{"mon":{"ceph version 13.2.5 (cbff874f9007f1869bfd3821b7e33b2a6ffd4988) mimic (stable)":1}}`, nil
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if strings.Join(args[:2], " ") == "mon getmap" {
+				return "binary monmap", nil
+			}
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	context := &clusterd.Context{Clientset: test.New(1), Executor: executor, ConfigDir: configDir}
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(1)
+	c.ClusterInfo.CephVersion = cephver.CephVersion{Major: 13, Minor: 2, Extra: 5}
+	c.spec.Mon.Count = 1
+	c.spec.Mon.BackupBeforeUpgrade = true
+
+	getBackup := func() (*v1.Secret, error) {
+		return context.Clientset.CoreV1().Secrets(namespace).Get(monStoreBackupName, metav1.GetOptions{})
+	}
+
+	// the backup must exist by the time the mon deployments are updated
+	backupExisted := []bool{}
+	updateDeploymentAndWait = func(context *clusterd.Context, deployment *apps.Deployment, namespace, daemonType, daemonName string, cephVersion cephver.CephVersion) error {
+		_, err := getBackup()
+		backupExisted = append(backupExisted, err == nil)
+		return nil
+	}
+
+	// no backup when the version is not changing
+	assert.NoError(t, c.startMons(1))
+	_, err := getBackup()
+	assert.True(t, errors.IsNotFound(err))
+
+	// the mons are updated to a new version
+	c.ClusterInfo.CephVersion = cephver.CephVersion{Major: 13, Minor: 2, Extra: 6}
+	assert.NoError(t, c.startMons(1))
+	backup, err := getBackup()
+	assert.NoError(t, err)
+	assert.Equal(t, "binary monmap", string(backup.Data[monStoreBackupMonmapKey]))
+	assert.Contains(t, string(backup.Data[monStoreBackupKeyringKey]), c.ClusterInfo.MonitorSecret)
+	assert.NotEmpty(t, backup.Data[monStoreBackupTimestampKey])
+	assert.Equal(t, []bool{true}, backupExisted)
+
+	// a second upgrade replaces the backup
+	assert.NoError(t, c.startMons(1))
+	assert.Equal(t, []bool{true, true}, backupExisted)
+
+	// no backup if not enabled
+	c.spec.Mon.BackupBeforeUpgrade = false
+	assert.NoError(t, context.Clientset.CoreV1().Secrets(namespace).Delete(monStoreBackupName, &metav1.DeleteOptions{}))
+	assert.NoError(t, c.startMons(1))
+	_, err = getBackup()
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// identifyBootstrapMon returns the name of the mon with the lowest index, which is the mon that
+// created the cluster. Mons with names that are not an index are only chosen if no mon name is an
+// index.
+func identifyBootstrapMon(mons []*monConfig) string {
+	bootstrap := ""
+	lowest := -1
+	for _, m := range mons {
+		id, err := k8sutil.NameToIndex(m.DaemonName)
+		if err != nil {
+			if lowest == -1 && (bootstrap == "" || m.DaemonName < bootstrap) {
+				bootstrap = m.DaemonName
+			}
+			continue
+		}
+		if lowest == -1 || id < lowest {
+			bootstrap = m.DaemonName
+			lowest = id
+		}
+	}
+	return bootstrap
+}
+
+// startBootstrapMon starts the bootstrap mon before any other mon when none of the mon deployments
+// exist, as when the cluster is recovered after losing all the mons. The other mons can only join the
+// mon that initializes the store. The mon pod must be running before the other mons are started, but
+// the mon cannot be in quorum alone if the monmap has other mons.
+func (c *Cluster) startBootstrapMon(mons []*monConfig) error {
+	name := identifyBootstrapMon(mons)
+	if name == "" {
+		return nil
+	}
+	if _, ok := c.ClusterInfo.Monitors[name]; !ok {
+		// a new cluster starts its mons one at a time from the lowest index
+		return nil
+	}
+
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()})
+	if err != nil {
+		return fmt.Errorf("failed to list the mon deployments. %+v", err)
+	}
+	if len(deployments.Items) > 0 {
+		return nil
+	}
+
+	var bootstrap *monConfig
+	for _, m := range mons {
+		if m.DaemonName == name {
+			bootstrap = m
+			break
+		}
+	}
+	logger.Infof("no mon deployment exists. starting the bootstrap mon %s before the other mons", name)
+	node, _ := c.mapping.Node[name]
+	if err := c.startMon(bootstrap, node.Hostname); err != nil {
+		return fmt.Errorf("failed to start the bootstrap mon %s. %+v", name, err)
+	}
+	return c.waitForMonPodRunning(name)
+}
+
+// waitForMonPodRunning waits for a pod of the mon to be running
+func (c *Cluster) waitForMonPodRunning(name string) error {
+	if !c.waitForStart {
+		return nil
+	}
+	start := time.Now()
+	for {
+		running, err := k8sutil.PodsRunningWithLabel(c.context.Clientset, c.Namespace, MonLabelSelector(c.Namespace, name).String())
+		if err != nil {
+			logger.Debugf("failed to query the pods of mon %s. %+v", name, err)
+		} else if running > 0 {
+			logger.Infof("mon %s is running", name)
+			return nil
+		}
+
+		if time.Since(start) > c.monPodTimeout {
+			return fmt.Errorf("timed out waiting for a pod of mon %s to be running", name)
+		}
+		<-time.After(c.monPodRetryInterval)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIdentifyBootstrapMon(t *testing.T) {
+	assert.Equal(t, "", identifyBootstrapMon(nil))
+	assert.Equal(t, "a", identifyBootstrapMon([]*monConfig{testGenMonConfig("c"), testGenMonConfig("a"), testGenMonConfig("b")}))
+	assert.Equal(t, "b", identifyBootstrapMon([]*monConfig{testGenMonConfig("d"), testGenMonConfig("b")}))
+	// the index of "aa" is after "z"
+	assert.Equal(t, "z", identifyBootstrapMon([]*monConfig{testGenMonConfig("aa"), testGenMonConfig("z")}))
+	// legacy names are only chosen without a mon with an index
+	assert.Equal(t, "c", identifyBootstrapMon([]*monConfig{testGenMonConfig("mon0"), testGenMonConfig("c")}))
+	assert.Equal(t, "mon0", identifyBootstrapMon([]*monConfig{testGenMonConfig("mon1"), testGenMonConfig("mon0")}))
+}
+
+func TestStartBootstrapMon(t *testing.T) {
+	clientset := test.New(3)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	c.spec.CephVersion.Image = "ceph/ceph:v14"
+	mons := []*monConfig{testGenMonConfig("b"), testGenMonConfig("c"), testGenMonConfig("a")}
+	for i, m := range mons {
+		node := fmt.Sprintf("node%d", i)
+		c.mapping.Node[m.DaemonName] = &NodeInfo{Name: node, Hostname: node}
+	}
+
+	// a new cluster starts the mons in order without a bootstrap step
+	newMons := []*monConfig{testGenMonConfig("d")}
+	c.mapping.Node["d"] = &NodeInfo{Name: "node0", Hostname: "node0"}
+	assert.NoError(t, c.startBootstrapMon(newMons))
+	deployments, err := clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, deployments.Items)
+
+	// only the bootstrap mon is started when none of the mons exist
+	assert.NoError(t, c.startBootstrapMon(mons))
+	deployments, err = clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deployments.Items))
+	assert.Equal(t, "rook-ceph-mon-a", deployments.Items[0].Name)
+
+	// nothing is started when a mon deployment exists
+	assert.NoError(t, clientset.AppsV1().Deployments("ns").Delete("rook-ceph-mon-a", &metav1.DeleteOptions{}))
+	assert.NoError(t, c.startMon(mons[0], "node0"))
+	assert.NoError(t, c.startBootstrapMon(mons))
+	deployments, err = clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deployments.Items))
+	assert.Equal(t, "rook-ceph-mon-b", deployments.Items[0].Name)
+}
+
+func TestWaitForMonPodRunning(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, false, v1.ResourceRequirements{})
+	c.waitForStart = true
+	c.monPodTimeout = 10 * time.Millisecond
+	c.monPodRetryInterval = time.Millisecond
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-a-123", Namespace: "ns", Labels: MonLabels("ns", "a")},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+	_, err := clientset.CoreV1().Pods("ns").Create(pod)
+	assert.NoError(t, err)
+	assert.Error(t, c.waitForMonPodRunning("a"))
+
+	pod.Status.Phase = v1.PodRunning
+	_, err = clientset.CoreV1().Pods("ns").Update(pod)
+	assert.NoError(t, err)
+	assert.NoError(t, c.waitForMonPodRunning("a"))
+}
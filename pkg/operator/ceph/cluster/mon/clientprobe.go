@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	monClientProbeAppName = "rook-ceph-mon-client-probe"
+	monClientProbeTimeout = 5 * time.Minute
+	// the seconds the probe waits for a mon to accept a connection
+	monClientProbeConnectSeconds = 5
+	monClientProbeReachable      = "reachable"
+	monClientProbeUnreachable    = "unreachable"
+)
+
+// probeMonsFromClientNetwork connects to the mon endpoints by name from a pod on the client network
+// and returns whether each mon could be reached. It is a variable so the unit tests do not need to
+// run a probe pod.
+var probeMonsFromClientNetwork = runMonClientProbe
+
+// FindClientUnreachableMons returns the mons that are in quorum but cannot be reached by the clients,
+// for example because a firewall blocks the public network of the mons. The mon endpoints are probed
+// from a pod on the pod network like the clients of the cluster. The mons that cannot be reached are
+// reported with an event.
+func (c *Cluster) FindClientUnreachableMons() ([]string, error) {
+	status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mon status. %+v", err)
+	}
+
+	endpoints := map[string]string{}
+	for name, mon := range c.ClusterInfo.Monitors {
+		if monFoundInQuorum(name, status) {
+			endpoints[name] = mon.Endpoint
+		}
+	}
+	unreachable := []string{}
+	if len(endpoints) == 0 {
+		return unreachable, nil
+	}
+
+	reachable, err := probeMonsFromClientNetwork(c, endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe the mons from the client network. %+v", err)
+	}
+	for name := range endpoints {
+		if !reachable[name] {
+			unreachable = append(unreachable, name)
+		}
+	}
+	sort.Strings(unreachable)
+
+	if len(unreachable) > 0 {
+		msg := fmt.Sprintf("mons %v are in quorum but cannot be reached from the client network", unreachable)
+		logger.Warningf(msg)
+		c.recordEvent(v1.EventTypeWarning, "MonClientUnreachable", msg)
+	}
+	return unreachable, nil
+}
+
+// runMonClientProbe runs a job that connects to each mon endpoint and reports the result in the
+// termination message of its pod
+func runMonClientProbe(c *Cluster, endpoints map[string]string) (map[string]bool, error) {
+	job, err := c.makeMonClientProbeJob(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return runMonProbeJob(c, job, monClientProbeTimeout)
+}
+
+// runMonProbeJob runs a job made by makeMonClientProbeJob and returns whether each mon was reached
+func runMonProbeJob(c *Cluster, job *batch.Job, timeout time.Duration) (map[string]bool, error) {
+	if err := k8sutil.RunReplaceableJob(c.context.Clientset, job, true); err != nil {
+		return nil, fmt.Errorf("failed to run the mon client probe job. %+v", err)
+	}
+	defer func() {
+		if err := k8sutil.DeleteBatchJob(c.context.Clientset, job.Namespace, job.Name, false); err != nil {
+			logger.Warningf("failed to delete the mon client probe job. %+v", err)
+		}
+	}()
+	if err := k8sutil.WaitForJobCompletion(c.context.Clientset, job, timeout); err != nil {
+		return nil, fmt.Errorf("failed to complete the mon client probe job. %+v", err)
+	}
+
+	pods, err := c.context.Clientset.CoreV1().Pods(job.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", job.Name)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the mon client probe pods. %+v", err)
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.ExitCode == 0 {
+				return parseMonClientProbeOutput(status.State.Terminated.Message), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("the mon client probe job did not report a result")
+}
+
+func (c *Cluster) makeMonClientProbeJob(endpoints map[string]string) (*batch.Job, error) {
+	names := []string{}
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// bash opens a tcp connection when redirecting from /dev/tcp/<host>/<port>
+	probes := []string{}
+	for _, name := range names {
+		host, port, err := net.SplitHostPort(endpoints[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q of mon %s. %+v", endpoints[name], name, err)
+		}
+		probes = append(probes, fmt.Sprintf("if timeout %d bash -c '</dev/tcp/%s/%s' 2>/dev/null; then echo '%s %s'; else echo '%s %s'; fi",
+			monClientProbeConnectSeconds, host, port, name, monClientProbeReachable, name, monClientProbeUnreachable))
+	}
+	probeCmd := fmt.Sprintf("{ %s; } | tee /dev/termination-log", strings.Join(probes, "; "))
+
+	labels := map[string]string{
+		k8sutil.AppAttr:     monClientProbeAppName,
+		k8sutil.ClusterAttr: c.Namespace,
+	}
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      monClientProbeAppName,
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name:    "probe-mons",
+							Command: []string{"/bin/bash"},
+							Args:    []string{"-c", probeCmd},
+							Image:   c.spec.CephVersion.Image,
+						},
+					},
+					// the clients of the cluster run on the pod network
+					HostNetwork:   false,
+					RestartPolicy: v1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+	k8sutil.AddRookVersionLabelToJob(job)
+	k8sutil.SetOwnerRef(&job.ObjectMeta, &c.ownerRef)
+	return job, nil
+}
+
+// parseMonClientProbeOutput returns whether each mon was reached from the "<name> reachable" and
+// "<name> unreachable" lines of the probe output
+func parseMonClientProbeOutput(output string) map[string]bool {
+	reachable := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		reachable[fields[0]] = fields[1] == monClientProbeReachable
+	}
+	return reachable
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestFindClientUnreachableMons(t *testing.T) {
+	defer func() { probeMonsFromClientNetwork = runMonClientProbe }()
+
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon_status" {
+				// mon c is out of quorum
+				return `{"quorum":[0,1],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(3), Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	// only the mons in quorum are probed
+	probed := map[string]string{}
+	reachable := map[string]bool{"a": true, "b": true}
+	probeMonsFromClientNetwork = func(c *Cluster, endpoints map[string]string) (map[string]bool, error) {
+		probed = endpoints
+		return reachable, nil
+	}
+	unreachable, err := c.FindClientUnreachableMons()
+	assert.NoError(t, err)
+	assert.Empty(t, unreachable)
+	assert.Equal(t, map[string]string{"a": "1.2.3.1:6789", "b": "1.2.3.2:6789"}, probed)
+	assert.Empty(t, recorder.Events)
+
+	// mon b is in quorum but the clients cannot reach it
+	reachable = map[string]bool{"a": true, "b": false}
+	unreachable, err = c.FindClientUnreachableMons()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, unreachable)
+	event := <-recorder.Events
+	assert.Contains(t, event, "MonClientUnreachable")
+	assert.Contains(t, event, "[b]")
+
+	// a mon missing from the probe result was not reached
+	reachable = map[string]bool{"a": true}
+	unreachable, err = c.FindClientUnreachableMons()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, unreachable)
+
+	// the probe failed
+	probeMonsFromClientNetwork = func(c *Cluster, endpoints map[string]string) (map[string]bool, error) {
+		return nil, fmt.Errorf("probe failed")
+	}
+	_, err = c.FindClientUnreachableMons()
+	assert.Error(t, err)
+}
+
+func TestMakeMonClientProbeJob(t *testing.T) {
+	c := newCluster(&clusterd.Context{}, "ns", true, false, v1.ResourceRequirements{})
+	c.spec.CephVersion.Image = "ceph/ceph:v14"
+
+	job, err := c.makeMonClientProbeJob(map[string]string{"b": "[fd00::2]:6789", "a": "1.2.3.1:3300"})
+	assert.NoError(t, err)
+	assert.Equal(t, monClientProbeAppName, job.Name)
+	spec := job.Spec.Template.Spec
+	// the probe runs on the client network even if the mons use the host network
+	assert.False(t, spec.HostNetwork)
+	assert.Equal(t, "ceph/ceph:v14", spec.Containers[0].Image)
+	script := spec.Containers[0].Args[1]
+	assert.True(t, strings.Index(script, "/dev/tcp/1.2.3.1/3300") < strings.Index(script, "/dev/tcp/fd00::2/6789"))
+	assert.Contains(t, script, "tee /dev/termination-log")
+
+	_, err = c.makeMonClientProbeJob(map[string]string{"a": "1.2.3.1"})
+	assert.Error(t, err)
+}
+
+func TestParseMonClientProbeOutput(t *testing.T) {
+	assert.Equal(t, map[string]bool{"a": true, "b": false}, parseMonClientProbeOutput("a reachable\nb unreachable\n\ngarbage\n"))
+	assert.Empty(t, parseMonClientProbeOutput(""))
+}
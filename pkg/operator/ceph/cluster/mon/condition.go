@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// monHealthCondition summarizes the health of the mons from the mon status. A nil status means the
+// status could not be retrieved from the mons. The mons are unavailable without a quorum, and degraded
+// if fewer than the desired mons exist, some mons are out of quorum or some mons are degraded.
+func monHealthCondition(status *client.MonStatusResponse, desiredMonCount int, degradedMons map[string]string) cephv1.MonHealthCondition {
+	if status == nil {
+		return cephv1.MonHealthCondition{Status: cephv1.MonUnavailable, Message: "failed to get the mon status"}
+	}
+
+	mons := len(status.MonMap.Mons)
+	if len(status.Quorum) < mons/2+1 {
+		return cephv1.MonHealthCondition{
+			Status:  cephv1.MonUnavailable,
+			Message: fmt.Sprintf("no quorum with %d of %d mons", len(status.Quorum), mons),
+		}
+	}
+
+	problems := []string{}
+	outOfQuorum := []string{}
+	for _, mon := range status.MonMap.Mons {
+		if !monInQuorum(mon, status.Quorum) {
+			outOfQuorum = append(outOfQuorum, mon.Name)
+		}
+	}
+	if len(outOfQuorum) > 0 {
+		sort.Strings(outOfQuorum)
+		problems = append(problems, fmt.Sprintf("mons %s are out of quorum", strings.Join(outOfQuorum, ",")))
+	}
+	if mons < desiredMonCount {
+		problems = append(problems, fmt.Sprintf("%d of %d mons are pending", desiredMonCount-mons, desiredMonCount))
+	}
+	if len(degradedMons) > 0 {
+		degraded := []string{}
+		for name := range degradedMons {
+			degraded = append(degraded, name)
+		}
+		sort.Strings(degraded)
+		problems = append(problems, fmt.Sprintf("mons %s are degraded", strings.Join(degraded, ",")))
+	}
+
+	if len(problems) > 0 {
+		return cephv1.MonHealthCondition{Status: cephv1.MonDegraded, Message: strings.Join(problems, "; ")}
+	}
+	return cephv1.MonHealthCondition{Status: cephv1.MonHealthy, Message: fmt.Sprintf("all %d mons are in quorum", mons)}
+}
+
+// MonHealthReport is the health of the mons as seen by the last mon health check
+type MonHealthReport struct {
+	// Condition is the mon health condition that is also reported in the status of the cluster
+	Condition cephv1.MonHealthCondition
+	// Scores are the scores of the mon endpoints by mon name from the last time the mons were scored
+	Scores map[string]MonScore
+}
+
+// MonHealthReport returns the health of the mons as seen by the last mon health check. The report
+// does not wait for the orchestration of the mons.
+func (c *Cluster) MonHealthReport() MonHealthReport {
+	c.healthReportMutex.RLock()
+	defer c.healthReportMutex.RUnlock()
+	report := MonHealthReport{Condition: c.healthReport.Condition, Scores: map[string]MonScore{}}
+	for name, score := range c.healthReport.Scores {
+		report.Scores[name] = score
+	}
+	return report
+}
+
+// reportMonHealth updates the mon health condition in the mon health report and in the cluster status
+// from the mon status
+func (c *Cluster) reportMonHealth(status *client.MonStatusResponse, desiredMonCount int) {
+	c.updateQuorumMetrics(status)
+	condition := monHealthCondition(status, desiredMonCount, c.degradedMons)
+	c.healthReportMutex.Lock()
+	c.healthReport.Condition = condition
+	c.healthReportMutex.Unlock()
+	if err := c.updateMonHealthCondition(condition); err != nil {
+		logger.Warningf("failed to report the mon health. %+v", err)
+	}
+}
+
+// updateMonHealthCondition writes the mon health condition to the status of the CephCluster that owns
+// the mons. The status is only updated when the condition changes.
+func (c *Cluster) updateMonHealthCondition(condition cephv1.MonHealthCondition) error {
+	if c.context.RookClientset == nil {
+		return nil
+	}
+
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster %s to update the mon health. %+v", c.ownerRef.Name, err)
+	}
+	current := cluster.Status.MonHealth
+	if current != nil && current.Status == condition.Status && current.Message == condition.Message {
+		return nil
+	}
+
+	condition.LastChanged = time.Now().UTC().Format(time.RFC3339)
+	cluster.Status.MonHealth = &condition
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cluster); err != nil {
+		return fmt.Errorf("failed to update the mon health of cluster %s. %+v", c.ownerRef.Name, err)
+	}
+	logger.Infof("mon health is %s: %s", condition.Status, condition.Message)
+	return nil
+}
+
+// monZoneCountCondition reports whether the mons are spread across enough zones to survive the outage
+// of a zone. The mons are keyed by name with their zone, which is empty if the node of the mon has
+// no zone label.
+func monZoneCountCondition(monZones map[string]string) cephv1.MonZoneCountCondition {
+	zones := map[string]bool{}
+	withoutZone := []string{}
+	for name, zone := range monZones {
+		if zone == "" {
+			withoutZone = append(withoutZone, name)
+			continue
+		}
+		zones[zone] = true
+	}
+	sort.Strings(withoutZone)
+
+	condition := cephv1.MonZoneCountCondition{Zones: len(zones), Sufficient: len(zones) >= 2}
+	switch {
+	case condition.Sufficient:
+		condition.Message = fmt.Sprintf("the mons span %d zones", len(zones))
+	case len(zones) == 1:
+		zone := ""
+		for z := range zones {
+			zone = z
+		}
+		condition.Message = fmt.Sprintf("all %d mons are in zone %s", len(monZones), zone)
+		if len(withoutZone) > 0 {
+			condition.Message = fmt.Sprintf("all the mons with a zone are in zone %s", zone)
+		}
+	default:
+		condition.Message = "the nodes of the mons have no zone label"
+	}
+	if len(zones) > 0 && len(withoutZone) > 0 {
+		condition.Message += fmt.Sprintf(". the nodes of mons %s have no zone label", strings.Join(withoutZone, ","))
+	}
+	return condition
+}
+
+// monZones returns the zone of each mon in the mapping. The zone the mon was placed in is saved in
+// the mapping, otherwise the zone is the zone label of the node of the mon.
+func (c *Cluster) monZones() (map[string]string, error) {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the nodes. %+v", err)
+	}
+	nodeZones := map[string]string{}
+	for _, node := range nodes.Items {
+		nodeZones[node.Name] = node.Labels[zoneLabel]
+	}
+
+	monZones := map[string]string{}
+	for name, info := range c.mapping.Node {
+		if info == nil {
+			continue
+		}
+		monZones[name] = info.Zone
+		if info.Zone == "" {
+			monZones[name] = nodeZones[info.Name]
+		}
+	}
+	return monZones, nil
+}
+
+// reportMonZoneCount updates the zone count condition in the cluster status. A Warning event is
+// recorded when the mons become confined to a single zone.
+func (c *Cluster) reportMonZoneCount() {
+	monZones, err := c.monZones()
+	if err != nil {
+		logger.Warningf("failed to get the zones of the mons. %+v", err)
+		return
+	}
+	condition := monZoneCountCondition(monZones)
+	changed, err := c.updateMonZoneCountCondition(condition)
+	if err != nil {
+		logger.Warningf("failed to report the zone count of the mons. %+v", err)
+		return
+	}
+	if changed && condition.Zones == 1 {
+		msg := fmt.Sprintf("%s. the mons do not survive the outage of the zone", condition.Message)
+		logger.Warningf(msg)
+		c.recordEvent(v1.EventTypeWarning, "MonZoneCountInsufficient", msg)
+	}
+}
+
+// updateMonZoneCountCondition writes the zone count condition to the status of the CephCluster that
+// owns the mons. The status is only updated when the condition changes, which is returned.
+func (c *Cluster) updateMonZoneCountCondition(condition cephv1.MonZoneCountCondition) (bool, error) {
+	if c.context.RookClientset == nil {
+		return false, nil
+	}
+
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get cluster %s to update the mon zone count. %+v", c.ownerRef.Name, err)
+	}
+	current := cluster.Status.MonZoneCountSufficient
+	if current != nil && current.Sufficient == condition.Sufficient && current.Zones == condition.Zones && current.Message == condition.Message {
+		return false, nil
+	}
+
+	condition.LastChanged = time.Now().UTC().Format(time.RFC3339)
+	cluster.Status.MonZoneCountSufficient = &condition
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cluster); err != nil {
+		return false, fmt.Errorf("failed to update the mon zone count of cluster %s. %+v", c.ownerRef.Name, err)
+	}
+	logger.Infof("mon zone count sufficient is %t: %s", condition.Sufficient, condition.Message)
+	return true, nil
+}
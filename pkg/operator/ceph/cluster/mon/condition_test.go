@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMonHealthCondition(t *testing.T) {
+	status := &client.MonStatusResponse{Quorum: []int{0, 1, 2}}
+	status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+
+	// healthy
+	condition := monHealthCondition(status, 3, nil)
+	assert.Equal(t, cephv1.MonHealthy, condition.Status)
+	assert.Equal(t, "all 3 mons are in quorum", condition.Message)
+
+	// degraded with a mon pending
+	condition = monHealthCondition(status, 5, nil)
+	assert.Equal(t, cephv1.MonDegraded, condition.Status)
+	assert.Equal(t, "2 of 5 mons are pending", condition.Message)
+
+	// degraded with a slow mon
+	condition = monHealthCondition(status, 3, map[string]string{"b": "high latency"})
+	assert.Equal(t, cephv1.MonDegraded, condition.Status)
+	assert.Equal(t, "mons b are degraded", condition.Message)
+
+	// degraded with one mon down
+	status.Quorum = []int{0, 2}
+	condition = monHealthCondition(status, 3, nil)
+	assert.Equal(t, cephv1.MonDegraded, condition.Status)
+	assert.Equal(t, "mons b are out of quorum", condition.Message)
+
+	// unavailable without quorum
+	status.Quorum = []int{0}
+	condition = monHealthCondition(status, 3, nil)
+	assert.Equal(t, cephv1.MonUnavailable, condition.Status)
+	assert.Equal(t, "no quorum with 1 of 3 mons", condition.Message)
+
+	// unavailable without a mon status
+	condition = monHealthCondition(nil, 3, nil)
+	assert.Equal(t, cephv1.MonUnavailable, condition.Status)
+}
+
+func TestUpdateMonHealthCondition(t *testing.T) {
+	rookClientset := rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	_, err := rookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.NoError(t, err)
+	c := newCluster(&clusterd.Context{Clientset: test.New(1), RookClientset: rookClientset}, "ns", false, false, v1.ResourceRequirements{})
+	c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+	monHealth := func() *cephv1.MonHealthCondition {
+		cluster, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cluster.Status.MonHealth
+	}
+
+	err = c.updateMonHealthCondition(cephv1.MonHealthCondition{Status: cephv1.MonHealthy, Message: "all 3 mons are in quorum"})
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.MonHealthy, monHealth().Status)
+	assert.NotEqual(t, "", monHealth().LastChanged)
+
+	// the status is not updated if the condition is the same
+	cluster, err = rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	assert.NoError(t, err)
+	cluster.Status.MonHealth.LastChanged = "earlier"
+	_, err = rookClientset.CephV1().CephClusters("ns").Update(cluster)
+	assert.NoError(t, err)
+	err = c.updateMonHealthCondition(cephv1.MonHealthCondition{Status: cephv1.MonHealthy, Message: "all 3 mons are in quorum"})
+	assert.NoError(t, err)
+	assert.Equal(t, "earlier", monHealth().LastChanged)
+
+	// a new condition replaces the previous one
+	err = c.updateMonHealthCondition(cephv1.MonHealthCondition{Status: cephv1.MonUnavailable, Message: "no quorum with 1 of 3 mons"})
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.MonUnavailable, monHealth().Status)
+	assert.Equal(t, "no quorum with 1 of 3 mons", monHealth().Message)
+	assert.NotEqual(t, "earlier", monHealth().LastChanged)
+}
+
+func TestReportMonHealth(t *testing.T) {
+	c := newCluster(&clusterd.Context{Clientset: test.New(1)}, "ns", false, false, v1.ResourceRequirements{})
+	status := client.MonStatusResponse{Quorum: []int{0}}
+	status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+
+	// the condition is in the health report
+	c.reportMonHealth(&status, 3)
+	report := c.MonHealthReport()
+	assert.Equal(t, cephv1.MonUnavailable, report.Condition.Status)
+	assert.Equal(t, "no quorum with 1 of 3 mons", report.Condition.Message)
+
+	c.reportMonHealth(nil, 3)
+	assert.Equal(t, "failed to get the mon status", c.MonHealthReport().Condition.Message)
+}
+
+func TestMonZoneCountCondition(t *testing.T) {
+	// mons in a single zone
+	condition := monZoneCountCondition(map[string]string{"a": "zone0", "b": "zone0", "c": "zone0"})
+	assert.False(t, condition.Sufficient)
+	assert.Equal(t, 1, condition.Zones)
+	assert.Equal(t, "all 3 mons are in zone zone0", condition.Message)
+
+	// mons in three zones
+	condition = monZoneCountCondition(map[string]string{"a": "zone0", "b": "zone1", "c": "zone2"})
+	assert.True(t, condition.Sufficient)
+	assert.Equal(t, 3, condition.Zones)
+	assert.Equal(t, "the mons span 3 zones", condition.Message)
+
+	// mons without a zone are not counted
+	condition = monZoneCountCondition(map[string]string{"a": "zone0", "b": "", "c": "zone0"})
+	assert.False(t, condition.Sufficient)
+	assert.Equal(t, "all the mons with a zone are in zone zone0. the nodes of mons b have no zone label", condition.Message)
+	condition = monZoneCountCondition(map[string]string{"a": "", "b": ""})
+	assert.False(t, condition.Sufficient)
+	assert.Equal(t, 0, condition.Zones)
+}
+
+func TestReportMonZoneCount(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	rookClientset := rookfake.NewSimpleClientset()
+	_, err := rookClientset.CephV1().CephClusters("ns").Create(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}})
+	assert.NoError(t, err)
+	c.context.RookClientset = rookClientset
+	c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	zoneCount := func() *cephv1.MonZoneCountCondition {
+		cluster, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cluster.Status.MonZoneCountSufficient
+	}
+
+	// the mons are in three zones
+	c.reportMonZoneCount()
+	assert.True(t, zoneCount().Sufficient)
+	assert.Equal(t, 3, zoneCount().Zones)
+	assert.Empty(t, recorder.Events)
+
+	// the zone of the node is used when the zone is not saved in the mapping
+	for _, info := range c.mapping.Node {
+		info.Zone = ""
+	}
+	c.reportMonZoneCount()
+	assert.True(t, zoneCount().Sufficient)
+
+	// a warning is recorded once when all the mons are in the same zone
+	for name, info := range c.mapping.Node {
+		if name != "a" {
+			info.Name = "node0"
+		}
+	}
+	c.reportMonZoneCount()
+	assert.False(t, zoneCount().Sufficient)
+	assert.Equal(t, 1, zoneCount().Zones)
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "MonZoneCountInsufficient")
+	c.reportMonZoneCount()
+	assert.Empty(t, recorder.Events)
+}
@@ -110,6 +110,10 @@ func CreateOrLoadClusterInfo(context *clusterd.Context, namespace string, ownerR
 			AdminSecret:   string(secrets.Data[adminSecretName]),
 		}
 		logger.Debugf("found existing monitor secrets for cluster %s", clusterInfo.Name)
+
+		if err := rebuildMonConfig(context, namespace, ownerRef); err != nil {
+			return nil, maxMonID, monMapping, err
+		}
 	}
 
 	// get the existing monitor config
@@ -216,8 +220,9 @@ func createClusterAccessSecret(clientset kubernetes.Interface, namespace string,
 	}
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      AppName,
-			Namespace: namespace,
+			Name:        AppName,
+			Namespace:   namespace,
+			Annotations: map[string]string{monKeyVersionAnnotation: initialMonKeyVersion},
 		},
 		Data: secrets,
 		Type: k8sutil.RookType,
@@ -255,6 +260,14 @@ func createNamedClusterInfo(context *clusterd.Context, clusterName string) (*cep
 		return nil, err
 	}
 
+	// refuse to bootstrap the cluster with weak keys
+	if err := validateMonKeyEntropy(monSecret); err != nil {
+		return nil, fmt.Errorf("invalid mon secret. %+v", err)
+	}
+	if err := validateMonKeyEntropy(adminSecret); err != nil {
+		return nil, fmt.Errorf("invalid admin secret. %+v", err)
+	}
+
 	return &cephconfig.ClusterInfo{
 		FSID:          fsid.String(),
 		MonitorSecret: monSecret,
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// lastKnownGoodMonConfigAnnotation is the annotation of the CephCluster with the data of the mon
+	// endpoint config map from the last time it was saved while the mons were in quorum
+	lastKnownGoodMonConfigAnnotation = "rook.io/last-known-good-mon-config"
+)
+
+// backupMonConfig saves the data of the mon endpoint config map in an annotation of the CephCluster
+// before the config map is changed. The config is only known to be good if the mons were in quorum at
+// the last health check.
+func (c *Cluster) backupMonConfig(configMap *v1.ConfigMap) error {
+	if c.context.RookClientset == nil || configMap == nil {
+		return nil
+	}
+
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster %s to back up the mon config. %+v", c.ownerRef.Name, err)
+	}
+	if cluster.Status.MonHealth == nil || cluster.Status.MonHealth.Status == cephv1.MonUnavailable {
+		logger.Debugf("not backing up the mon config without a mon quorum at the last health check")
+		return nil
+	}
+
+	data, err := json.Marshal(configMap.Data)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the mon config. %+v", err)
+	}
+	if cluster.Annotations[lastKnownGoodMonConfigAnnotation] == string(data) {
+		return nil
+	}
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[lastKnownGoodMonConfigAnnotation] = string(data)
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cluster); err != nil {
+		return fmt.Errorf("failed to back up the mon config to cluster %s. %+v", c.ownerRef.Name, err)
+	}
+	logger.Debugf("backed up the mon config to cluster %s", c.ownerRef.Name)
+	return nil
+}
+
+// rebuildMonConfig recreates the mon endpoint config map of an existing cluster from the backup in the
+// annotation of the CephCluster if the config map was deleted. Without the config map the operator
+// would start new mons that cannot join the existing cluster.
+func rebuildMonConfig(context *clusterd.Context, namespace string, ownerRef *metav1.OwnerReference) error {
+	if context.RookClientset == nil || ownerRef == nil {
+		return nil
+	}
+	_, err := context.Clientset.CoreV1().ConfigMaps(namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get mon endpoint config map. %+v", err)
+	}
+
+	cluster, err := context.RookClientset.CephV1().CephClusters(namespace).Get(ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster %s to restore the mon config. %+v", ownerRef.Name, err)
+	}
+	backup, ok := cluster.Annotations[lastKnownGoodMonConfigAnnotation]
+	if !ok {
+		return nil
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal([]byte(backup), &data); err != nil {
+		return fmt.Errorf("invalid mon config backup in cluster %s. %+v", ownerRef.Name, err)
+	}
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      EndpointConfigMapName,
+			Namespace: namespace,
+		},
+		Data: data,
+	}
+	k8sutil.SetOwnerRef(&configMap.ObjectMeta, ownerRef)
+	if _, err := context.Clientset.CoreV1().ConfigMaps(namespace).Create(configMap); err != nil {
+		return fmt.Errorf("failed to restore mon endpoint config map. %+v", err)
+	}
+	logger.Warningf("mon endpoint config map was not found. restored it from the last known good config in cluster %s", ownerRef.Name)
+	return nil
+}
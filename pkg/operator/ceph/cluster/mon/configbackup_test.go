@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackupMonConfig(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	rookClientset := rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"},
+		Status:     cephv1.ClusterStatus{MonHealth: &cephv1.MonHealthCondition{Status: cephv1.MonHealthy}},
+	}
+	_, err := rookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.NoError(t, err)
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset, RookClientset: rookClientset, ConfigDir: configDir}, "ns", false, false, v1.ResourceRequirements{})
+	c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+	c.ClusterInfo = test.CreateConfigDir(3)
+	backup := func() string {
+		cluster, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cluster.Annotations[lastKnownGoodMonConfigAnnotation]
+	}
+	configMapData := func() map[string]string {
+		cm, err := clientset.CoreV1().ConfigMaps("ns").Get(EndpointConfigMapName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cm.Data
+	}
+
+	// nothing to back up before the config map exists
+	_, err = c.saveMonConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "", backup())
+
+	// the config map is backed up before it is saved again
+	saved := configMapData()
+	delete(c.ClusterInfo.Monitors, "c")
+	_, err = c.saveMonConfig()
+	assert.NoError(t, err)
+	backedUp := map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(backup()), &backedUp))
+	assert.Equal(t, saved, backedUp)
+
+	// the config is not backed up without quorum
+	cluster, err = rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	assert.NoError(t, err)
+	cluster.Status.MonHealth.Status = cephv1.MonUnavailable
+	_, err = rookClientset.CephV1().CephClusters("ns").Update(cluster)
+	assert.NoError(t, err)
+	delete(c.ClusterInfo.Monitors, "b")
+	_, err = c.saveMonConfig()
+	assert.NoError(t, err)
+	backedUp = map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(backup()), &backedUp))
+	assert.Equal(t, saved, backedUp)
+}
+
+func TestRebuildMonConfig(t *testing.T) {
+	rookClientset := rookfake.NewSimpleClientset()
+	data := map[string]string{
+		EndpointDataKey: "a=1.2.3.1:6789,b=1.2.3.2:6789",
+		MaxMonIDKey:     "1",
+		MappingKey:      `{"node":{"a":{"Name":"node0","Hostname":"node0","Address":"1.2.3.1"}},"port":{}}`,
+	}
+	backup, err := json.Marshal(data)
+	assert.NoError(t, err)
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	_, err = rookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.NoError(t, err)
+	clientset := test.New(1)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookClientset}
+	ownerRef := &metav1.OwnerReference{Name: "rook-ceph"}
+
+	// nothing to restore without a backup
+	assert.NoError(t, rebuildMonConfig(context, "ns", ownerRef))
+	mons, _, _, err := loadMonConfig(clientset, "ns")
+	assert.NoError(t, err)
+	assert.Empty(t, mons)
+
+	// the deleted config map is restored from the backup
+	cluster.Annotations = map[string]string{lastKnownGoodMonConfigAnnotation: string(backup)}
+	_, err = rookClientset.CephV1().CephClusters("ns").Update(cluster)
+	assert.NoError(t, err)
+	assert.NoError(t, rebuildMonConfig(context, "ns", ownerRef))
+	mons, maxMonID, mapping, err := loadMonConfig(clientset, "ns")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mons))
+	assert.Equal(t, "1.2.3.2:6789", mons["b"].Endpoint)
+	assert.Equal(t, 1, maxMonID)
+	assert.Equal(t, "node0", mapping.Node["a"].Name)
+	cm, err := clientset.CoreV1().ConfigMaps("ns").Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "rook-ceph", cm.OwnerReferences[0].Name)
+
+	// an existing config map is not replaced
+	cm.Data[MaxMonIDKey] = "5"
+	_, err = clientset.CoreV1().ConfigMaps("ns").Update(cm)
+	assert.NoError(t, err)
+	assert.NoError(t, rebuildMonConfig(context, "ns", ownerRef))
+	_, maxMonID, _, err = loadMonConfig(clientset, "ns")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, maxMonID)
+
+	// an invalid backup is an error
+	assert.NoError(t, clientset.CoreV1().ConfigMaps("ns").Delete(EndpointConfigMapName, &metav1.DeleteOptions{}))
+	cluster.Annotations[lastKnownGoodMonConfigAnnotation] = "{"
+	_, err = rookClientset.CephV1().CephClusters("ns").Update(cluster)
+	assert.NoError(t, err)
+	assert.Error(t, rebuildMonConfig(context, "ns", ownerRef))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// monConfigHashAnnotation is the annotation on the mon deployments with the hash of the inputs the
+	// deployment was generated from
+	monConfigHashAnnotation = "rook.io/mon-config-hash"
+)
+
+// monConfigHashInputs are the settings that the mon deployment is generated from. If none of these
+// change, the deployment does not need to be updated.
+type monConfigHashInputs struct {
+	Mon         monConfig
+	Node        *NodeInfo
+	Image       string
+	RookVersion string
+	CephVersion cephver.CephVersion
+	FSID        string
+	// the mons read mon_host from the stored config when they start, so they are updated when the
+	// mon endpoints change
+	MonEndpoints string
+	HostNetwork  bool
+	CPUSet       string
+	DNSPolicy    *v1.DNSPolicy
+	DNSConfig    *v1.PodDNSConfig
+	Resources    v1.ResourceRequirements
+	Annotations  rookalpha.Annotations
+	Placement    rookalpha.Placement
+	PVCTemplate  bool
+	CheckStore   bool
+	RecoverFSID  bool
+	Liveness     int
+	PodSecurity  string
+	NonRoot      bool
+	KeyVersion   string
+}
+
+// computeMonConfigHash returns a hash of the settings the deployment of the mon is generated from
+func computeMonConfigHash(c *Cluster, m *monConfig) string {
+	inputs := monConfigHashInputs{
+		Mon:         *m,
+		Node:        c.mapping.Node[m.DaemonName],
+		Image:       c.spec.CephVersion.Image,
+		RookVersion: c.rookVersion,
+		HostNetwork: c.HostNetwork,
+		CPUSet:      c.spec.Mon.CPUSet,
+		DNSPolicy:   c.spec.Mon.DNSPolicy,
+		DNSConfig:   c.spec.Mon.DNSConfig,
+		Resources:   cephv1.GetMonResources(c.spec.Resources),
+		Annotations: cephv1.GetMonAnnotations(c.spec.Annotations),
+		Placement:   cephv1.GetMonPlacement(c.spec.Placement),
+		PVCTemplate: c.spec.Mon.VolumeClaimTemplate != nil,
+		CheckStore:  c.spec.Mon.CheckStoreOnStart,
+		RecoverFSID: c.spec.Mon.RecoverFSIDMismatch,
+		Liveness:    c.spec.Mon.QuorumLivenessTimeoutMinutes,
+		PodSecurity: c.spec.Mon.PodSecurityStandard,
+		NonRoot:     monRunAsNonRoot(c.spec.Mon),
+		KeyVersion:  c.monKeyVersion,
+	}
+	if c.ClusterInfo != nil {
+		inputs.CephVersion = c.ClusterInfo.CephVersion
+		inputs.FSID = c.ClusterInfo.FSID
+		inputs.MonEndpoints = sortMonEndpointsByScore(c.ClusterInfo.Monitors, nil, c.ClusterInfo.CephVersion)
+	}
+
+	// the inputs are only plain data and are always serializable
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		logger.Warningf("failed to serialize the config of mon %s. %+v", m.DaemonName, err)
+		return ""
+	}
+	return k8sutil.Hash(string(data))
+}
+
+// monConfigHashMatches returns whether the existing deployment was generated from the same settings
+// as the new one
+func monConfigHashMatches(existing, d *apps.Deployment) bool {
+	hash, ok := d.Annotations[monConfigHashAnnotation]
+	if !ok || hash == "" {
+		return false
+	}
+	return existing.Annotations[monConfigHashAnnotation] == hash
+}
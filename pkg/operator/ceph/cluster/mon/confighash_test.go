@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeMonConfigHash(t *testing.T) {
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.2"
+	m := &monConfig{ResourceName: "rook-ceph-mon-a", DaemonName: "a", Port: DefaultMsgr1Port}
+
+	hash := computeMonConfigHash(c, m)
+	assert.NotEmpty(t, hash)
+	assert.Equal(t, hash, computeMonConfigHash(c, m))
+
+	// the image changes the hash
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.3"
+	newHash := computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the resources change the hash
+	c.spec.Resources["mon"] = v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	newHash = computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the network mode changes the hash
+	c.HostNetwork = true
+	newHash = computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the mon settings change the hash
+	m.Port = 6790
+	newHash = computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the liveness probe changes the hash
+	c.spec.Mon.QuorumLivenessTimeoutMinutes = 5
+	newHash = computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the pod security standard changes the hash
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	newHash = computeMonConfigHash(c, m)
+	assert.NotEqual(t, hash, newHash)
+	hash = newHash
+
+	// the mon endpoints change the hash
+	c.ClusterInfo.Monitors["a"].Endpoint = "2.3.4.5:6789"
+	assert.NotEqual(t, hash, computeMonConfigHash(c, m))
+}
+
+func TestStartMonSkipsUnchangedDeployment(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: &exectest.MockExecutor{}}, "ns", false, true, v1.ResourceRequirements{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	m := c.newMonConfig(0)
+
+	// the deployment is annotated with the hash when it is created
+	err := c.startMon(m, "node0")
+	assert.Nil(t, err)
+	d, err := clientset.AppsV1().Deployments(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, computeMonConfigHash(c, m), d.Annotations[monConfigHashAnnotation])
+
+	// the deployment is not updated when nothing changed
+	err = c.startMon(m, "node0")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(*deploymentsUpdated))
+
+	// the deployment is updated when the image changes
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.3"
+	err = c.startMon(m, "node0")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{m.ResourceName}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/csi"
+)
+
+// checkCSIConfig verifies that each mon endpoint in the ceph-csi config of the cluster belongs to a
+// mon in the mon map, and saves the current endpoints to the ceph-csi config if any endpoint is
+// stale. The stale endpoints are returned.
+func (c *Cluster) checkCSIConfig(status client.MonStatusResponse) []string {
+	if !csi.CSIEnabled() {
+		return nil
+	}
+
+	endpoints, err := csi.GetClusterMonitors(c.context.Clientset, c.Namespace)
+	if err != nil {
+		logger.Warningf("failed to get the mon endpoints of the csi config. %+v", err)
+		return nil
+	}
+
+	monMapEndpoints := map[string]struct{}{}
+	for _, mon := range status.MonMap.Mons {
+		monMapEndpoints[monMapEndpoint(mon)] = struct{}{}
+	}
+	stale := []string{}
+	for _, endpoint := range endpoints {
+		if _, ok := monMapEndpoints[endpoint]; !ok {
+			stale = append(stale, endpoint)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	sort.Strings(stale)
+	logger.Warningf("the csi config has the mon endpoints %v that are not in the mon map. refreshing the csi config", stale)
+	if err := csi.SaveClusterConfig(c.context.Clientset, c.Namespace, c.ClusterInfo, c.csiConfigMutex); err != nil {
+		logger.Warningf("failed to refresh the csi config. %+v", err)
+	}
+	return stale
+}
+
+// monMapEndpoint returns the <ip>:<port> endpoint of the mon without the nonce of the mon map
+// address <ip>:<port>/<nonce>
+func monMapEndpoint(mon client.MonMapEntry) string {
+	return strings.Split(mon.Address, "/")[0]
+}
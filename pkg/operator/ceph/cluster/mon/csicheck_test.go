@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/csi"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckCSIConfig(t *testing.T) {
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-ceph-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+	enableRBD := csi.EnableRBD
+	defer func() { csi.EnableRBD = enableRBD }()
+
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(2)
+	c.csiConfigMutex = &sync.Mutex{}
+	status := client.MonStatusResponse{Quorum: []int{0, 1}}
+	status.MonMap.Mons = []client.MonMapEntry{
+		{Name: "a", Rank: 0, Address: "1.2.3.1:6789/0"},
+		{Name: "b", Rank: 1, Address: "1.2.3.2:6789/0"},
+	}
+
+	// the mon c that was failed over is still in the csi config
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: csi.ConfigName, Namespace: "rook-ceph-system"},
+		Data: map[string]string{
+			csi.ConfigKey: `[{"clusterID":"ns","monitors":["1.2.3.1:6789","1.2.3.3:6789"]},{"clusterID":"other","monitors":["5.6.7.8:6789"]}]`,
+		},
+	}
+	_, err := clientset.CoreV1().ConfigMaps("rook-ceph-system").Create(configMap)
+	assert.NoError(t, err)
+
+	// nothing is checked without csi
+	csi.EnableRBD = false
+	assert.Empty(t, c.checkCSIConfig(status))
+
+	// the stale endpoint is detected and the csi config is refreshed
+	csi.EnableRBD = true
+	assert.Equal(t, []string{"1.2.3.3:6789"}, c.checkCSIConfig(status))
+	monitors, err := csi.GetClusterMonitors(clientset, "ns")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1.2.3.1:6789", "1.2.3.2:6789"}, monitors)
+
+	// the other clusters are left alone
+	monitors, err = csi.GetClusterMonitors(clientset, "other")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5.6.7.8:6789"}, monitors)
+
+	// the refreshed config is up to date
+	assert.Empty(t, c.checkCSIConfig(status))
+}
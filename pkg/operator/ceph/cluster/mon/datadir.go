@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	monDataDirMigrationAppName = "rook-ceph-mon-data-dir-migration"
+)
+
+// MigrateMonDataDir moves the store of a mon from the data dir of one dataDirHostPath on the host to
+// the data dir of another without replacing the mon. It works like SwapMonStorage: the mon is
+// stopped while the other mons keep the quorum, a job on the node of the mon copies the store to the
+// new directory, and the mon is started on the new directory. If the store cannot be copied, the mon
+// is started again on its current directory. The old directory is left on the host.
+func (c *Cluster) MigrateMonDataDir(name, fromPath, toPath string) error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+	return c.migrateMonDataDir(name, fromPath, toPath)
+}
+
+// migrateMonDataDirs moves the store of each mon on the host that is not in the data dir of the
+// dataDirHostPath of the cluster, which happens when the dataDirHostPath is changed. The mons are
+// moved one at a time, and a mon is only stopped when all the mons are in quorum. The first mon
+// that cannot be moved stops the migration until the next orchestration.
+func (c *Cluster) migrateMonDataDirs() error {
+	names := []string{}
+	for name := range c.ClusterInfo.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(resourceName(name), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get the deployment of mon %s. %+v", name, err)
+		}
+		volume, ok := monDataVolume(d)
+		if !ok || volume.HostPath == nil {
+			continue
+		}
+
+		relativeDir := dataDirRelativeHostPath(name)
+		if volume.HostPath.Path == path.Join(c.dataDirHostPath, relativeDir) {
+			continue
+		}
+		// the store is only moved from the data dir of another dataDirHostPath
+		if !strings.HasSuffix(volume.HostPath.Path, "/"+relativeDir) {
+			logger.Warningf("mon %s stores its data in %s, which is not the data dir of a dataDirHostPath. the store is not moved", name, volume.HostPath.Path)
+			continue
+		}
+		fromPath := strings.TrimSuffix(volume.HostPath.Path, "/"+relativeDir)
+		logger.Infof("dataDirHostPath changed from %s to %s. moving the store of mon %s", fromPath, c.dataDirHostPath, name)
+		if err := c.migrateMonDataDir(name, fromPath, c.dataDirHostPath); err != nil {
+			return fmt.Errorf("failed to move the store of mon %s to dataDirHostPath %s. %+v", name, c.dataDirHostPath, err)
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) migrateMonDataDir(name, fromPath, toPath string) error {
+	if _, ok := c.ClusterInfo.Monitors[name]; !ok {
+		return fmt.Errorf("mon %s not found", name)
+	}
+	if fromPath == "" || toPath == "" {
+		return fmt.Errorf("the data dirs to migrate mon %s from and to are required", name)
+	}
+	oldDir := path.Join(fromPath, dataDirRelativeHostPath(name))
+	newDir := path.Join(toPath, dataDirRelativeHostPath(name))
+	if oldDir == newDir {
+		return fmt.Errorf("mon %s already stores its data in %s", name, newDir)
+	}
+
+	d, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(resourceName(name), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the deployment of mon %s. %+v", name, err)
+	}
+	volume, ok := monDataVolume(d)
+	if !ok || volume.HostPath == nil {
+		return fmt.Errorf("mon %s does not store its data on the host", name)
+	}
+	if volume.HostPath.Path != oldDir {
+		return fmt.Errorf("mon %s stores its data in %s, not in %s", name, volume.HostPath.Path, oldDir)
+	}
+
+	if _, err := c.waitForFullQuorum(); err != nil {
+		return fmt.Errorf("cannot safely stop mon %s. %+v", name, err)
+	}
+
+	logger.Infof("stopping mon %s to move its store from %s to %s", name, oldDir, newDir)
+	if err := c.scaleMonDeployment(d, 0); err != nil {
+		return fmt.Errorf("failed to stop mon %s. %+v", name, err)
+	}
+
+	dirType := v1.HostPathDirectoryOrCreate
+	job := c.makeMonStoreCopyJob(monDataDirMigrationAppName, name, d, v1.VolumeSource{
+		HostPath: &v1.HostPathVolumeSource{Path: newDir, Type: &dirType},
+	})
+	if err := c.runMonStoreCopyJob(job); err != nil {
+		logger.Errorf("failed to copy the store of mon %s. starting the mon on its current data dir. %+v", name, err)
+		if err := c.scaleMonDeployment(d, 1); err != nil {
+			logger.Errorf("failed to start mon %s again. %+v", name, err)
+		}
+		return fmt.Errorf("failed to copy the store of mon %s to %s. %+v", name, newDir, err)
+	}
+
+	logger.Infof("starting mon %s on %s", name, newDir)
+	useMonDataHostPath(&d.Spec.Template.Spec, newDir)
+	if err := c.scaleMonDeployment(d, 1); err != nil {
+		return fmt.Errorf("failed to start mon %s on %s. %+v", name, newDir, err)
+	}
+
+	status, err := c.waitForFullQuorum()
+	if err != nil {
+		return fmt.Errorf("quorum not restored after moving the store of mon %s. %+v", name, err)
+	}
+	if !monFoundInQuorum(name, status) {
+		return fmt.Errorf("mon %s not in quorum after moving its store", name)
+	}
+	logger.Infof("moved the store of mon %s to %s", name, newDir)
+	return nil
+}
+
+// useMonDataHostPath points the data volume of a mon pod on the host to the directory
+func useMonDataHostPath(podSpec *v1.PodSpec, dir string) {
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == monDataVolumeName && podSpec.Volumes[i].HostPath != nil {
+			podSpec.Volumes[i].HostPath = &v1.HostPathVolumeSource{Path: dir}
+		}
+	}
+}
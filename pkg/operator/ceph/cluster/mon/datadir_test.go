@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestMigrateMonDataDir(t *testing.T) {
+	defer func() { waitForMonStorageCopy = k8sutil.WaitForJobCompletion }()
+
+	events := []string{}
+	clientset := test.New(3)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon_status" {
+				events = append(events, "mon_status")
+				return `{"quorum":[0,1,2],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	c.spec.CephVersion.Image = "ceph/ceph:v14"
+	m := testGenMonConfig("a")
+	assert.NoError(t, c.startMon(m, "node0"))
+
+	var copyJob *batch.Job
+	copyErr := error(nil)
+	waitForMonStorageCopy = func(clientset kubernetes.Interface, job *batch.Job, timeout time.Duration) error {
+		// the mon is stopped while the store is copied
+		d, err := clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), *d.Spec.Replicas)
+		events = append(events, "copy")
+		copyJob = job
+		return copyErr
+	}
+
+	// the mon must exist and store its data in the old dir
+	assert.Error(t, c.MigrateMonDataDir("z", "/var/lib/rook", "/mnt/rook"))
+	assert.Error(t, c.MigrateMonDataDir("a", "/var/lib/other", "/mnt/rook"))
+	assert.Error(t, c.MigrateMonDataDir("a", "/var/lib/rook", "/var/lib/rook/"))
+	assert.Empty(t, events)
+
+	// the store is copied on the node of the mon to the new dir
+	assert.NoError(t, c.MigrateMonDataDir("a", "/var/lib/rook", "/mnt/rook"))
+	assert.Equal(t, []string{"mon_status", "copy", "mon_status"}, events)
+	assert.Equal(t, "rook-ceph-mon-data-dir-migration-a", copyJob.Name)
+	jobPod := copyJob.Spec.Template.Spec
+	assert.Equal(t, map[string]string{v1.LabelHostname: "node0"}, jobPod.NodeSelector)
+	assert.Equal(t, "/var/lib/rook/mon-a/data", jobPod.Volumes[0].HostPath.Path)
+	assert.Equal(t, "/mnt/rook/mon-a/data", jobPod.Volumes[1].HostPath.Path)
+	assert.Equal(t, "", jobPod.Containers[0].VolumeMounts[1].SubPath)
+
+	// the mon is started on the new dir and keeps it when the mons are reconciled
+	d, err := clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	volume, _ := monDataVolume(d)
+	assert.Equal(t, "/mnt/rook/mon-a/data", volume.HostPath.Path)
+	c.spec.Mon.CheckStoreOnStart = true
+	assert.NoError(t, c.startMon(m, "node0"))
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	volume, _ = monDataVolume(d)
+	assert.Equal(t, "/mnt/rook/mon-a/data", volume.HostPath.Path)
+
+	// the mon is started on its current dir if the copy fails
+	copyErr = fmt.Errorf("copy failed")
+	events = []string{}
+	assert.Error(t, c.MigrateMonDataDir("a", "/mnt/rook", "/mnt/other"))
+	assert.Equal(t, []string{"mon_status", "copy"}, events)
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	volume, _ = monDataVolume(d)
+	assert.Equal(t, "/mnt/rook/mon-a/data", volume.HostPath.Path)
+}
+
+func TestMigrateMonDataDirsOnHostPathChange(t *testing.T) {
+	defer func() { waitForMonStorageCopy = k8sutil.WaitForJobCompletion }()
+
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
+	clusterContext := newTestStartClusterWithQuorumResponse("ns", func() (string, error) {
+		return `{"quorum":[0,1,2],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`, nil
+	})
+	c := newCluster(clusterContext, "ns", false, true, v1.ResourceRequirements{})
+	c.dataDirHostPath = "/var/lib/rook"
+	c.spec.DataDirHostPath = "/var/lib/rook"
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+
+	monDir := func(name string) string {
+		d, err := clusterContext.Clientset.AppsV1().Deployments("ns").Get(resourceName(name), metav1.GetOptions{})
+		assert.NoError(t, err)
+		volume, _ := monDataVolume(d)
+		return volume.HostPath.Path
+	}
+	assert.Equal(t, "/var/lib/rook/mon-a/data", monDir("a"))
+
+	// the mons are moved one at a time to the new host path
+	copied := []string{}
+	waitForMonStorageCopy = func(clientset kubernetes.Interface, job *batch.Job, timeout time.Duration) error {
+		// the other mons are not stopped during the copy
+		for _, name := range []string{"a", "b", "c"} {
+			d, err := clientset.AppsV1().Deployments("ns").Get(resourceName(name), metav1.GetOptions{})
+			assert.NoError(t, err)
+			if name == job.Labels[monDaemonAttr] {
+				assert.Equal(t, int32(0), *d.Spec.Replicas)
+			} else {
+				assert.Equal(t, int32(1), *d.Spec.Replicas)
+			}
+		}
+		copied = append(copied, job.Spec.Template.Spec.Volumes[1].HostPath.Path)
+		return nil
+	}
+	c.spec.DataDirHostPath = "/mnt/rook"
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/mnt/rook/mon-a/data", "/mnt/rook/mon-b/data", "/mnt/rook/mon-c/data"}, copied)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, path.Join("/mnt/rook", "mon-"+name, "data"), monDir(name))
+	}
+
+	// the mon configs use the new host path
+	_, mons := c.initMonConfig(3)
+	for _, m := range mons {
+		assert.Equal(t, path.Join("/mnt/rook", "mon-"+m.DaemonName, "data"), m.DataPathMap.HostDataDir)
+	}
+
+	// the mons are not moved again
+	copied = []string{}
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	assert.Empty(t, copied)
+}
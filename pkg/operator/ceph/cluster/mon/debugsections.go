@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	// the range of the debug log levels of the ceph subsystems
+	minMonDebugLevel = 0
+	maxMonDebugLevel = 20
+)
+
+var monDebugSectionPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateMonDebugSections checks that the debug sections of the mon spec are ceph subsystem names
+// with a valid log level
+func validateMonDebugSections(sections map[string]int) error {
+	for section, level := range sections {
+		if !monDebugSectionPattern.MatchString(section) {
+			return fmt.Errorf("invalid mon debug section %q. the section must be the name of a ceph subsystem like \"paxos\"", section)
+		}
+		if level < minMonDebugLevel || level > maxMonDebugLevel {
+			return fmt.Errorf("invalid level %d of mon debug section %s. the level must be between %d and %d", level, section, minMonDebugLevel, maxMonDebugLevel)
+		}
+	}
+	return nil
+}
+
+// applyMonDebugSections sets the debug log level of each debug section of the mon spec in the mon
+// config. The sections that were applied before but are no longer in the spec are set back to 0.
+func (c *Cluster) applyMonDebugSections() error {
+	sections := []string{}
+	for section := range c.spec.Mon.DebugSections {
+		sections = append(sections, section)
+	}
+	for section := range c.monDebugSections {
+		if _, ok := c.spec.Mon.DebugSections[section]; !ok {
+			sections = append(sections, section)
+		}
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		level := c.spec.Mon.DebugSections[section]
+		key := "debug_" + section
+		if err := client.MonSetConfig(c.context, c.ClusterInfo.Name, key, strconv.Itoa(level)); err != nil {
+			return err
+		}
+		logger.Infof("set mon config %s=%d", key, level)
+		if _, ok := c.spec.Mon.DebugSections[section]; ok {
+			c.monDebugSections[section] = level
+		} else {
+			delete(c.monDebugSections, section)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateMonDebugSections(t *testing.T) {
+	assert.NoError(t, validateMonDebugSections(nil))
+	assert.NoError(t, validateMonDebugSections(map[string]int{"paxos": 10, "mon": 5, "ms": 0, "rocksdb": 20}))
+	assert.Error(t, validateMonDebugSections(map[string]int{"paxos": 21}))
+	assert.Error(t, validateMonDebugSections(map[string]int{"paxos": -1}))
+	assert.Error(t, validateMonDebugSections(map[string]int{"debug paxos": 10}))
+	assert.Error(t, validateMonDebugSections(map[string]int{"": 10}))
+
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	c.spec.Mon.DebugSections = map[string]int{"Paxos": 10}
+	assert.Error(t, c.validateSpec())
+}
+
+func TestApplyMonDebugSections(t *testing.T) {
+	commands := [][]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				commands = append(commands, args[:5])
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// nothing is set without debug sections
+	assert.NoError(t, c.applyMonDebugSections())
+	assert.Empty(t, commands)
+
+	// each section is set in the mon config
+	c.spec.Mon.DebugSections = map[string]int{"paxos": 10, "mon": 5}
+	assert.NoError(t, c.applyMonDebugSections())
+	assert.Equal(t, [][]string{
+		{"config", "set", "mon", "debug_mon", "5"},
+		{"config", "set", "mon", "debug_paxos", "10"},
+	}, commands)
+
+	// a removed section is reset to 0 once
+	commands = [][]string{}
+	c.spec.Mon.DebugSections = map[string]int{"mon": 5}
+	assert.NoError(t, c.applyMonDebugSections())
+	assert.Equal(t, [][]string{
+		{"config", "set", "mon", "debug_mon", "5"},
+		{"config", "set", "mon", "debug_paxos", "0"},
+	}, commands)
+	commands = [][]string{}
+	c.spec.Mon.DebugSections = nil
+	assert.NoError(t, c.applyMonDebugSections())
+	assert.Equal(t, [][]string{{"config", "set", "mon", "debug_mon", "0"}}, commands)
+	commands = [][]string{}
+	assert.NoError(t, c.applyMonDebugSections())
+	assert.Empty(t, commands)
+
+	// the sections removed in a spec change are reset after an operator restart
+	c = newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	oldSpec := cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 3, DebugSections: map[string]int{"paxos": 10}}}
+	newSpec := cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 3}}
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.Equal(t, [][]string{{"config", "set", "mon", "debug_paxos", "0"}}, commands)
+}
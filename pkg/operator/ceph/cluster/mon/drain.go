@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/util"
+)
+
+// DrainPlan describes the effect on the mons of draining a set of nodes
+type DrainPlan struct {
+	// AffectedMons are the mons assigned to the drained nodes
+	AffectedMons []string
+	// RemainingQuorum is the number of mons in quorum that are not on the drained nodes
+	RemainingQuorum int
+	// QuorumSize is the number of mons needed to form a quorum
+	QuorumSize int
+	// Safe is whether the mons keep quorum while all the nodes are drained at once
+	Safe bool
+}
+
+// PlanNodeDrain reports the mons that would be affected by draining the nodes and whether the mons
+// would keep quorum if the nodes were all drained at once. The nodes are matched by node name or
+// hostname.
+func (c *Cluster) PlanNodeDrain(nodes []string) (*DrainPlan, error) {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mon status. %+v", err)
+	}
+
+	drained := util.CreateSet(nodes)
+	plan := &DrainPlan{
+		AffectedMons: []string{},
+		QuorumSize:   len(status.MonMap.Mons)/2 + 1,
+	}
+	for _, mon := range status.MonMap.Mons {
+		if nodeInfo, ok := c.mapping.Node[mon.Name]; ok {
+			if drained.Contains(nodeInfo.Name) || (nodeInfo.Hostname != "" && drained.Contains(nodeInfo.Hostname)) {
+				plan.AffectedMons = append(plan.AffectedMons, mon.Name)
+				continue
+			}
+		}
+		if monInQuorum(mon, status.Quorum) {
+			plan.RemainingQuorum++
+		}
+	}
+	sort.Strings(plan.AffectedMons)
+	plan.Safe = plan.RemainingQuorum >= plan.QuorumSize
+
+	logger.Infof("draining nodes %v affects mons %v. %d of the %d mons needed for quorum would remain",
+		nodes, plan.AffectedMons, plan.RemainingQuorum, plan.QuorumSize)
+	return plan, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestPlanNodeDrain(t *testing.T) {
+	clusterInfo := test.CreateConfigDir(3)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponseFromMons(clusterInfo.Monitors), nil
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(3), Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = clusterInfo
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0", Hostname: "host0"}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "host1"}
+	c.mapping.Node["c"] = &NodeInfo{Name: "node2", Hostname: "host2"}
+
+	// draining one of the three mon nodes keeps quorum
+	plan, err := c.PlanNodeDrain([]string{"node0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, plan.AffectedMons)
+	assert.Equal(t, 2, plan.RemainingQuorum)
+	assert.Equal(t, 2, plan.QuorumSize)
+	assert.True(t, plan.Safe)
+
+	// draining two of the three mon nodes at once breaks quorum
+	plan, err = c.PlanNodeDrain([]string{"host2", "node0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, plan.AffectedMons)
+	assert.Equal(t, 1, plan.RemainingQuorum)
+	assert.False(t, plan.Safe)
+
+	// nodes without mons
+	plan, err = c.PlanNodeDrain([]string{"node3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, plan.AffectedMons)
+	assert.True(t, plan.Safe)
+}
@@ -18,34 +18,215 @@ package mon
 
 import (
 	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephutil "github.com/rook/rook/pkg/daemon/ceph/util"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+const (
+	msgr1AddrPrefix = "v1:"
+	msgr2AddrPrefix = "v2:"
 )
 
 // FlattenMonEndpoints returns a comma-delimited string of all mons and endpoints in the form
-// <mon-name>=<mon-endpoint>
-func FlattenMonEndpoints(mons map[string]*cephconfig.MonInfo) string {
+// <mon-name>=<mon-endpoint>. On Nautilus and newer the endpoint of a mon on the default port includes
+// the msgr2 address in the form <mon-name>=[v2:<ip>:3300,v1:<ip>:6789].
+func FlattenMonEndpoints(mons map[string]*cephconfig.MonInfo, cephVersion cephver.CephVersion) string {
+	endpoints := []string{}
+	for _, m := range mons {
+		endpoints = append(endpoints, formatMonEndpoint(m, cephVersion))
+	}
+	return strings.Join(endpoints, ",")
+}
+
+// formatMonEndpoint returns the <mon-name>=<mon-endpoint> form of a single mon for FlattenMonEndpoints
+func formatMonEndpoint(m *cephconfig.MonInfo, cephVersion cephver.CephVersion) string {
+	// mons on another port (e.g. with host networking) don't listen on the default msgr2 port, and
+	// clients before nautilus cannot parse the msgr2 address
+	if !cephVersion.IsAtLeastNautilus() || cephutil.GetPortFromEndpoint(m.Endpoint) != DefaultMsgr1Port {
+		return fmt.Sprintf("%s=%s", m.Name, m.Endpoint)
+	}
+	ip := cephutil.GetIPFromEndpoint(m.Endpoint)
+	msgr2Endpoint := net.JoinHostPort(ip, strconv.Itoa(int(DefaultMsgr2Port)))
+	return fmt.Sprintf("%s=[%s%s,%s%s]", m.Name, msgr2AddrPrefix, msgr2Endpoint, msgr1AddrPrefix, m.Endpoint)
+}
+
+// legacyMonEndpoints returns the flattened mons in the same order in the <mon-name>=<ip>:<port> form
+// with only the msgr1 endpoint of each mon. Unlike the address vectors, this form can be split at
+// every comma, as the daemons and agents of previous versions of rook do.
+func legacyMonEndpoints(endpoints string) string {
+	if endpoints == "" {
+		return endpoints
+	}
+	legacy := []string{}
+	for _, rawMon := range splitMonEndpoints(endpoints) {
+		parts := strings.SplitN(rawMon, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		endpoint, err := parseMonAddrs(parts[1])
+		if err != nil {
+			continue
+		}
+		legacy = append(legacy, fmt.Sprintf("%s=%s", parts[0], endpoint))
+	}
+	return strings.Join(legacy, ",")
+}
+
+// shuffleMonEndpoints returns the same form as FlattenMonEndpoints, but with the mons in a random
+// order. The order is deterministic for a given seed so that the same configmap generation always
+// produces the same endpoint order.
+func shuffleMonEndpoints(mons map[string]*cephconfig.MonInfo, cephVersion cephver.CephVersion, seed int64) string {
 	endpoints := []string{}
 	for _, m := range mons {
-		endpoints = append(endpoints, fmt.Sprintf("%s=%s", m.Name, m.Endpoint))
+		endpoints = append(endpoints, formatMonEndpoint(m, cephVersion))
 	}
+	// sort first since map iteration order would otherwise make the shuffle non-deterministic
+	sort.Strings(endpoints)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(endpoints), func(i, j int) {
+		endpoints[i], endpoints[j] = endpoints[j], endpoints[i]
+	})
 	return strings.Join(endpoints, ",")
 }
 
+// orderMonEndpointsByIPVersion moves the mons with an endpoint of the preferred IP version to the
+// front of the flattened mon endpoints of a dual-stack cluster. The mons of the same IP version keep
+// their order, so the endpoints remain sorted by score or shuffled.
+func orderMonEndpointsByIPVersion(endpoints string, preferIPv6 bool) string {
+	if endpoints == "" {
+		return endpoints
+	}
+	preferred := []string{}
+	others := []string{}
+	for _, rawMon := range splitMonEndpoints(endpoints) {
+		if isIPv6MonEndpoint(rawMon) == preferIPv6 {
+			preferred = append(preferred, rawMon)
+		} else {
+			others = append(others, rawMon)
+		}
+	}
+	return strings.Join(append(preferred, others...), ",")
+}
+
+// isIPv6MonEndpoint returns whether the endpoint of a mon in the <mon-name>=<mon-endpoint> form is an
+// IPv6 address
+func isIPv6MonEndpoint(rawMon string) bool {
+	parts := strings.SplitN(rawMon, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	endpoint, err := parseMonAddrs(parts[1])
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(cephutil.GetIPFromEndpoint(endpoint))
+	return ip != nil && ip.To4() == nil
+}
+
 // ParseMonEndpoints parses a flattened representation of mons and endpoints in the form
-// <mon-name>=<mon-endpoint> and returns a list of Ceph mon configs.
+// <mon-name>=<mon-endpoint> and returns a list of Ceph mon configs. The endpoints with both the
+// msgr2 and msgr1 addresses are accepted, in which case the msgr1 address is the endpoint of the mon.
 func ParseMonEndpoints(input string) map[string]*cephconfig.MonInfo {
 	logger.Infof("parsing mon endpoints: %s", input)
 	mons := map[string]*cephconfig.MonInfo{}
-	rawMons := strings.Split(input, ",")
-	for _, rawMon := range rawMons {
-		parts := strings.Split(rawMon, "=")
+	for _, rawMon := range splitMonEndpoints(input) {
+		parts := strings.SplitN(rawMon, "=", 2)
 		if len(parts) != 2 {
 			logger.Warningf("ignoring invalid monitor %s", rawMon)
 			continue
 		}
-		mons[parts[0]] = &cephconfig.MonInfo{Name: parts[0], Endpoint: parts[1]}
+		endpoint, err := parseMonAddrs(parts[1])
+		if err != nil {
+			logger.Warningf("ignoring invalid monitor %s. %+v", rawMon, err)
+			continue
+		}
+		mons[parts[0]] = &cephconfig.MonInfo{Name: parts[0], Endpoint: endpoint}
 	}
 	return mons
 }
+
+// splitMonEndpoints splits the flattened mons at the commas that are not inside the brackets of an
+// address vector
+func splitMonEndpoints(input string) []string {
+	rawMons := []string{}
+	depth := 0
+	start := 0
+	for i, r := range input {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				rawMons = append(rawMons, input[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(rawMons, input[start:])
+}
+
+// parseMonAddrs returns the msgr1 endpoint of a mon from either the bare <ip>:<port> form or the
+// address vector form [v2:<ip>:<port>,v1:<ip>:<port>]. A bare IPv6 endpoint [<ip>]:<port> is not an
+// address vector.
+func parseMonAddrs(addrs string) (string, error) {
+	if !strings.HasPrefix(addrs, "["+msgr2AddrPrefix) && !strings.HasPrefix(addrs, "["+msgr1AddrPrefix) {
+		return addrs, nil
+	}
+	if !strings.HasSuffix(addrs, "]") {
+		return "", fmt.Errorf("unterminated address vector")
+	}
+	for _, addr := range strings.Split(addrs[1:len(addrs)-1], ",") {
+		if strings.HasPrefix(addr, msgr1AddrPrefix) {
+			return strings.TrimPrefix(addr, msgr1AddrPrefix), nil
+		}
+	}
+	return "", fmt.Errorf("no msgr1 address")
+}
+
+// MonEndpointsDiff is the change of the mon endpoints between two saves of the mon endpoints. The
+// maps are keyed by the mon name.
+type MonEndpointsDiff struct {
+	// Added are the endpoints of the mons that were not in the previous endpoints
+	Added map[string]string
+	// Removed are the previous endpoints of the mons that are gone
+	Removed map[string]string
+	// Changed are the new endpoints of the mons whose endpoint changed
+	Changed map[string]string
+}
+
+// Empty returns whether none of the mon endpoints changed
+func (d *MonEndpointsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffMonEndpoints returns the change from the previous to the current mon endpoints
+func diffMonEndpoints(previous, current map[string]*cephconfig.MonInfo) *MonEndpointsDiff {
+	diff := &MonEndpointsDiff{
+		Added:   map[string]string{},
+		Removed: map[string]string{},
+		Changed: map[string]string{},
+	}
+	for name, mon := range current {
+		old, ok := previous[name]
+		if !ok {
+			diff.Added[name] = mon.Endpoint
+		} else if old.Endpoint != mon.Endpoint {
+			diff.Changed[name] = mon.Endpoint
+		}
+	}
+	for name, mon := range previous {
+		if _, ok := current[name]; !ok {
+			diff.Removed[name] = mon.Endpoint
+		}
+	}
+	return diff
+}
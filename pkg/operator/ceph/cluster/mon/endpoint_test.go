@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,7 +30,7 @@ func TestMonFlattening(t *testing.T) {
 	mons := map[string]*cephconfig.MonInfo{
 		"foo": {Name: "foo", Endpoint: "1.2.3.4:5000"},
 	}
-	flattened := FlattenMonEndpoints(mons)
+	flattened := FlattenMonEndpoints(mons, cephver.Mimic)
 	assert.Equal(t, "foo=1.2.3.4:5000", flattened)
 	parsed := ParseMonEndpoints(flattened)
 	assert.Equal(t, 1, len(parsed))
@@ -38,7 +39,7 @@ func TestMonFlattening(t *testing.T) {
 
 	// multiple endpoints
 	mons["bar"] = &cephconfig.MonInfo{Name: "bar", Endpoint: "2.3.4.5:6000"}
-	flattened = FlattenMonEndpoints(mons)
+	flattened = FlattenMonEndpoints(mons, cephver.Mimic)
 	parsed = ParseMonEndpoints(flattened)
 	assert.Equal(t, 2, len(parsed))
 	assert.Equal(t, "foo", parsed["foo"].Name)
@@ -46,3 +47,94 @@ func TestMonFlattening(t *testing.T) {
 	assert.Equal(t, "bar", parsed["bar"].Name)
 	assert.Equal(t, "2.3.4.5:6000", parsed["bar"].Endpoint)
 }
+
+func TestMonFlatteningMsgr2(t *testing.T) {
+	mons := map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.2.3.1:6789"},
+	}
+
+	// nautilus includes the msgr2 address for mons on the default port
+	flattened := FlattenMonEndpoints(mons, cephver.Nautilus)
+	assert.Equal(t, "a=[v2:1.2.3.1:3300,v1:1.2.3.1:6789]", flattened)
+
+	// mons on other ports only have the msgr1 address
+	mons["b"] = &cephconfig.MonInfo{Name: "b", Endpoint: "1.2.3.2:5000"}
+	flattened = FlattenMonEndpoints(mons, cephver.Nautilus)
+	assert.Contains(t, flattened, "b=1.2.3.2:5000")
+
+	// both forms are parsed back to the msgr1 endpoint
+	parsed := ParseMonEndpoints(flattened)
+	assert.Equal(t, 2, len(parsed))
+	assert.Equal(t, "a", parsed["a"].Name)
+	assert.Equal(t, "1.2.3.1:6789", parsed["a"].Endpoint)
+	assert.Equal(t, "b", parsed["b"].Name)
+	assert.Equal(t, "1.2.3.2:5000", parsed["b"].Endpoint)
+
+	// invalid address vectors are ignored
+	parsed = ParseMonEndpoints("b=[v2:1.2.3.2:3300],c=1.2.3.3:6789")
+	assert.Equal(t, 1, len(parsed))
+	assert.Equal(t, "1.2.3.3:6789", parsed["c"].Endpoint)
+	assert.Equal(t, 0, len(ParseMonEndpoints("a=[v2:1.2.3.1:3300,v1:1.2.3.1:6789")))
+}
+
+func TestShuffleMonEndpoints(t *testing.T) {
+	mons := map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.2.3.1:6789"},
+		"b": {Name: "b", Endpoint: "1.2.3.2:6789"},
+		"c": {Name: "c", Endpoint: "1.2.3.3:6789"},
+		"d": {Name: "d", Endpoint: "1.2.3.4:6789"},
+	}
+
+	// the same seed always results in the same order
+	shuffled := shuffleMonEndpoints(mons, cephver.Mimic, 1)
+	assert.Equal(t, shuffled, shuffleMonEndpoints(mons, cephver.Mimic, 1))
+
+	// all the mons are still present after the shuffle
+	parsed := ParseMonEndpoints(shuffled)
+	assert.Equal(t, 4, len(parsed))
+	for name, m := range mons {
+		assert.Equal(t, m.Endpoint, parsed[name].Endpoint)
+	}
+
+	// different seeds eventually result in a different order
+	different := false
+	for seed := int64(2); seed < 10; seed++ {
+		if shuffleMonEndpoints(mons, cephver.Mimic, seed) != shuffled {
+			different = true
+			break
+		}
+	}
+	assert.True(t, different)
+}
+
+func TestOrderMonEndpointsByIPVersion(t *testing.T) {
+	// a dual-stack cluster with the mons sorted by score
+	endpoints := "a=10.0.0.1:6789,b=[v2:[fd00::2]:3300,v1:[fd00::2]:6789],c=[fd00::3]:5000,d=[v2:10.0.0.4:3300,v1:10.0.0.4:6789]"
+
+	// the ipv4 mons are first by default and the order of the mons of each ip version is kept
+	assert.Equal(t, "a=10.0.0.1:6789,d=[v2:10.0.0.4:3300,v1:10.0.0.4:6789],b=[v2:[fd00::2]:3300,v1:[fd00::2]:6789],c=[fd00::3]:5000",
+		orderMonEndpointsByIPVersion(endpoints, false))
+	assert.Equal(t, "b=[v2:[fd00::2]:3300,v1:[fd00::2]:6789],c=[fd00::3]:5000,a=10.0.0.1:6789,d=[v2:10.0.0.4:3300,v1:10.0.0.4:6789]",
+		orderMonEndpointsByIPVersion(endpoints, true))
+
+	// the order of a single stack cluster is not changed
+	ipv4 := "c=10.0.0.3:6789,a=10.0.0.1:6789"
+	assert.Equal(t, ipv4, orderMonEndpointsByIPVersion(ipv4, true))
+	ipv6 := "c=[fd00::3]:6789,a=[fd00::1]:6789"
+	assert.Equal(t, ipv6, orderMonEndpointsByIPVersion(ipv6, false))
+	assert.Equal(t, "", orderMonEndpointsByIPVersion("", true))
+}
+
+func TestLegacyMonEndpoints(t *testing.T) {
+	// the msgr1 addresses are kept in the same order
+	endpoints := "d=[v2:10.0.0.4:3300,v1:10.0.0.4:6789],a=10.0.0.1:6790,b=[v2:[fd00::2]:3300,v1:[fd00::2]:6789],c=[fd00::3]:6789"
+	assert.Equal(t, "d=10.0.0.4:6789,a=10.0.0.1:6790,b=[fd00::2]:6789,c=[fd00::3]:6789", legacyMonEndpoints(endpoints))
+
+	// the legacy form is not changed
+	legacy := "b=10.0.0.2:6789,a=10.0.0.1:6789"
+	assert.Equal(t, legacy, legacyMonEndpoints(legacy))
+	assert.Equal(t, "", legacyMonEndpoints(""))
+
+	// invalid mons are dropped
+	assert.Equal(t, "a=10.0.0.1:6789", legacyMonEndpoints("a=10.0.0.1:6789,b=[v2:10.0.0.2:3300]"))
+}
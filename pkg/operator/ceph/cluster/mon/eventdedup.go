@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"time"
+)
+
+const (
+	// EventDeduplicationInterval is how long an event that was already recorded for a mon is
+	// suppressed while its message does not change
+	EventDeduplicationInterval = 30 * time.Minute
+)
+
+// monEvent is the last event recorded for a mon with a given reason
+type monEvent struct {
+	message  string
+	recorded time.Time
+}
+
+// recordMonEvent records an event about a mon unless the same message was recorded for the mon and
+// reason within the deduplication interval. A mon that stays unhealthy would otherwise record the
+// same event on every health check.
+func (c *Cluster) recordMonEvent(name, eventType, reason, message string) {
+	if c.monEvents == nil {
+		c.monEvents = map[string]map[string]*monEvent{}
+	}
+	events, ok := c.monEvents[name]
+	if !ok {
+		events = map[string]*monEvent{}
+		c.monEvents[name] = events
+	}
+
+	now := time.Now()
+	if last, ok := events[reason]; ok && last.message == message && now.Sub(last.recorded) < EventDeduplicationInterval {
+		logger.Debugf("not recording the repeated %s event for mon %s", reason, name)
+		return
+	}
+	events[reason] = &monEvent{message: message, recorded: now}
+	c.recordEvent(eventType, reason, message)
+}
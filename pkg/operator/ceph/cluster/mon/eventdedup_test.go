@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordMonEvent(t *testing.T) {
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	recorder := record.NewFakeRecorder(100)
+	c.recorder = recorder
+
+	// the same event is recorded only once however many times the mon is checked
+	for i := 0; i < 20; i++ {
+		c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid foreign")
+	}
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "Warning MonFSIDMismatch mon a has a store with fsid foreign")
+
+	// other mons and reasons are tracked on their own
+	c.recordMonEvent("b", v1.EventTypeWarning, "MonFSIDMismatch", "mon b has a store with fsid foreign")
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonStoreCorrupted", "the store of mon a failed the consistency check")
+	assert.Equal(t, 2, len(recorder.Events))
+	<-recorder.Events
+	<-recorder.Events
+
+	// a changed message is recorded right away
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid other")
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid other")
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "fsid other")
+
+	// the event is recorded again once the interval is past
+	c.monEvents["a"]["MonFSIDMismatch"].recorded = time.Now().Add(-EventDeduplicationInterval - time.Second)
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid other")
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid other")
+	assert.Equal(t, 1, len(recorder.Events))
+	<-recorder.Events
+
+	// the events of a removed mon are forgotten
+	delete(c.monEvents, "a")
+	c.recordMonEvent("a", v1.EventTypeWarning, "MonFSIDMismatch", "mon a has a store with fsid other")
+	assert.Equal(t, 1, len(recorder.Events))
+}
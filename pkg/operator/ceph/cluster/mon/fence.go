@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monFencedTaintKey is the key of the taint added to the node of a fenced mon. The value is the
+	// name of the mon. The mons never tolerate the taint, so the pod of the fenced mon cannot start on
+	// the node again.
+	monFencedTaintKey = "ceph.rook.io/fenced-mon"
+)
+
+// FenceMon stops a mon that is suspected of causing a split brain. The pods of the mon are deleted
+// without a grace period, the node of the mon is tainted so that no mon is scheduled on it again, and
+// the mon is removed from the monmap. The mon is then failed over by the next health check since it
+// is no longer in the monmap.
+func FenceMon(ctx context.Context, cluster *Cluster, monID string) error {
+	cluster.acquireOrchestrationLock()
+	defer cluster.releaseOrchestrationLock()
+
+	if _, ok := cluster.ClusterInfo.Monitors[monID]; !ok {
+		return fmt.Errorf("cannot fence unknown mon %s", monID)
+	}
+	logger.Warningf("fencing mon %s", monID)
+
+	if err := forceDeleteMonPods(cluster, monID); err != nil {
+		return fmt.Errorf("failed to fence mon %s. %+v", monID, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if node, ok := cluster.mapping.Node[monID]; ok && node != nil && node.Name != "" {
+		if err := taintFencedMonNode(cluster, node.Name, monID); err != nil {
+			return fmt.Errorf("failed to fence mon %s. %+v", monID, err)
+		}
+	} else {
+		logger.Infof("mon %s is not assigned to a node. not tainting a node", monID)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := removeMonitorFromQuorum(cluster.context, cluster.ClusterInfo.Name, monID); err != nil {
+		return fmt.Errorf("failed to fence mon %s. %+v", monID, err)
+	}
+	cluster.recordEvent(v1.EventTypeWarning, "MonFenced", fmt.Sprintf("mon %s was fenced and removed from the monmap", monID))
+	return nil
+}
+
+// forceDeleteMonPods deletes the pods of the mon without waiting for the mon to shut down
+func forceDeleteMonPods(cluster *Cluster, monID string) error {
+	selector := MonLabelSelector(cluster.Namespace, monID).String()
+	pods, err := cluster.context.Clientset.CoreV1().Pods(cluster.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of mon %s. %+v", monID, err)
+	}
+	var gracePeriod int64
+	for _, pod := range pods.Items {
+		err := cluster.context.Clientset.CoreV1().Pods(cluster.Namespace).Delete(pod.Name, &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s of mon %s. %+v", pod.Name, monID, err)
+		}
+		logger.Infof("deleted pod %s of mon %s", pod.Name, monID)
+	}
+	return nil
+}
+
+// taintFencedMonNode adds the NoSchedule taint of the fenced mon to the node. A node that is already
+// tainted for a fenced mon keeps its taint.
+func taintFencedMonNode(cluster *Cluster, nodeName, monID string) error {
+	node, err := cluster.context.Clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node %s of mon %s. %+v", nodeName, monID, err)
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == monFencedTaintKey && taint.Effect == v1.TaintEffectNoSchedule {
+			logger.Infof("node %s is already tainted for fenced mon %s", nodeName, taint.Value)
+			return nil
+		}
+	}
+	node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: monFencedTaintKey, Value: monID, Effect: v1.TaintEffectNoSchedule})
+	if _, err := cluster.context.Clientset.CoreV1().Nodes().Update(node); err != nil {
+		return fmt.Errorf("failed to taint node %s of mon %s. %+v", nodeName, monID, err)
+	}
+	logger.Infof("tainted node %s of fenced mon %s", nodeName, monID)
+	return nil
+}
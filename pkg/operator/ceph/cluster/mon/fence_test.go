@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestFenceMon(t *testing.T) {
+	steps := []string{}
+	clientset := test.New(3)
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		steps = append(steps, "delete pod "+action.(k8stesting.DeleteActionImpl).Name)
+		return false, nil, nil
+	})
+	clientset.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		steps = append(steps, "taint "+action.(k8stesting.UpdateActionImpl).Object.(*v1.Node).Name)
+		return false, nil, nil
+	})
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon" && args[1] == "remove" {
+				steps = append(steps, strings.Join(args[:3], " "))
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	c.recorder = record.NewFakeRecorder(10)
+	c.mapping.Node["a"] = &NodeInfo{Name: "node1", Hostname: "node1"}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-a-123", Namespace: "ns", Labels: MonLabels("ns", "a")}}
+	_, err := clientset.CoreV1().Pods("ns").Create(pod)
+	assert.NoError(t, err)
+
+	// the mon must exist
+	assert.Error(t, FenceMon(context.Background(), c, "z"))
+	assert.Empty(t, steps)
+
+	// the pod is deleted, the node is tainted and the mon is removed in that order
+	assert.NoError(t, FenceMon(context.Background(), c, "a"))
+	assert.Equal(t, []string{"delete pod rook-ceph-mon-a-123", "taint node1", "mon remove a"}, steps)
+	node, err := clientset.CoreV1().Nodes().Get("node1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.Taint{{Key: monFencedTaintKey, Value: "a", Effect: v1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	pods, err := clientset.CoreV1().Pods("ns").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, pods.Items)
+
+	// a node that is already tainted is not tainted again
+	steps = []string{}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "node1"}
+	assert.NoError(t, FenceMon(context.Background(), c, "b"))
+	assert.Equal(t, []string{"mon remove b"}, steps)
+
+	// the mon is not removed if the fencing is canceled
+	steps = []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, FenceMon(ctx, c, "c"))
+	assert.Equal(t, []string{}, steps)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// the results reported by the fsid check init container in its termination message
+	monStoreFSIDMismatch = "mismatch"
+	monStoreFSIDWiped    = "wiped"
+)
+
+// makeMonStoreFSIDCheckInitContainer makes the init container that reads the fsid from the monmap in
+// the store of the mon before the store is created by the mkfs. A mon cannot be asked for its fsid
+// when its store belongs to another cluster, for example because the data dir of a previous cluster
+// was reused, since it cannot authenticate with the cluster. The check fails on such a store so the
+// mon daemon is never started on it, unless the recovery is enabled, in which case the store is
+// wiped and created again by the mkfs with the cluster fsid. The result is reported in the
+// termination message of the container.
+func (c *Cluster) makeMonStoreFSIDCheckInitContainer(monConfig *monConfig) v1.Container {
+	dataDir := monConfig.DataPathMap.ContainerDataDir
+	onMismatch := fmt.Sprintf("echo \"%s $fsid\" | tee /dev/termination-log; exit 1", monStoreFSIDMismatch)
+	if c.spec.Mon.RecoverFSIDMismatch {
+		onMismatch = fmt.Sprintf("echo \"%s $fsid\" | tee /dev/termination-log; find %s -mindepth 1 -delete", monStoreFSIDWiped, dataDir)
+	}
+	// a store that cannot be read is left to the store check and to the mon daemon
+	checkCmd := fmt.Sprintf("if [ ! -e %[1]s/store.db ]; then exit 0; fi; "+
+		"fsid=$(%[2]s %[1]s get monmap -- --out /tmp/monmap > /dev/null && %[3]s --print /tmp/monmap | sed -n 's/^fsid //p'); "+
+		"if [ -z \"$fsid\" ] || [ \"$fsid\" = \"%[4]s\" ]; then exit 0; fi; %[5]s",
+		dataDir, monStoreToolCommand, monmaptoolCommand, c.ClusterInfo.FSID, onMismatch)
+	return v1.Container{
+		Name: monStoreFSIDCheckContainerName,
+		Command: []string{
+			"/bin/sh",
+		},
+		Args: []string{
+			"-c",
+			checkCmd,
+		},
+		Image:           c.spec.CephVersion.Image,
+		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
+		SecurityContext: c.monSecurityContext(),
+		Resources:       cephv1.GetMonResources(c.spec.Resources),
+	}
+}
+
+// checkMonStoreFSIDs reports the mons whose store was found by the fsid check of the mon pod to
+// belong to another cluster. Such a mon is not started until it is failed over or, with the recovery
+// enabled, its store was wiped and the mon joins the quorum as a new mon. An event is recorded when
+// the foreign store of a mon is first detected.
+func (c *Cluster) checkMonStoreFSIDs(pods []v1.Pod) {
+	foreign := map[string]string{}
+	for _, pod := range pods {
+		name, ok := pod.Labels[monDaemonAttr]
+		if !ok {
+			continue
+		}
+		result, fsid, ok := monStoreFSIDCheckResult(pod)
+		if !ok {
+			continue
+		}
+
+		var msg string
+		if result == monStoreFSIDWiped {
+			msg = fmt.Sprintf("mon %s had a store with fsid %s instead of the cluster fsid %s. the store was wiped and is created again", name, fsid, c.ClusterInfo.FSID)
+		} else {
+			msg = fmt.Sprintf("mon %s has a store with fsid %s instead of the cluster fsid %s and will not be started. "+
+				"enable recoverFSIDMismatch to wipe the store or fail over the mon", name, fsid, c.ClusterInfo.FSID)
+		}
+		if c.foreignStoreMons[name] != result {
+			logger.Warningf(msg)
+			c.recordMonEvent(name, v1.EventTypeWarning, "MonFSIDMismatch", msg)
+		}
+		foreign[name] = result
+	}
+	c.foreignStoreMons = foreign
+}
+
+// monStoreFSIDCheckResult returns the result and the store fsid reported by the fsid check init
+// container of the pod if it found a store of another cluster
+func monStoreFSIDCheckResult(pod v1.Pod) (string, string, bool) {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != monStoreFSIDCheckContainerName {
+			continue
+		}
+		// the init container is restarted after it fails, so the result may be in its last state
+		terminated := status.State.Terminated
+		if terminated == nil {
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated == nil {
+			return "", "", false
+		}
+		fields := strings.Fields(terminated.Message)
+		if len(fields) != 2 || (fields[0] != monStoreFSIDMismatch && fields[0] != monStoreFSIDWiped) {
+			return "", "", false
+		}
+		return fields[0], fields[1], true
+	}
+	return "", "", false
+}
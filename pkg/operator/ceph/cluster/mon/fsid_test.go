@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMonStoreFSIDCheckInitContainer(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: test.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// the fsid is checked before the mkfs so a foreign store is not used by the mon
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	check := d.Spec.Template.Spec.InitContainers[1]
+	assert.Equal(t, monStoreFSIDCheckContainerName, check.Name)
+	assert.Equal(t, "init-mon-fs", d.Spec.Template.Spec.InitContainers[2].Name)
+	script := check.Args[1]
+	assert.Contains(t, script, "ceph-monstore-tool /var/lib/ceph/mon/ceph-a get monmap -- --out /tmp/monmap")
+	assert.Contains(t, script, "[ \"$fsid\" = \""+c.ClusterInfo.FSID+"\" ]")
+	assert.True(t, strings.HasSuffix(script, "echo \"mismatch $fsid\" | tee /dev/termination-log; exit 1"))
+
+	// the store is wiped instead if the recovery is enabled
+	c.spec.Mon.RecoverFSIDMismatch = true
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	script = d.Spec.Template.Spec.InitContainers[1].Args[1]
+	assert.True(t, strings.HasSuffix(script, "echo \"wiped $fsid\" | tee /dev/termination-log; find /var/lib/ceph/mon/ceph-a -mindepth 1 -delete"))
+	assert.NotContains(t, script, "exit 1")
+}
+
+func TestCheckMonStoreFSIDs(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	monPod := func(name string, status v1.ContainerStatus) {
+		status.Name = monStoreFSIDCheckContainerName
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-" + name, Namespace: c.Namespace, Labels: MonLabels(c.Namespace, name)},
+			Status:     v1.PodStatus{InitContainerStatuses: []v1.ContainerStatus{status}},
+		}
+		_, err := clientset.CoreV1().Pods(c.Namespace).Create(pod)
+		assert.Nil(t, err)
+	}
+	terminated := func(exitCode int32, message string) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode, Message: message}}
+	}
+	// a has the cluster store, b has a foreign store, c is waiting to check its foreign store again
+	// and the foreign store of d was wiped
+	monPod("a", v1.ContainerStatus{State: terminated(0, "")})
+	monPod("b", v1.ContainerStatus{State: terminated(1, "mismatch foreign\n")})
+	monPod("c", v1.ContainerStatus{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}, LastTerminationState: terminated(1, "mismatch foreign\n")})
+	monPod("d", v1.ContainerStatus{State: terminated(0, "wiped other\n")})
+
+	c.checkMonStoreFSIDs(monPods(t, clientset, c.Namespace))
+	assert.Equal(t, map[string]string{"b": monStoreFSIDMismatch, "c": monStoreFSIDMismatch, "d": monStoreFSIDWiped}, c.foreignStoreMons)
+	assert.Equal(t, 3, len(recorder.Events))
+	events := strings.Join([]string{<-recorder.Events, <-recorder.Events, <-recorder.Events}, "\n")
+	assert.Contains(t, events, "Warning MonFSIDMismatch mon b has a store with fsid foreign instead of the cluster fsid "+c.ClusterInfo.FSID)
+	assert.Contains(t, events, "Warning MonFSIDMismatch mon c has a store with fsid foreign")
+	assert.Contains(t, events, "Warning MonFSIDMismatch mon d had a store with fsid other")
+
+	// the events are only recorded once
+	c.checkMonStoreFSIDs(monPods(t, clientset, c.Namespace))
+	assert.Equal(t, 3, len(c.foreignStoreMons))
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// the mon is forgotten once the pod with the foreign store is gone
+	err := clientset.CoreV1().Pods(c.Namespace).Delete("rook-ceph-mon-b", &metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	c.checkMonStoreFSIDs(monPods(t, clientset, c.Namespace))
+	assert.NotContains(t, c.foreignStoreMons, "b")
+	assert.Equal(t, 2, len(c.foreignStoreMons))
+}
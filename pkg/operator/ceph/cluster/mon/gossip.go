@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// waitForMonGossipConsistency waits until the monmap of each mon contains all the mons. A mon that
+// was just added may not be known yet by all the mons until the monmap has propagated. An error
+// with the mons that have a stale monmap is returned if they do not catch up within the mon pod
+// timeout.
+func waitForMonGossipConsistency(ctx context.Context, cluster *Cluster) error {
+	expected := []string{}
+	for name := range cluster.ClusterInfo.Monitors {
+		expected = append(expected, name)
+	}
+	sort.Strings(expected)
+
+	var stale map[string][]string
+	timeout := time.After(cluster.monPodTimeout)
+	for {
+		stale = findStaleMonGossip(cluster, expected)
+		if len(stale) == 0 {
+			logger.Infof("all mons %v know about each other", expected)
+			return nil
+		}
+		for _, name := range expected {
+			if missing, ok := stale[name]; ok {
+				logger.Infof("waiting for mon %s to learn about mons %v", name, missing)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for the mon gossip. %+v", ctx.Err())
+		case <-timeout:
+			return fmt.Errorf("timed out after %v waiting for the mon gossip. mons with a stale monmap: %v", cluster.monPodTimeout, stale)
+		case <-time.After(cluster.monPodRetryInterval):
+		}
+	}
+}
+
+// findStaleMonGossip returns the expected mons that are missing from the monmap of each mon. A mon
+// that cannot be queried misses all the other mons.
+func findStaleMonGossip(cluster *Cluster, expected []string) map[string][]string {
+	stale := map[string][]string{}
+	for _, name := range expected {
+		monMap, err := client.GetMonDump(cluster.context, cluster.ClusterInfo.Name, name)
+		if err != nil {
+			logger.Debugf("%+v", err)
+			stale[name] = otherMons(expected, name)
+			continue
+		}
+
+		known := map[string]bool{}
+		for _, m := range monMap.Mons {
+			known[m.Name] = true
+		}
+		missing := []string{}
+		for _, other := range expected {
+			if !known[other] {
+				missing = append(missing, other)
+			}
+		}
+		if len(missing) > 0 {
+			stale[name] = missing
+		}
+	}
+	return stale
+}
+
+// otherMons returns the mons other than the given mon
+func otherMons(mons []string, name string) []string {
+	others := []string{}
+	for _, m := range mons {
+		if m != name {
+			others = append(others, m)
+		}
+	}
+	return others
+}
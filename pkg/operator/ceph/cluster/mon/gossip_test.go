@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestWaitForMonGossipConsistency(t *testing.T) {
+	dumps := 0
+	staleDumps := 2
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "tell" && args[2] == "mon" && args[3] == "dump" {
+				dumps++
+				if args[1] == "mon.b" && staleDumps > 0 {
+					staleDumps--
+					return `{"epoch":2,"mons":[{"name":"a","rank":0},{"name":"b","rank":1}]}`, nil
+				}
+				if args[1] == "mon.c" && staleDumps < 0 {
+					return "", fmt.Errorf("mon.c timed out")
+				}
+				return `{"epoch":3,"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}`, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// mon b learns about mon c after two checks
+	assert.NoError(t, waitForMonGossipConsistency(context.Background(), c))
+	assert.Equal(t, 9, dumps)
+
+	// the stale mons are reported when they do not catch up
+	staleDumps = 1000
+	c.monPodTimeout = 50 * time.Millisecond
+	err := waitForMonGossipConsistency(context.Background(), c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mons with a stale monmap: map[b:[c]]")
+
+	// a mon that cannot be queried is stale
+	staleDumps = -1
+	err = waitForMonGossipConsistency(context.Background(), c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "map[c:[a b]]")
+
+	// the wait stops with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.monPodTimeout = time.Minute
+	err = waitForMonGossipConsistency(ctx, c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped waiting for the mon gossip")
+}
+
+func TestFindStaleMonGossip(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[1] == "mon.a" {
+				return `{"mons":[{"name":"a"}]}`, nil
+			}
+			return `{"mons":[{"name":"a"},{"name":"b"},{"name":"c"}]}`, nil
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	stale := findStaleMonGossip(c, []string{"a", "b", "c"})
+	assert.Equal(t, map[string][]string{"a": {"b", "c"}}, stale)
+	assert.Empty(t, findStaleMonGossip(c, []string{"b", "c"}))
+}
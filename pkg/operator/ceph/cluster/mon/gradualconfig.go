@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// ApplyMonConfigGradually sets a config option for all the mons, one mon at a time so that an option
+// that disrupts the mons cannot break the quorum of all the mons at once
+func (c *Cluster) ApplyMonConfigGradually(key, value string) error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	return applyMonConfigGradually(context.Background(), c, key, value)
+}
+
+// applyMonConfigGradually sets the option for each mon in turn with the leader last, and waits for all
+// the mons to be in quorum after each mon. If the quorum is lost, the option is removed again from the
+// mons it was set for. Once all the mons have the option, it is set for all the mons and the settings
+// of the single mons are removed.
+func applyMonConfigGradually(ctx context.Context, cluster *Cluster, key, value string) error {
+	status, err := cluster.waitForFullQuorum()
+	if err != nil {
+		return fmt.Errorf("cannot safely apply mon config %s. %+v", key, err)
+	}
+
+	mons := append([]client.MonMapEntry{}, status.MonMap.Mons...)
+	sort.Slice(mons, func(i, j int) bool { return mons[i].Rank > mons[j].Rank })
+	applied := []string{}
+	for _, mon := range mons {
+		if err := ctx.Err(); err != nil {
+			return rollbackMonConfig(cluster, key, applied, fmt.Errorf("stopped applying mon config %s. %+v", key, err))
+		}
+		if err := client.MonDaemonSetConfig(cluster.context, cluster.ClusterInfo.Name, mon.Name, key, value); err != nil {
+			return rollbackMonConfig(cluster, key, applied, err)
+		}
+		applied = append(applied, mon.Name)
+		logger.Infof("set config %s=%s for mon %s", key, value, mon.Name)
+
+		if _, err := cluster.waitForFullQuorum(); err != nil {
+			return rollbackMonConfig(cluster, key, applied, fmt.Errorf("quorum lost after setting mon config %s for mon %s. %+v", key, mon.Name, err))
+		}
+	}
+
+	if err := client.MonSetConfig(cluster.context, cluster.ClusterInfo.Name, key, value); err != nil {
+		return fmt.Errorf("failed to apply mon config. %+v", err)
+	}
+	for _, name := range applied {
+		if err := client.MonDaemonRemoveConfig(cluster.context, cluster.ClusterInfo.Name, name, key); err != nil {
+			logger.Warningf("%+v", err)
+		}
+	}
+	logger.Infof("set mon config %s=%s for %d mons", key, value, len(applied))
+	return nil
+}
+
+// rollbackMonConfig removes the option from the mons it was set for and returns the error that caused
+// the rollback
+func rollbackMonConfig(cluster *Cluster, key string, applied []string, cause error) error {
+	logger.Errorf("rolling back mon config %s for mons %v. %+v", key, applied, cause)
+	for _, name := range applied {
+		if err := client.MonDaemonRemoveConfig(cluster.context, cluster.ClusterInfo.Name, name, key); err != nil {
+			logger.Errorf("failed to roll back mon config %s. %+v", key, err)
+		}
+	}
+	return cause
+}
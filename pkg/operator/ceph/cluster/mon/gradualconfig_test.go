@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestApplyMonConfigGradually(t *testing.T) {
+	// the quorum is lost once the config is set for the mon that breaks it
+	breaking := ""
+	quorum := []int{0, 1, 2}
+	events := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				events = append(events, fmt.Sprintf("set %s %s=%s", args[2], args[3], args[4]))
+				if args[2] == "mon."+breaking {
+					quorum = []int{0}
+				}
+				return "", nil
+			}
+			if args[0] == "config" && args[1] == "rm" {
+				events = append(events, fmt.Sprintf("rm %s %s", args[2], args[3]))
+				if args[2] == "mon."+breaking {
+					quorum = []int{0, 1, 2}
+				}
+				return "", nil
+			}
+			if args[0] == "mon_status" {
+				status := client.MonStatusResponse{Quorum: quorum}
+				status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+				events = append(events, fmt.Sprintf("quorum %d", len(status.Quorum)))
+				serialized, _ := json.Marshal(status)
+				return string(serialized), nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(3), Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	c.monPodTimeout = 50 * time.Millisecond
+
+	// the config is set for one mon at a time with the leader last, then for all the mons
+	assert.NoError(t, c.ApplyMonConfigGradually("mon_lease", "10"))
+	assert.Equal(t, []string{
+		"quorum 3",
+		"set mon.c mon_lease=10", "quorum 3",
+		"set mon.b mon_lease=10", "quorum 3",
+		"set mon.a mon_lease=10", "quorum 3",
+		"set mon mon_lease=10",
+		"rm mon.c mon_lease", "rm mon.b mon_lease", "rm mon.a mon_lease",
+	}, events)
+
+	// the config is removed from the mons it was set for when the quorum is lost
+	events = []string{}
+	breaking = "b"
+	err := applyMonConfigGradually(context.Background(), c, "mon_lease", "1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "quorum lost after setting mon config mon_lease for mon b")
+	assert.Contains(t, events, "set mon.c mon_lease=1")
+	assert.Contains(t, events, "set mon.b mon_lease=1")
+	assert.NotContains(t, events, "set mon.a mon_lease=1")
+	assert.NotContains(t, events, "set mon mon_lease=1")
+	assert.Equal(t, []string{"rm mon.c mon_lease", "rm mon.b mon_lease"}, events[len(events)-2:])
+	assert.Equal(t, []int{0, 1, 2}, quorum)
+
+	// nothing is set if the mons are not all in quorum
+	events = []string{}
+	breaking = ""
+	quorum = []int{0, 1}
+	assert.Error(t, applyMonConfigGradually(context.Background(), c, "mon_lease", "1"))
+	for _, event := range events {
+		assert.Contains(t, event, "quorum")
+	}
+
+	// nothing is set once the context is canceled
+	quorum = []int{0, 1, 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	events = []string{}
+	assert.Error(t, applyMonConfigGradually(ctx, c, "mon_lease", "1"))
+	assert.Equal(t, []string{"quorum 3"}, events)
+}
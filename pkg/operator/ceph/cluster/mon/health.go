@@ -17,6 +17,7 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -39,6 +40,15 @@ var (
 	MonOutTimeout = 600 * time.Second
 )
 
+const (
+	// the stats checks send a command to each mon, so they run less often than the health check
+	monStatsCheckInterval = 5 * time.Minute
+	// the stats checks are stopped after this time so that they do not delay a failover for long
+	monStatsCheckTimeout = time.Minute
+	// a mon that does not answer a command of the stats checks within this time is skipped
+	monTellTimeout = 15 * time.Second
+)
+
 // HealthChecker aggregates the mon/cluster info needed to check the health of the monitors
 type HealthChecker struct {
 	monCluster  *Cluster
@@ -80,12 +90,29 @@ func (c *Cluster) checkHealth() error {
 	c.acquireOrchestrationLock()
 	defer c.releaseOrchestrationLock()
 
+	if c.monReconcilePaused() {
+		logger.Infof("skipping the mon health check since the mon reconcile is paused by the %q annotation of the cluster", monReconcilePausedAnnotation)
+		return nil
+	}
+	// the mons are still checked if the change cannot be applied, as after a failed ReconcileSpec
+	if err := c.reconcilePausedSpecChange(); err != nil {
+		logger.Warningf("failed to apply the mon settings changed while the mon reconcile was paused. %+v", err)
+	}
+
 	logger.Debugf("Checking health for mons in cluster. %s", c.ClusterInfo.Name)
 
+	// fast path when none of the mons can be reached
+	if err := c.probeMons(); err != nil {
+		c.reportMonHealth(nil, 0)
+		return fmt.Errorf("failed to connect to the mons. %+v", err)
+	}
+	c.checkMonScores()
+
 	// connect to the mons
 	// get the status and check for quorum
 	status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, true)
 	if err != nil {
+		c.reportMonHealth(nil, 0)
 		return fmt.Errorf("failed to get mon status. %+v", err)
 	}
 	logger.Debugf("Mon status: %+v", status)
@@ -93,6 +120,29 @@ func (c *Cluster) checkHealth() error {
 		return c.handleExternalMonStatus(status)
 	}
 
+	// the mon pods and deployments are listed once for the checks of this health check
+	listOptions := metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()}
+	if pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(listOptions); err != nil {
+		logger.Warningf("failed to list the mon pods to check the mon stores. %+v", err)
+	} else {
+		c.checkMonStores(pods.Items)
+		c.checkMonStoreFSIDs(pods.Items)
+	}
+	if deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(listOptions); err != nil {
+		logger.Warningf("failed to list the mon deployments to check their replicas. %+v", err)
+	} else {
+		c.checkMonReplicas(deployments.Items)
+	}
+	c.checkMonServices()
+	// mons on the same host are expected if multiple mons are allowed per node
+	if !c.spec.Mon.AllowMultiplePerNode {
+		if _, _, err := checkMonHighAvailability(context.Background(), c); err != nil {
+			logger.Warningf("failed to check the high availability of the mons. %+v", err)
+		}
+	}
+	c.checkMonZoneConnectivity()
+	c.checkCSIConfig(status)
+
 	// Use a local mon count in case the user updates the crd in another goroutine.
 	// We need to complete a health check with a consistent value.
 	desiredMonCount, msg, err := c.getTargetMonCount()
@@ -100,6 +150,8 @@ func (c *Cluster) checkHealth() error {
 		return fmt.Errorf("failed to get target mon count. %+v", err)
 	}
 	logger.Debugf(msg)
+	c.reportMonHealth(&status, desiredMonCount)
+	c.reportMonZoneCount()
 
 	// Source of truth of which mons should exist is our *clusterInfo*
 	monsNotFound := map[string]interface{}{}
@@ -151,7 +203,7 @@ func (c *Cluster) checkHealth() error {
 
 			// when the timeout for the mon has been reached, continue to the
 			// normal failover/delete mon pod part of the code
-			if time.Since(c.monTimeoutList[mon.Name]) <= MonOutTimeout {
+			if time.Since(c.monTimeoutList[mon.Name]) <= c.failoverTimeout() {
 				logger.Warningf("mon %s not found in quorum, waiting for timeout before failover", mon.Name)
 				continue
 			}
@@ -172,6 +224,15 @@ func (c *Cluster) checkHealth() error {
 		return nil
 	}
 
+	c.checkMonStats(status)
+
+	// move a mon back into its zone if the zone has recovered from an outage
+	if allMonsInQuorum {
+		if done, err := c.returnMonToRecoveredZone(); done || err != nil {
+			return err
+		}
+	}
+
 	// find any mons that invalidate our placement policy, and if necessary,
 	// reschedule them to other nodes.
 	done, err := c.resolveInvalidMonitorPlacement(desiredMonCount)
@@ -179,10 +240,17 @@ func (c *Cluster) checkHealth() error {
 		return err
 	}
 
+	// the mon count is not changed to a count that is rejected by the validation
+	if len(status.MonMap.Mons) != desiredMonCount {
+		if err := c.validateMonCount(); err != nil {
+			return fmt.Errorf("not changing the mon count. %+v", err)
+		}
+	}
+
 	// create/start new mons when there are fewer mons than the desired count in the CRD
 	if len(status.MonMap.Mons) < desiredMonCount {
 		logger.Infof("adding mons. currently %d mons are in quorum and the desired count is %d.", len(status.MonMap.Mons), desiredMonCount)
-		return c.startMons(desiredMonCount)
+		return c.startMons(c.limitMonCountChange(len(c.ClusterInfo.Monitors), desiredMonCount))
 	}
 
 	// remove extra mons if the desired count has decreased in the CRD and all the mons are currently healthy
@@ -190,14 +258,100 @@ func (c *Cluster) checkHealth() error {
 		if desiredMonCount < 2 && len(status.MonMap.Mons) == 2 {
 			logger.Warningf("cannot reduce mon quorum size from 2 to 1")
 		} else {
-			logger.Infof("removing an extra mon. currently %d are in quorum and only %d are desired", len(status.MonMap.Mons), desiredMonCount)
-			return c.removeMon(status.MonMap.Mons[0].Name)
+			targetCount := c.limitMonCountChange(len(status.MonMap.Mons), desiredMonCount)
+			for i := 0; i < len(status.MonMap.Mons)-targetCount; i++ {
+				logger.Infof("removing an extra mon. currently %d are in quorum and only %d are desired", len(status.MonMap.Mons)-i, desiredMonCount)
+				if err := c.removeMon(status.MonMap.Mons[i].Name); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
 	}
 
 	return nil
 }
 
+// checkMonStats runs the checks of the stats of each mon and of the osds if they did not run within
+// the interval
+func (c *Cluster) checkMonStats(status client.MonStatusResponse) {
+	if time.Since(c.lastMonStatsCheck) < monStatsCheckInterval {
+		return
+	}
+	c.lastMonStatsCheck = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), monStatsCheckTimeout)
+	defer cancel()
+
+	// report the mons with an overloaded mon store
+	c.checkPaxosLatency(ctx, status)
+	if err := collectRocksDBMetrics(ctx, c); err != nil {
+		logger.Warningf("failed to collect the mon rocksdb metrics. %+v", err)
+	}
+
+	// expand the mon volumes that are running out of space
+	c.checkMonPVCSize(ctx)
+
+	if _, err := c.checkPGPerOSD(); err != nil {
+		logger.Warningf("failed to check the pgs per osd. %+v", err)
+	}
+	if err := c.runAutopilot(); err != nil {
+		logger.Warningf("failed to run the mon autopilot. %+v", err)
+	}
+}
+
+// checkPaxosLatency reports the mons whose paxos proposal latency exceeds the threshold in the
+// cluster spec as degraded. A Warning event is recorded when a mon first becomes degraded.
+func (c *Cluster) checkPaxosLatency(ctx context.Context, status client.MonStatusResponse) {
+	threshold := time.Duration(c.spec.Mon.PaxosLatencyThresholdMs) * time.Millisecond
+	if threshold <= 0 {
+		return
+	}
+
+	inMonMap := map[string]struct{}{}
+	for _, mon := range status.MonMap.Mons {
+		inMonMap[mon.Name] = struct{}{}
+		// the latency of mons out of quorum is irrelevant since they are failed over anyway
+		if !monInQuorum(mon, status.Quorum) {
+			continue
+		}
+
+		// the latency of the remaining mons is checked on the next run
+		if ctx.Err() != nil {
+			logger.Warningf("stopped checking the paxos latency of the mons. %+v", ctx.Err())
+			break
+		}
+
+		counters, err := client.GetMonPerfCounters(c.context, c.ClusterInfo.Name, mon.Name, monTellTimeout)
+		if err != nil {
+			logger.Warningf("failed to check paxos latency of mon %s. %+v", mon.Name, err)
+			continue
+		}
+
+		latency := time.Duration(counters.Paxos.BeginLatency.AvgTime * float64(time.Second))
+		if latency <= threshold {
+			if _, ok := c.degradedMons[mon.Name]; ok {
+				logger.Infof("mon %s paxos proposal latency %v is back under the threshold %v", mon.Name, latency, threshold)
+				delete(c.degradedMons, mon.Name)
+			}
+			continue
+		}
+
+		msg := fmt.Sprintf("mon %s paxos proposal latency %v exceeds the threshold %v. the mon store may be overloaded", mon.Name, latency, threshold)
+		logger.Warningf(msg)
+		if _, ok := c.degradedMons[mon.Name]; !ok {
+			c.recordMonEvent(mon.Name, v1.EventTypeWarning, "MonPaxosLatencyHigh", msg)
+		}
+		c.degradedMons[mon.Name] = msg
+	}
+
+	// forget about mons that were removed
+	for name := range c.degradedMons {
+		if _, ok := inMonMap[name]; !ok {
+			delete(c.degradedMons, name)
+		}
+	}
+}
+
 func (c *Cluster) checkMonsOnSameNode(desiredMonCount int) (bool, error) {
 	nodesUsed := map[string]struct{}{}
 	for name, node := range c.mapping.Node {
@@ -246,6 +400,14 @@ func (c *Cluster) checkMonsOnValidNodes() (bool, error) {
 	return false, nil
 }
 
+// failoverTimeout returns how long a mon may be out of quorum before it is failed over
+func (c *Cluster) failoverTimeout() time.Duration {
+	if c.spec.Mon.FailoverTimeoutMinutes > 0 {
+		return time.Duration(c.spec.Mon.FailoverTimeoutMinutes) * time.Minute
+	}
+	return MonOutTimeout
+}
+
 // failMon compares the monCount against desiredMonCount
 func (c *Cluster) failMon(monCount, desiredMonCount int, name string) {
 	if monCount > desiredMonCount {
@@ -262,29 +424,57 @@ func (c *Cluster) failMon(monCount, desiredMonCount int, name string) {
 }
 
 func (c *Cluster) failoverMon(name string) error {
+	if detectMonReplacementLoop(c, name) {
+		return fmt.Errorf("not replacing mon %s since it was replaced more than %d times in %s", name, MaxReplacementsBeforeAlert, ReplacementLoopWindow)
+	}
+	c.recordMonReplacement(name)
+
+	zone := c.failoverZone(name)
+	// a mon that was moved out of its zone is replaced in the zone it was moved out of
+	if originZone, ok := c.displacedMons[name]; ok {
+		zone = originZone
+	}
+	newName, err := c.failoverMonToZone(name, zone)
+	if err != nil {
+		return err
+	}
+	c.trackDisplacedMon(newName, zone)
+	c.inheritMonReplacements(name, newName)
+	return nil
+}
+
+// failoverMonToZone replaces the mon with a new mon that is placed in the given zone if the zone
+// has a valid node. The name of the new mon is returned.
+func (c *Cluster) failoverMonToZone(name, zone string) (string, error) {
 	logger.Infof("Failing over monitor %s", name)
+	monFailovers.WithLabelValues(c.Namespace).Inc()
 
 	// Start a new monitor
 	m := c.newMonConfig(c.maxMonID + 1)
+	m.PreferredZone = zone
+	m.Replacement = true
 	logger.Infof("starting new mon: %+v", m)
 
 	// Create the service endpoint
 	serviceIP, err := c.createService(m)
 	if err != nil {
-		return fmt.Errorf("failed to create mon service. %+v", err)
+		return "", fmt.Errorf("failed to create mon service. %+v", err)
 	}
 
 	mConf := []*monConfig{m}
 
 	// Assign the pod to a node
 	if err = c.assignMons(mConf); err != nil {
-		return fmt.Errorf("failed to place new mon on a node. %+v", err)
+		return "", fmt.Errorf("failed to place new mon on a node. %+v", err)
+	}
+	if err = c.validateMonPorts(mConf); err != nil {
+		return "", fmt.Errorf("invalid port for new mon. %+v", err)
 	}
 
 	if c.HostNetwork {
 		node, ok := c.mapping.Node[m.DaemonName]
 		if !ok {
-			return fmt.Errorf("mon %s doesn't exist in assignment map", m.DaemonName)
+			return "", fmt.Errorf("mon %s doesn't exist in assignment map", m.DaemonName)
 		}
 		m.PublicIP = node.Address
 	} else {
@@ -294,13 +484,50 @@ func (c *Cluster) failoverMon(name string) error {
 
 	// Start the deployment
 	if err = c.startDeployments(mConf, true); err != nil {
-		return fmt.Errorf("failed to start new mon %s. %+v", m.DaemonName, err)
+		return "", fmt.Errorf("failed to start new mon %s. %+v", m.DaemonName, err)
 	}
 
 	// Only increment the max mon id if the new pod started successfully
 	c.maxMonID++
 
-	return c.removeMon(name)
+	return m.DaemonName, c.removeMon(name)
+}
+
+// failoverZone returns the zone to prefer for the mon replacing the failed mon. the replacement is
+// kept in the zone of the failed mon if it is the only mon in the zone, which keeps the mons spread
+// across the zones. if the zone has other mons (e.g. the zone is overloaded and the mon is moved to
+// rebalance the mons), the mon is scheduled as usual.
+func (c *Cluster) failoverZone(daemonName string) string {
+	zone := c.monZone(daemonName)
+	if zone == "" {
+		return ""
+	}
+	for otherMon := range c.mapping.Node {
+		if otherMon != daemonName && c.monZone(otherMon) == zone {
+			return ""
+		}
+	}
+	return zone
+}
+
+// monZone returns the failure domain zone of the node the mon is assigned to, or an empty string if
+// it is not known
+func (c *Cluster) monZone(daemonName string) string {
+	nodeInfo, ok := c.mapping.Node[daemonName]
+	if !ok {
+		return ""
+	}
+	if nodeInfo.Zone != "" {
+		return nodeInfo.Zone
+	}
+
+	// the zone is not in the mappings saved by older versions of the operator
+	node, err := c.context.Clientset.CoreV1().Nodes().Get(nodeInfo.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get the zone of mon %s on node %s. %+v", daemonName, nodeInfo.Name, err)
+		return ""
+	}
+	return node.Labels[zoneLabel]
 }
 
 func (c *Cluster) removeMon(daemonName string) error {
@@ -308,6 +535,12 @@ func (c *Cluster) removeMon(daemonName string) error {
 
 	resourceName := resourceName(daemonName)
 
+	// Remove the bad monitor from quorum first. Otherwise the mon would stay in the monmap and clients
+	// would keep trying to connect to it if removing the mon fails after its pod is gone.
+	if err := removeMonitorFromQuorum(c.context, c.ClusterInfo.Name, daemonName); err != nil {
+		return fmt.Errorf("failed to remove mon %s from quorum. %+v", daemonName, err)
+	}
+
 	// Remove the mon pod if it is still there
 	var gracePeriod int64
 	propagation := metav1.DeletePropagationForeground
@@ -319,12 +552,10 @@ func (c *Cluster) removeMon(daemonName string) error {
 			return fmt.Errorf("failed to remove dead mon deployment %s. %+v", resourceName, err)
 		}
 	}
-
-	// Remove the bad monitor from quorum
-	if err := removeMonitorFromQuorum(c.context, c.ClusterInfo.Name, daemonName); err != nil {
-		return fmt.Errorf("failed to remove mon %s from quorum. %+v", daemonName, err)
-	}
 	delete(c.ClusterInfo.Monitors, daemonName)
+	delete(c.displacedMons, daemonName)
+	deleteMonRocksDBMetrics(c.Namespace, daemonName)
+	delete(c.monEvents, daemonName)
 	// check if a mapping exists for the mon
 	if _, ok := c.mapping.Node[daemonName]; ok {
 		nodeName := c.mapping.Node[daemonName].Name
@@ -351,7 +582,7 @@ func (c *Cluster) removeMon(daemonName string) error {
 		}
 	}
 
-	if err := c.saveMonConfig(); err != nil {
+	if _, err := c.saveMonConfig(); err != nil {
 		return fmt.Errorf("failed to save mon config after failing over mon %s. %+v", daemonName, err)
 	}
 
@@ -523,7 +754,7 @@ func (c *Cluster) handleExternalMonStatus(status client.MonStatusResponse) error
 
 	// let's save the monitor's config if anything happened
 	if changed {
-		if err := c.saveMonConfig(); err != nil {
+		if _, err := c.saveMonConfig(); err != nil {
 			return fmt.Errorf("failed to save mon config after adding/removing external mon. %+v", err)
 		}
 	}
@@ -17,12 +17,14 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
@@ -35,7 +37,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestCheckHealth(t *testing.T) {
@@ -58,6 +63,8 @@ func TestCheckHealth(t *testing.T) {
 	}
 	c := New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
 	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	// add both missing mons in a single health check
+	c.spec.Mon.MaxMonChangeRate = 2
 	logger.Infof("initial mons: %v", c.ClusterInfo.Monitors)
 	c.waitForStart = false
 	defer os.RemoveAll(c.context.ConfigDir)
@@ -89,6 +96,82 @@ func TestCheckHealth(t *testing.T) {
 	}
 }
 
+func TestCheckHealthMonChangeRate(t *testing.T) {
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
+
+	var c *Cluster
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponseFromMons(c.ClusterInfo.Monitors), nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset: test.New(1),
+		ConfigDir: configDir,
+		Executor:  executor,
+	}
+	c = New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 5, AllowMultiplePerNode: true}, "myversion")
+	c.waitForStart = false
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0"}
+	c.maxMonID = 0
+
+	// only one mon is added per health check by default
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 2, len(c.ClusterInfo.Monitors))
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 3, len(c.ClusterInfo.Monitors))
+
+	// the remaining mons are added at once if the rate allows it
+	c.spec.Mon.MaxMonChangeRate = 2
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 5, len(c.ClusterInfo.Monitors))
+
+	// the mons are removed at the same rate
+	c.spec.Mon.Count = 1
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 3, len(c.ClusterInfo.Monitors))
+	c.spec.Mon.MaxMonChangeRate = 0
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 2, len(c.ClusterInfo.Monitors))
+}
+
+func TestCheckHealthListsMonsOnce(t *testing.T) {
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
+
+	var c *Cluster
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponseFromMons(c.ClusterInfo.Monitors), nil
+		},
+	}
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset: clientset,
+		ConfigDir: configDir,
+		Executor:  executor,
+	}
+	c = New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 1, AllowMultiplePerNode: true, CheckStoreOnStart: true}, "myversion")
+	c.waitForStart = false
+
+	// the mon pods and deployments are listed once for all the checks of the health check. the
+	// placement of the mons is validated with the node usage, which lists the mon pods again.
+	assert.NoError(t, c.checkHealth())
+	lists := map[string]int{}
+	for _, action := range clientset.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok && list.GetListRestrictions().Labels.String() == MonLabelSelector("ns", "").String() {
+			lists[list.GetResource().Resource]++
+		}
+	}
+	assert.Equal(t, 2, lists["pods"])
+	assert.Equal(t, 1, lists["deployments"])
+}
+
 func TestCheckHealthNotFound(t *testing.T) {
 	var deploymentsUpdated *[]*apps.Deployment
 	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
@@ -145,6 +228,74 @@ func TestCheckHealthNotFound(t *testing.T) {
 	}
 }
 
+func TestCheckHealthFailoverTimeout(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	// mon c is in the mon map but out of quorum
+	status := client.MonStatusResponse{Quorum: []int{0, 1}}
+	status.MonMap.Mons = []client.MonMapEntry{
+		{Name: "a", Rank: 0, Address: "1.2.3.1"},
+		{Name: "b", Rank: 1, Address: "1.2.3.2"},
+		{Name: "c", Rank: 2, Address: "1.2.3.3"},
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			serialized, _ := json.Marshal(status)
+			return string(serialized), nil
+		},
+	}
+
+	newTestCluster := func(outOfQuorum time.Duration) *Cluster {
+		configDir, _ := ioutil.TempDir("", "")
+		context := &clusterd.Context{
+			Clientset: test.New(1),
+			ConfigDir: configDir,
+			Executor:  executor,
+		}
+		c := New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+		setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+		c.spec.Mon.FailoverTimeoutMinutes = 2
+		c.waitForStart = false
+		for _, name := range []string{"a", "b", "c"} {
+			c.mapping.Node[name] = &NodeInfo{Name: "node0"}
+		}
+		c.mapping.Port["node0"] = DefaultMsgr1Port
+		c.maxMonID = 2
+		c.monTimeoutList["c"] = time.Now().Add(-outOfQuorum)
+		return c
+	}
+
+	// the failover is not started just under the timeout
+	c := newTestCluster(2*time.Minute - 10*time.Second)
+	defer os.RemoveAll(c.context.ConfigDir)
+	err := c.checkHealth()
+	assert.Nil(t, err)
+	assert.Contains(t, c.ClusterInfo.Monitors, "c")
+	assert.NotContains(t, c.ClusterInfo.Monitors, "d")
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+
+	// the mon is failed over after the configured timeout, well before the default timeout
+	c = newTestCluster(2*time.Minute + 10*time.Second)
+	defer os.RemoveAll(c.context.ConfigDir)
+	err = c.checkHealth()
+	assert.Nil(t, err)
+	assert.NotContains(t, c.ClusterInfo.Monitors, "c")
+	assert.Contains(t, c.ClusterInfo.Monitors, "d")
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+}
+
+func TestFailoverTimeout(t *testing.T) {
+	c := &Cluster{}
+	assert.Equal(t, MonOutTimeout, c.failoverTimeout())
+
+	c.spec.Mon.FailoverTimeoutMinutes = 3
+	assert.Equal(t, 3*time.Minute, c.failoverTimeout())
+
+	c.spec.Mon.FailoverTimeoutMinutes = -1
+	assert.Error(t, c.validateSpec())
+}
+
 func TestOverloadedZoneRebalances(t *testing.T) {
 	clientset := test.New(3)
 
@@ -230,6 +381,160 @@ func TestOverloadedZoneRebalances(t *testing.T) {
 	assert.Equal(t, "mynode1", cluster.mapping.Node["b"].Hostname)
 }
 
+func TestFailoverMonSameZone(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	// three zones with two nodes each
+	clientset := test.New(6)
+	for i := 0; i < 6; i++ {
+		node, err := clientset.CoreV1().Nodes().Get(fmt.Sprintf("node%d", i), metav1.GetOptions{})
+		assert.NoError(t, err)
+		node.Labels = map[string]string{"failure-domain.beta.kubernetes.io/zone": fmt.Sprintf("zone%d", i/2)}
+		_, err = clientset.CoreV1().Nodes().Update(node)
+		assert.NoError(t, err)
+	}
+
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset: clientset,
+		ConfigDir: configDir,
+		Executor:  executor,
+	}
+	c := New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3}, "myversion")
+	c.waitForStart = false
+
+	// one mon per zone, before the zone was saved in the mapping
+	for i, mon := range []string{"a", "b", "c"} {
+		c.mapping.Node[mon] = &NodeInfo{Name: fmt.Sprintf("node%d", i*2), Address: "0.0.0.0"}
+	}
+	c.maxMonID = 2
+
+	// the replacement of mon a stays in the zone of mon a
+	err := c.failoverMon("a")
+	assert.NoError(t, err)
+	assert.NotContains(t, c.mapping.Node, "a")
+	assert.Equal(t, "node1", c.mapping.Node["d"].Name)
+	assert.Equal(t, "zone0", c.mapping.Node["d"].Zone)
+	assert.ElementsMatch(t, []string{}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+
+	// another zone is used if there is no valid node left in the zone
+	node, err := clientset.CoreV1().Nodes().Get("node0", metav1.GetOptions{})
+	assert.NoError(t, err)
+	node.Spec.Unschedulable = true
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.NoError(t, err)
+	err = c.failoverMon("d")
+	assert.NoError(t, err)
+	assert.NotContains(t, c.mapping.Node, "d")
+	assert.NotEqual(t, "zone0", c.mapping.Node["e"].Zone)
+}
+
+func TestCheckPaxosLatency(t *testing.T) {
+	latencies := map[string]float64{"a": 0.002, "b": 2.5}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFileTimeout: func(debug bool, timeout time.Duration, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if len(args) > 2 && args[0] == "tell" && args[2] == "perf" {
+				return fmt.Sprintf(`{"paxos":{"begin_latency":{"avgcount":10,"sum":1,"avgtime":%f}}}`, latencies[args[1][len("mon."):]]), nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := New(&clusterd.Context{Clientset: test.New(1), Executor: executor}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 2, cephv1.MonSpec{Count: 3}, "myversion")
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	status := client.MonStatusResponse{Quorum: []int{0, 1}}
+	status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}}
+
+	// the check is disabled by default
+	c.checkPaxosLatency(context.Background(), status)
+	assert.Equal(t, 0, len(c.degradedMons))
+
+	// mon b is over the threshold
+	c.spec.Mon.PaxosLatencyThresholdMs = 100
+	c.checkPaxosLatency(context.Background(), status)
+	assert.Equal(t, 1, len(c.degradedMons))
+	assert.Contains(t, c.degradedMons, "b")
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "Warning MonPaxosLatencyHigh mon b")
+
+	// the event is only recorded when the mon becomes degraded
+	c.checkPaxosLatency(context.Background(), status)
+	assert.Contains(t, c.degradedMons, "b")
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// mon b recovers
+	latencies["b"] = 0.05
+	c.checkPaxosLatency(context.Background(), status)
+	assert.Equal(t, 0, len(c.degradedMons))
+
+	// the latency is not checked after the check is stopped
+	latencies["b"] = 2.5
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.checkPaxosLatency(ctx, status)
+	assert.Equal(t, 0, len(c.degradedMons))
+}
+
+func TestCheckMonStatsInterval(t *testing.T) {
+	dumps := 0
+	osdCommands := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if len(args) > 1 && args[0] == "osd" && args[1] == "df" {
+				osdCommands++
+				return `{"nodes":[{"id":0,"name":"osd.0","pgs":10}],"summary":{"total_kb":0}}`, nil
+			}
+			if len(args) > 1 && args[0] == "osd" && args[1] == "stat" {
+				osdCommands++
+				return `{"epoch":10,"num_osds":1,"num_up_osds":1,"num_in_osds":1}`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+		MockExecuteCommandWithOutputFileTimeout: func(debug bool, timeout time.Duration, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			// a mon that hangs is given up after the timeout
+			assert.Equal(t, monTellTimeout, timeout)
+			if len(args) > 2 && args[0] == "tell" && args[2] == "perf" {
+				dumps++
+				return `{"paxos":{"begin_latency":{"avgcount":10,"sum":1,"avgtime":0.001}}}`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := New(&clusterd.Context{Clientset: test.New(1), Executor: executor}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 2, cephv1.MonSpec{Count: 3}, "myversion")
+	c.spec.Mon.PaxosLatencyThresholdMs = 100
+	c.spec.MaxPGPerOSD = 300
+	c.spec.Mon.Autopilot = true
+	status := client.MonStatusResponse{Quorum: []int{0, 1}}
+	status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}}
+
+	// the paxos latency and the rocksdb metrics of both mons are read, and the pgs per osd and the
+	// osd count are checked
+	c.checkMonStats(status)
+	assert.Equal(t, 4, dumps)
+	assert.Equal(t, 2, osdCommands)
+
+	// the stats are not read again within the interval
+	c.checkMonStats(status)
+	assert.Equal(t, 4, dumps)
+	assert.Equal(t, 2, osdCommands)
+
+	c.lastMonStatsCheck = time.Now().Add(-monStatsCheckInterval)
+	c.checkMonStats(status)
+	assert.Equal(t, 8, dumps)
+	assert.Equal(t, 4, osdCommands)
+}
+
 func TestCheckHealthTwoMonsOneNode(t *testing.T) {
 	executorNextMons := false
 	executor := &exectest.MockExecutor{
@@ -519,6 +824,45 @@ func TestAddRemoveMons(t *testing.T) {
 	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
 }
 
+func TestScaleDownRemovesMonFromMonMap(t *testing.T) {
+	clientset := test.New(3)
+	clusterInfo := test.CreateConfigDir(3)
+	removed := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if len(args) > 2 && args[0] == "mon" && args[1] == "remove" {
+				// the mon must still have its deployment when it is removed from the monmap
+				_, err := clientset.AppsV1().Deployments("ns").Get(resourceName(args[2]), metav1.GetOptions{})
+				assert.NoError(t, err)
+				removed = append(removed, args[2])
+				return "", nil
+			}
+			return clienttest.MonInQuorumResponseFromMons(clusterInfo.Monitors), nil
+		},
+	}
+	c := New(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	c.ClusterInfo = clusterInfo
+	c.spec.Mon.Count = 1
+	for name := range c.ClusterInfo.Monitors {
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: resourceName(name), Namespace: "ns"}}
+		_, err := clientset.AppsV1().Deployments("ns").Create(d)
+		assert.NoError(t, err)
+	}
+
+	err := c.checkHealth()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(removed))
+	assert.Equal(t, 2, len(c.ClusterInfo.Monitors))
+	assert.NotContains(t, c.ClusterInfo.Monitors, removed[0])
+
+	// the deployment and the endpoint of the removed mon are gone
+	_, err = clientset.AppsV1().Deployments("ns").Get(resourceName(removed[0]), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	cm, err := clientset.CoreV1().ConfigMaps("ns").Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, ParseMonEndpoints(cm.Data[EndpointDataKey]), removed[0])
+}
+
 func TestAddOrRemoveExternalMonitor(t *testing.T) {
 	var changed bool
 	var err error
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	// HealthGateTimeout is how long the reconcile of the other daemons waits for the mons to be in quorum
+	HealthGateTimeout = 10 * time.Minute
+)
+
+// MonHealthGate blocks the reconcile of the daemons that need the mons until all the mons are in
+// quorum
+type MonHealthGate interface {
+	// WaitForQuorum waits until all the mons are in quorum or the context is done
+	WaitForQuorum(ctx context.Context) error
+}
+
+// WaitForQuorum waits until all the mons in the monmap are in quorum. The mons may still be starting,
+// so the wait only ends when the context is done. A mon that stays out of quorum holds the gate until
+// the context is done, which is the timeout of the callers.
+func (c *Cluster) WaitForQuorum(ctx context.Context) error {
+	for {
+		// the gate does not wait for the mon orchestration or the health check to read the cluster info
+		c.clusterInfoMutex.RLock()
+		clusterInfo := c.ClusterInfo
+		c.clusterInfoMutex.RUnlock()
+
+		if clusterInfo != nil {
+			status, err := client.GetMonStatus(c.context, clusterInfo.Name, false)
+			if err != nil {
+				logger.Debugf("failed to get mon status. %+v", err)
+			} else if len(status.MonMap.Mons) > 0 && len(status.Quorum) == len(status.MonMap.Mons) {
+				return nil
+			} else {
+				logger.Infof("waiting for all mons to be in quorum. %d of %d mons are in quorum", len(status.Quorum), len(status.MonMap.Mons))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for the mon quorum. %+v", ctx.Err())
+		case <-time.After(c.monPodRetryInterval):
+		}
+	}
+}
+
+// WaitForMonHealthGate waits for the gate to be released within the health gate timeout. There is
+// nothing to wait for without a gate.
+func WaitForMonHealthGate(gate MonHealthGate) error {
+	if gate == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), HealthGateTimeout)
+	defer cancel()
+	return gate.WaitForQuorum(ctx)
+}
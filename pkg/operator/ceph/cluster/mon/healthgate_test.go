@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestMonHealthGate(t *testing.T) {
+	// the mons join the quorum one at a time
+	statuses := []string{
+		`{"quorum":[],"monmap":{"mons":[]}}`,
+		`{"quorum":[0],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`,
+		`{"quorum":[0,1],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`,
+		`{"quorum":[0,1,2],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`,
+	}
+	checks := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon_status" {
+				checks++
+				if checks == 1 {
+					return "", fmt.Errorf("mons not started")
+				}
+				i := checks - 2
+				if i >= len(statuses) {
+					i = len(statuses) - 1
+				}
+				return statuses[i], nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(1), Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	var gate MonHealthGate = c
+
+	// the gate is not released before the cluster info is known
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, gate.WaitForQuorum(ctx))
+	assert.Equal(t, 0, checks)
+
+	// the gate is released once all the mons are in quorum
+	c.ClusterInfo = test.CreateConfigDir(3)
+	assert.NoError(t, gate.WaitForQuorum(context.Background()))
+	assert.Equal(t, 5, checks)
+
+	// the gate does not wait for the mon orchestration
+	c.acquireOrchestrationLock()
+	checks = 1
+	assert.NoError(t, gate.WaitForQuorum(context.Background()))
+	c.releaseOrchestrationLock()
+
+	// the cluster info is not read while it is replaced
+	c.clusterInfoMutex.Lock()
+	checks = 1
+	done := make(chan error)
+	go func() { done <- gate.WaitForQuorum(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, checks)
+	c.clusterInfoMutex.Unlock()
+	assert.NoError(t, <-done)
+
+	// the wait ends with the context
+	statuses = []string{`{"quorum":[0],"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1}]}}`}
+	checks = 1
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := gate.WaitForQuorum(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped waiting for the mon quorum")
+
+	// there is nothing to wait for without a gate
+	assert.NoError(t, WaitForMonHealthGate(nil))
+}
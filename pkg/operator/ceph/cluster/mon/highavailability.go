@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkMonHighAvailability checks that no two mons run on the same host. The host of a mon is the
+// hostname label of its node. Mons on the same host fail together, so they do not add to the
+// failures the quorum tolerates. If mons share a host, false is returned with an explanation and a
+// warning event is reported.
+func checkMonHighAvailability(ctx context.Context, cluster *Cluster) (bool, string, error) {
+	if err := ctx.Err(); err != nil {
+		return false, "", err
+	}
+
+	hostMons := map[string][]string{}
+	for name, info := range cluster.mapping.Node {
+		if info == nil {
+			continue
+		}
+		node, err := cluster.context.Clientset.CoreV1().Nodes().Get(info.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", fmt.Errorf("failed to get node %s of mon %s. %+v", info.Name, name, err)
+		}
+		host, ok := node.Labels[v1.LabelHostname]
+		if !ok {
+			host = info.Hostname
+		}
+		hostMons[host] = append(hostMons[host], name)
+	}
+
+	shared := []string{}
+	for host, mons := range hostMons {
+		if len(mons) > 1 {
+			sort.Strings(mons)
+			shared = append(shared, fmt.Sprintf("mons %s on host %s", strings.Join(mons, ", "), host))
+		}
+	}
+	if len(shared) == 0 {
+		return true, "", nil
+	}
+
+	sort.Strings(shared)
+	msg := fmt.Sprintf("the mons are not highly available since they share hosts: %s", strings.Join(shared, "; "))
+	logger.Warningf(msg)
+	cluster.recordEvent(v1.EventTypeWarning, "MonsShareHost", msg)
+	return false, msg, nil
+}
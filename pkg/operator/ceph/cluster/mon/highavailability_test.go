@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestCheckMonHighAvailability(t *testing.T) {
+	clientset := test.New(3)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, false, v1.ResourceRequirements{})
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	for i, name := range []string{"node0", "node1", "node2"} {
+		node, err := clientset.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		node.Labels = map[string]string{v1.LabelHostname: []string{"host0", "host1", "host2"}[i]}
+		_, err = clientset.CoreV1().Nodes().Update(node)
+		assert.NoError(t, err)
+	}
+
+	// each mon is on its own host
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0", Hostname: "host0"}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "host1"}
+	c.mapping.Node["c"] = &NodeInfo{Name: "node2", Hostname: "host2"}
+	ok, msg, err := checkMonHighAvailability(context.Background(), c)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, msg)
+	assert.Empty(t, recorder.Events)
+
+	// node2 is relabeled to the host of node0
+	node, err := clientset.CoreV1().Nodes().Get("node2", metav1.GetOptions{})
+	assert.NoError(t, err)
+	node.Labels[v1.LabelHostname] = "host0"
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.NoError(t, err)
+	ok, msg, err = checkMonHighAvailability(context.Background(), c)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, msg, "mons a, c on host host0")
+	assert.Contains(t, <-recorder.Events, "MonsShareHost")
+
+	// the node of a mon is missing
+	c.mapping.Node["d"] = &NodeInfo{Name: "node9"}
+	_, _, err = checkMonHighAvailability(context.Background(), c)
+	assert.Error(t, err)
+	delete(c.mapping.Node, "d")
+
+	// the check is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = checkMonHighAvailability(ctx, c)
+	assert.Error(t, err)
+}
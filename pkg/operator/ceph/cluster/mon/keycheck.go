@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// minMonKeyEntropyBits is the entropy a key generated with ceph-authtool has at least
+	minMonKeyEntropyBits = 128
+	// the size of the type, creation time and secret length that precede the secret in a ceph key
+	cephKeyHeaderSize = 12
+)
+
+// validateMonKeyEntropy checks that a key has enough entropy to protect the cluster. The secret of the
+// key must be at least 128 bits long and its bytes must not be mostly repeated, which would mean it
+// was not randomly generated.
+func validateMonKeyEntropy(key string) error {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("failed to decode key. %+v", err)
+	}
+
+	secret := cephKeySecret(decoded)
+	bits := monKeyEntropyBits(secret)
+	if bits < minMonKeyEntropyBits {
+		return fmt.Errorf("key has %d bits of entropy, at least %d are required", bits, minMonKeyEntropyBits)
+	}
+	return nil
+}
+
+// cephKeySecret returns the secret of a ceph key, or all of the decoded bytes if they are not a ceph key
+func cephKeySecret(decoded []byte) []byte {
+	if len(decoded) < cephKeyHeaderSize {
+		return decoded
+	}
+	secretLen := int(binary.LittleEndian.Uint16(decoded[cephKeyHeaderSize-2 : cephKeyHeaderSize]))
+	if len(decoded) != cephKeyHeaderSize+secretLen {
+		return decoded
+	}
+	return decoded[cephKeyHeaderSize:]
+}
+
+// monKeyEntropyBits estimates the bits of entropy of a secret. Each byte counts for 8 bits, but a
+// secret with fewer distinct bytes than half its length is not random and counts only for the
+// distinct bytes.
+func monKeyEntropyBits(secret []byte) int {
+	distinct := map[byte]bool{}
+	for _, b := range secret {
+		distinct[b] = true
+	}
+	if len(distinct) < len(secret)/2 {
+		return 8 * len(distinct)
+	}
+	return 8 * len(secret)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMonKeyEntropy(t *testing.T) {
+	// keys generated by ceph-authtool have a 128 bit secret
+	assert.Nil(t, validateMonKeyEntropy("AQCATJRdAAAAABAAw3sp0Ji3MfFf4XFez5WBrg=="))
+	assert.Nil(t, validateMonKeyEntropy("AQCATJRdAAAAABAAcmaKuuNct7MZyDXk/J7miQ=="))
+
+	// a 64 bit secret
+	err := validateMonKeyEntropy("AQCATJRdAAAAAAgAw0nbGm1iSA4=")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "64 bits")
+
+	// a secret of zeros is not random
+	err = validateMonKeyEntropy("AQCATJRdAAAAABAAAAAAAAAAAAAAAAAAAAAAAA==")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "8 bits")
+
+	// not base64
+	assert.NotNil(t, validateMonKeyEntropy("monsecret"))
+
+	// a plain secret that is not a ceph key counts by its bytes
+	assert.Nil(t, validateMonKeyEntropy("MDEyMzQ1Njc4OWFiY2RlZg=="))
+	assert.NotNil(t, validateMonKeyEntropy("YWJjZGVm"))
+}
+
+func TestCreateClusterInfoRejectsWeakKeys(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	monKey := "AQCATJRdAAAAABAAcmaKuuNct7MZyDXk/J7miQ=="
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			// the keyring path follows --create-keyring
+			keyring := args[1]
+			key := "AQCATJRdAAAAABAAw3sp0Ji3MfFf4XFez5WBrg=="
+			if strings.HasSuffix(keyring, "mon.keyring") {
+				key = monKey
+			}
+			return "", ioutil.WriteFile(keyring, []byte("key = "+key), 0644)
+		},
+	}
+	context := &clusterd.Context{Executor: executor, ConfigDir: configDir}
+
+	info, err := createNamedClusterInfo(context, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, monKey, info.MonitorSecret)
+
+	// a weak mon key is refused
+	monKey = "AQCATJRdAAAAABAAAAAAAAAAAAAAAAAAAAAAAA=="
+	info, err = createNamedClusterInfo(context, "ns")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid mon secret")
+	assert.Nil(t, info)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monKeyVersionAnnotation is the annotation of the mon secret with the version of the mon keyring.
+	// The mon pods have a label with the same key for the version of the keyring they started with.
+	monKeyVersionAnnotation = "rook.io/mon-key-version"
+	// the version of a mon secret created before the keyring was versioned
+	initialMonKeyVersion = "1"
+)
+
+// loadMonKeyVersion reads the version of the mon keyring from the mon secret
+func (c *Cluster) loadMonKeyVersion() error {
+	secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the mon secret. %+v", err)
+	}
+	c.monKeyVersion = initialMonKeyVersion
+	if version, ok := secret.Annotations[monKeyVersionAnnotation]; ok && version != "" {
+		c.monKeyVersion = version
+	}
+	return nil
+}
+
+// incrementMonKeyVersion increments the version of the mon keyring in the mon secret when the keyring
+// is rotated. The mons are labeled with the new version when their deployments are updated. The new
+// version is returned.
+func (c *Cluster) incrementMonKeyVersion() (string, error) {
+	secrets := c.context.Clientset.CoreV1().Secrets(c.Namespace)
+	secret, err := secrets.Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the mon secret. %+v", err)
+	}
+
+	current := initialMonKeyVersion
+	if version, ok := secret.Annotations[monKeyVersionAnnotation]; ok && version != "" {
+		current = version
+	}
+	n, err := strconv.Atoi(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid mon key version %q. %+v", current, err)
+	}
+	version := strconv.Itoa(n + 1)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[monKeyVersionAnnotation] = version
+	if _, err := secrets.Update(secret); err != nil {
+		return "", fmt.Errorf("failed to update the mon key version to %s. %+v", version, err)
+	}
+	logger.Infof("mon key version is now %s", version)
+	c.monKeyVersion = version
+	return version, nil
+}
+
+// waitForMonKeyRollout waits until the pods of all the mons are labeled with the target key version,
+// which means they were restarted with the keyring of that version
+func waitForMonKeyRollout(ctx context.Context, cluster *Cluster, targetVersion string) error {
+	selector := MonLabelSelector(cluster.Namespace, "").String()
+	for {
+		pods, err := cluster.context.Clientset.CoreV1().Pods(cluster.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			logger.Debugf("failed to list the mon pods. %+v", err)
+		} else {
+			// a mon is pending until it has a pod with the target version and no pod with another
+			// version, e.g. the pod with the old key that is still terminating
+			updated := map[string]bool{}
+			outdated := map[string]bool{}
+			for _, pod := range pods.Items {
+				name := pod.Labels[monDaemonAttr]
+				if pod.Labels[monKeyVersionAnnotation] == targetVersion && pod.DeletionTimestamp == nil {
+					updated[name] = true
+				} else {
+					outdated[name] = true
+				}
+			}
+			pending := []string{}
+			for name := range cluster.ClusterInfo.Monitors {
+				if !updated[name] || outdated[name] {
+					pending = append(pending, name)
+				}
+			}
+			if len(pending) == 0 {
+				logger.Infof("all mons use key version %s", targetVersion)
+				return nil
+			}
+			logger.Debugf("waiting for mons %v to use key version %s", pending, targetVersion)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for the mons to use key version %s. %+v", targetVersion, ctx.Err())
+		case <-time.After(cluster.monPodRetryInterval):
+		}
+	}
+}
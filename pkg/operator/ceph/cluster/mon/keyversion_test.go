@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMonKeyVersion(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	namespace := "ns"
+	clusterContext := newTestStartCluster(namespace)
+	defer os.RemoveAll(clusterContext.ConfigDir)
+	c := newCluster(clusterContext, namespace, false, true, v1.ResourceRequirements{})
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+
+	// a new cluster starts with the first key version
+	secret, err := clusterContext.Clientset.CoreV1().Secrets(namespace).Get(AppName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, initialMonKeyVersion, secret.Annotations[monKeyVersionAnnotation])
+	d, err := clusterContext.Clientset.AppsV1().Deployments(namespace).Get(resourceName("a"), metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, initialMonKeyVersion, d.Spec.Template.Labels[monKeyVersionAnnotation])
+
+	// the mons are updated with the new version when the key is rotated
+	*deploymentsUpdated = []*apps.Deployment{}
+	version, err := c.incrementMonKeyVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "2", version)
+	secret, err = clusterContext.Clientset.CoreV1().Secrets(namespace).Get(AppName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", secret.Annotations[monKeyVersionAnnotation])
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{resourceName("a"), resourceName("b"), resourceName("c")}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+	for _, d := range *deploymentsUpdated {
+		assert.Equal(t, "2", d.Spec.Template.Labels[monKeyVersionAnnotation])
+	}
+
+	// a secret without a version has the first version
+	delete(secret.Annotations, monKeyVersionAnnotation)
+	_, err = clusterContext.Clientset.CoreV1().Secrets(namespace).Update(secret)
+	assert.NoError(t, err)
+	assert.NoError(t, c.loadMonKeyVersion())
+	assert.Equal(t, initialMonKeyVersion, c.monKeyVersion)
+}
+
+func TestWaitForMonKeyRollout(t *testing.T) {
+	namespace := "ns"
+	clusterContext := newTestStartCluster(namespace)
+	defer os.RemoveAll(clusterContext.ConfigDir)
+	c := newCluster(clusterContext, namespace, false, true, v1.ResourceRequirements{})
+	c.monPodRetryInterval = time.Millisecond
+	c.ClusterInfo = test.CreateConfigDir(2)
+	pods := clusterContext.Clientset.CoreV1().Pods(namespace)
+	setPodKeyVersion := func(pod, mon, version string) {
+		labels := MonLabels(namespace, mon)
+		labels[monKeyVersionAnnotation] = version
+		p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: namespace, Labels: labels}}
+		if _, err := pods.Get(pod, metav1.GetOptions{}); err == nil {
+			_, err = pods.Update(p)
+			assert.NoError(t, err)
+			return
+		}
+		_, err := pods.Create(p)
+		assert.NoError(t, err)
+	}
+	waitShortly := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		return waitForMonKeyRollout(ctx, c, "2")
+	}
+
+	// mon b still has the old key
+	setPodKeyVersion("a-1", "a", "2")
+	setPodKeyVersion("b-1", "b", "1")
+	assert.Error(t, waitShortly())
+
+	// the new pod of mon b is running, but the old one is still there
+	setPodKeyVersion("b-2", "b", "2")
+	assert.Error(t, waitShortly())
+
+	// all the mon pods have the new key
+	assert.NoError(t, pods.Delete("b-1", &metav1.DeleteOptions{}))
+	assert.NoError(t, waitShortly())
+}
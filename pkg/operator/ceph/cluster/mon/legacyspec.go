@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+)
+
+const (
+	// legacyMonCountKey is the mon count in the cluster spec of rook 0.7, before the mon settings
+	// were moved to the mon section
+	legacyMonCountKey = "monCount"
+	monSpecKey        = "mon"
+	monCountKey       = "count"
+	preferredCountKey = "preferredCount"
+	allowMultipleKey  = "allowMultiplePerNode"
+)
+
+// MigrateLegacyMonSpec converts the mon settings of a cluster spec written for a rook release before
+// 1.0 to the current mon spec. The legacy spec is the undecoded "spec" of the cluster CR:
+//   - the mon count of the top level "monCount" of rook 0.7 becomes the count of the mon section
+//   - counts written as strings or floats and booleans written as strings are converted
+//   - a missing count is set to the default mon count
+//
+// The settings of the mon section that have not changed are decoded as they are.
+func MigrateLegacyMonSpec(legacy map[string]interface{}) (*cephv1.MonSpec, error) {
+	mon := map[string]interface{}{}
+	if section, ok := legacy[monSpecKey]; ok && section != nil {
+		legacyMon, ok := section.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid mon settings %v. the mon settings must be an object", section)
+		}
+		for key, value := range legacyMon {
+			mon[key] = value
+		}
+	}
+
+	if legacyCount, ok := legacy[legacyMonCountKey]; ok {
+		count, err := legacyInt(legacyMonCountKey, legacyCount)
+		if err != nil {
+			return nil, err
+		}
+		if current, ok := mon[monCountKey]; ok {
+			if c, err := legacyInt(monCountKey, current); err != nil || c != count {
+				return nil, fmt.Errorf("conflicting mon counts %v in %s and %v in the mon settings", legacyCount, legacyMonCountKey, current)
+			}
+		}
+		mon[monCountKey] = count
+	}
+
+	for _, key := range []string{monCountKey, preferredCountKey} {
+		if value, ok := mon[key]; ok {
+			count, err := legacyInt(key, value)
+			if err != nil {
+				return nil, err
+			}
+			mon[key] = count
+		}
+	}
+	if value, ok := mon[allowMultipleKey]; ok {
+		allow, err := legacyBool(allowMultipleKey, value)
+		if err != nil {
+			return nil, err
+		}
+		mon[allowMultipleKey] = allow
+	}
+
+	// the remaining settings have the same names and types as in the current spec
+	data, err := json.Marshal(mon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the mon settings. %+v", err)
+	}
+	spec := &cephv1.MonSpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("failed to convert the mon settings. %+v", err)
+	}
+	if spec.Count == 0 {
+		spec.Count = DefaultMonCount
+	}
+	return spec, nil
+}
+
+// LegacyMonSpecPatch returns the json patch that converts the legacy mon settings in the undecoded
+// "spec" of a cluster CR to the current mon spec with MigrateLegacyMonSpec. False is returned if the
+// mon settings of the spec can already be decoded as they are.
+func LegacyMonSpecPatch(legacy map[string]interface{}) ([]byte, bool, error) {
+	_, hasLegacyCount := legacy[legacyMonCountKey]
+	if !hasLegacyCount {
+		data, err := json.Marshal(legacy[monSpecKey])
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to serialize the mon settings. %+v", err)
+		}
+		if err := json.Unmarshal(data, &cephv1.MonSpec{}); err == nil {
+			return nil, false, nil
+		}
+	}
+
+	spec, err := MigrateLegacyMonSpec(legacy)
+	if err != nil {
+		return nil, false, err
+	}
+	type patchOp struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value,omitempty"`
+	}
+	// adding a member that exists replaces it
+	patch := []patchOp{{Op: "add", Path: "/spec/" + monSpecKey, Value: spec}}
+	if hasLegacyCount {
+		patch = append(patch, patchOp{Op: "remove", Path: "/spec/" + legacyMonCountKey})
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to serialize the mon settings patch. %+v", err)
+	}
+	return data, true, nil
+}
+
+// legacyInt converts a whole number written as a number or a string to an int
+func legacyInt(key string, value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("invalid %s %v. the value must be a whole number", key, v)
+		}
+		return int(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q. %+v", key, v, err)
+		}
+		return int(i), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q. %+v", key, v, err)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("invalid %s %v of type %T", key, value, value)
+}
+
+// legacyBool converts a boolean written as a boolean or a string to a bool
+func legacyBool(key string, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s %q. %+v", key, v, err)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("invalid %s %v of type %T", key, value, value)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateLegacyMonSpec(t *testing.T) {
+	decode := func(raw string) map[string]interface{} {
+		legacy := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(raw), &legacy))
+		return legacy
+	}
+
+	// the mon count of rook 0.7 is moved to the mon section
+	spec, err := MigrateLegacyMonSpec(decode(`{"monCount": 5, "dataDirHostPath": "/var/lib/rook"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, spec.Count)
+	assert.False(t, spec.AllowMultiplePerNode)
+	spec, err = MigrateLegacyMonSpec(decode(`{"monCount": "5", "mon": {"count": 5}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, spec.Count)
+	_, err = MigrateLegacyMonSpec(decode(`{"monCount": 5, "mon": {"count": 3}}`))
+	assert.Error(t, err)
+	_, err = MigrateLegacyMonSpec(decode(`{"monCount": 2.5}`))
+	assert.Error(t, err)
+
+	// counts written as strings
+	spec, err = MigrateLegacyMonSpec(decode(`{"mon": {"count": "3", "preferredCount": "5"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, spec.Count)
+	assert.Equal(t, 5, spec.PreferredCount)
+	_, err = MigrateLegacyMonSpec(decode(`{"mon": {"count": "three"}}`))
+	assert.Error(t, err)
+	_, err = MigrateLegacyMonSpec(decode(`{"mon": {"preferredCount": true}}`))
+	assert.Error(t, err)
+
+	// booleans written as strings
+	spec, err = MigrateLegacyMonSpec(decode(`{"mon": {"count": 3, "allowMultiplePerNode": "true"}}`))
+	assert.NoError(t, err)
+	assert.True(t, spec.AllowMultiplePerNode)
+	spec, err = MigrateLegacyMonSpec(decode(`{"mon": {"count": 3, "allowMultiplePerNode": false}}`))
+	assert.NoError(t, err)
+	assert.False(t, spec.AllowMultiplePerNode)
+	_, err = MigrateLegacyMonSpec(decode(`{"mon": {"allowMultiplePerNode": "sometimes"}}`))
+	assert.Error(t, err)
+
+	// the default count is filled in
+	spec, err = MigrateLegacyMonSpec(decode(`{"dataDirHostPath": "/var/lib/rook"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMonCount, spec.Count)
+	spec, err = MigrateLegacyMonSpec(decode(`{"mon": null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMonCount, spec.Count)
+
+	// the settings that did not change are kept
+	spec, err = MigrateLegacyMonSpec(decode(`{"mon": {"count": 3, "cpuSet": "0-1"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "0-1", spec.CPUSet)
+	_, err = MigrateLegacyMonSpec(decode(`{"mon": "3"}`))
+	assert.Error(t, err)
+}
+
+func TestLegacyMonSpecPatch(t *testing.T) {
+	decode := func(raw string) map[string]interface{} {
+		legacy := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(raw), &legacy))
+		return legacy
+	}
+
+	// the current mon settings are not patched
+	_, ok, err := LegacyMonSpecPatch(decode(`{"mon": {"count": 3, "allowMultiplePerNode": true}}`))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = LegacyMonSpecPatch(decode(`{"dataDirHostPath": "/var/lib/rook"}`))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the mon count of rook 0.7 is moved to the mon section
+	patch, ok, err := LegacyMonSpecPatch(decode(`{"monCount": 5}`))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `[{"op":"add","path":"/spec/mon","value":{"count":5}},{"op":"remove","path":"/spec/monCount"}]`, string(patch))
+
+	// the mon section is replaced if it cannot be decoded
+	patch, ok, err = LegacyMonSpecPatch(decode(`{"mon": {"count": "3", "allowMultiplePerNode": "true"}}`))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `[{"op":"add","path":"/spec/mon","value":{"count":3,"allowMultiplePerNode":true}}]`, string(patch))
+
+	// invalid legacy settings are not converted
+	_, _, err = LegacyMonSpecPatch(decode(`{"mon": {"count": "three"}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// the admin socket of a mon with the default run dir and cluster name
+	monAdminSocketPathFormat = "/var/run/ceph/ceph-mon.%s.asok"
+	// the file in the mon container with the last time the probe found the mon in quorum
+	monLastInQuorumFile = "/tmp/mon-last-in-quorum"
+	// the seconds between two runs of the quorum liveness probe
+	monQuorumLivenessPeriodSeconds = 30
+)
+
+// makeMonQuorumLivenessProbe returns a liveness probe that fails if the mon does not answer on its
+// admin socket or has been out of quorum for longer than the timeout. A mon is in quorum when its
+// own mon_status reports it as the leader or a peon. The time is counted from the start of the
+// container until the mon first joins the quorum.
+func makeMonQuorumLivenessProbe(daemonName string, timeoutMinutes int) *v1.Probe {
+	socket := fmt.Sprintf(monAdminSocketPathFormat, daemonName)
+	script := fmt.Sprintf(`now=$(date +%%s)
+status=$(ceph --admin-daemon %s mon_status) || exit 1
+if echo "$status" | grep -Eq '"state": "(leader|peon)"'; then
+  echo $now > %s
+  exit 0
+fi
+[ -f %s ] || echo $now > %s
+last=$(cat %s)
+echo "mon %s is out of quorum since $(( now - last ))s"
+[ $(( now - last )) -lt %d ]`,
+		socket, monLastInQuorumFile, monLastInQuorumFile, monLastInQuorumFile, monLastInQuorumFile, daemonName, timeoutMinutes*60)
+
+	return &v1.Probe{
+		Handler: v1.Handler{
+			Exec: &v1.ExecAction{
+				Command: []string{"/bin/sh", "-c", script},
+			},
+		},
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       monQuorumLivenessPeriodSeconds,
+	}
+}
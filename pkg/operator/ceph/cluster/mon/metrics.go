@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	metricsNamespace = "rook"
+	metricsSubsystem = "ceph_mon"
+
+	// the phases of the mon reconcile that are timed
+	reconcilePhaseTotal       = "total"
+	reconcilePhaseScheduling  = "scheduling"
+	reconcilePhaseCreation    = "creation"
+	reconcilePhaseQuorumWait  = "quorum_wait"
+	reconcilePhasePersistence = "persistence"
+)
+
+var (
+	// monExpectedCount is the number of mons in the cluster spec
+	monExpectedCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "expected_count",
+		Help:      "The number of mons the operator expects in the cluster.",
+	}, []string{"namespace"})
+	// monQuorumCount is the number of mons found in quorum by the last mon health check
+	monQuorumCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "quorum_count",
+		Help:      "The number of mons in quorum at the last mon health check.",
+	}, []string{"namespace"})
+	// monFailovers is the number of mon failovers started by the operator since it started
+	monFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "failovers_total",
+		Help:      "The number of mon failovers started by the operator.",
+	}, []string{"namespace"})
+	// monReconcileDuration is the time each phase of the mon reconcile takes. the quorum wait can take
+	// up to several minutes per mon, so the buckets range from one second to about half an hour.
+	monReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "reconcile_duration_seconds",
+		Help:      "The time the phases of the mon reconcile take.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"namespace", "phase"})
+
+	// the rocksdb perf counters of each mon from the last collection. the counters are set to the
+	// values ceph reports since the mon started.
+	monRocksDBGets               = newMonGauge("rocksdb_gets", "The number of rocksdb reads of the mon since it started.")
+	monRocksDBTransactions       = newMonGauge("rocksdb_transactions", "The number of rocksdb transactions of the mon since it started.")
+	monRocksDBSyncTransactions   = newMonGauge("rocksdb_sync_transactions", "The number of synchronous rocksdb transactions of the mon since it started.")
+	monRocksDBGetLatency         = newMonGauge("rocksdb_get_latency_seconds", "The average latency of the rocksdb reads of the mon.")
+	monRocksDBSubmitLatency      = newMonGauge("rocksdb_submit_latency_seconds", "The average latency of the rocksdb transactions of the mon.")
+	monRocksDBSubmitSyncLatency  = newMonGauge("rocksdb_submit_sync_latency_seconds", "The average latency of the synchronous rocksdb transactions of the mon, which includes syncing the WAL.")
+	monRocksDBCompactions        = newMonGauge("rocksdb_compactions", "The number of rocksdb compactions of the mon since it started.")
+	monRocksDBCompactQueueLength = newMonGauge("rocksdb_compact_queue_length", "The number of rocksdb compactions the mon has queued.")
+
+	// the scores of each mon endpoint from the last time the mons were scored
+	monEndpointScore        = newMonGauge("endpoint_score", "The overall score of the mon endpoint between 0 (unusable) and 1 (healthy).")
+	monEndpointAvailability = newMonGauge("endpoint_availability", "The fraction of the probes that connected to the mon endpoint.")
+	monEndpointErrorRate    = newMonGauge("endpoint_error_rate", "The fraction of the probes of the mon endpoint that failed with an error other than a timeout.")
+	monEndpointP99Latency   = newMonGauge("endpoint_p99_latency_seconds", "The 99th percentile of the time to connect to the mon endpoint.")
+
+	monEndpointScoreGauges = []*prometheus.GaugeVec{monEndpointScore, monEndpointAvailability, monEndpointErrorRate, monEndpointP99Latency}
+
+	monRocksDBGauges = []*prometheus.GaugeVec{
+		monRocksDBGets, monRocksDBTransactions, monRocksDBSyncTransactions, monRocksDBGetLatency,
+		monRocksDBSubmitLatency, monRocksDBSubmitSyncLatency, monRocksDBCompactions, monRocksDBCompactQueueLength,
+	}
+)
+
+func init() {
+	prometheus.MustRegister(monExpectedCount, monQuorumCount, monFailovers, monReconcileDuration)
+	for _, gauge := range append(monRocksDBGauges, monEndpointScoreGauges...) {
+		prometheus.MustRegister(gauge)
+	}
+}
+
+func newMonGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      name,
+		Help:      help,
+	}, []string{"namespace", "mon"})
+}
+
+// updateQuorumMetrics sets the mon gauges of the cluster from the mon status. A nil status means the
+// status could not be retrieved from the mons, so no mon is counted in quorum.
+func (c *Cluster) updateQuorumMetrics(status *client.MonStatusResponse) {
+	monExpectedCount.WithLabelValues(c.Namespace).Set(float64(c.spec.Mon.Count))
+
+	inQuorum := 0
+	if status != nil {
+		for _, mon := range status.MonMap.Mons {
+			if monFoundInQuorum(mon.Name, *status) {
+				inQuorum++
+			}
+		}
+	}
+	monQuorumCount.WithLabelValues(c.Namespace).Set(float64(inQuorum))
+}
+
+// updateMonScoreMetrics sets the score gauges of the mon endpoint
+func updateMonScoreMetrics(namespace, name string, score MonScore) {
+	monEndpointScore.WithLabelValues(namespace, name).Set(score.Overall)
+	monEndpointAvailability.WithLabelValues(namespace, name).Set(score.Availability)
+	monEndpointErrorRate.WithLabelValues(namespace, name).Set(score.ErrorRate)
+	monEndpointP99Latency.WithLabelValues(namespace, name).Set(score.P99LatencyMs / 1000)
+}
+
+// deleteMonScoreMetrics removes the score gauges of a mon that was removed
+func deleteMonScoreMetrics(namespace, name string) {
+	for _, gauge := range monEndpointScoreGauges {
+		gauge.DeleteLabelValues(namespace, name)
+	}
+}
+
+// observeReconcilePhase records the time since the start of a phase of the mon reconcile
+func (c *Cluster) observeReconcilePhase(phase string, start time.Time) {
+	monReconcileDuration.WithLabelValues(c.Namespace, phase).Observe(time.Since(start).Seconds())
+}
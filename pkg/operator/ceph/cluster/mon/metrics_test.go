@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/metrics"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func metricValue(t *testing.T, metric prometheus.Metric) float64 {
+	m := &dto.Metric{}
+	assert.NoError(t, metric.Write(m))
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
+
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	assert.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.Histogram.GetSampleCount()
+}
+
+// scrapeMetrics returns the metrics as they are served by the operator
+func scrapeMetrics(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", metrics.Path, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func TestMonMetrics(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	defer os.RemoveAll(c.context.ConfigDir)
+	c.Namespace = "metrics-ns"
+	failovers := metricValue(t, monFailovers.WithLabelValues(c.Namespace))
+
+	// mon c is out of quorum
+	status := client.MonStatusResponse{Quorum: []int{0, 1}}
+	status.MonMap.Mons = []client.MonMapEntry{
+		{Name: "a", Rank: 0},
+		{Name: "b", Rank: 1},
+		{Name: "c", Rank: 2},
+	}
+	c.reportMonHealth(&status, 3)
+	assert.Equal(t, float64(3), metricValue(t, monExpectedCount.WithLabelValues(c.Namespace)))
+	assert.Equal(t, float64(2), metricValue(t, monQuorumCount.WithLabelValues(c.Namespace)))
+
+	// the failover is counted
+	assert.NoError(t, c.failoverMon("c"))
+	assert.Equal(t, failovers+1, metricValue(t, monFailovers.WithLabelValues(c.Namespace)))
+
+	// the metrics are served by the operator
+	served := scrapeMetrics(t)
+	assert.Contains(t, served, fmt.Sprintf(`rook_ceph_mon_failovers_total{namespace="metrics-ns"} %v`, failovers+1))
+	assert.Contains(t, served, `rook_ceph_mon_quorum_count{namespace="metrics-ns"} 2`)
+	assert.Contains(t, served, `rook_ceph_mon_expected_count{namespace="metrics-ns"} 3`)
+
+	// all the mons are in quorum again
+	status = client.MonStatusResponse{Quorum: []int{0, 1, 2}}
+	status.MonMap.Mons = []client.MonMapEntry{
+		{Name: "a", Rank: 0},
+		{Name: "b", Rank: 1},
+		{Name: "d", Rank: 2},
+	}
+	c.reportMonHealth(&status, 3)
+	assert.Equal(t, float64(3), metricValue(t, monQuorumCount.WithLabelValues(c.Namespace)))
+
+	// no mon is counted in quorum if the mons cannot be reached
+	c.reportMonHealth(nil, 0)
+	assert.Equal(t, float64(0), metricValue(t, monQuorumCount.WithLabelValues(c.Namespace)))
+	assert.Equal(t, failovers+1, metricValue(t, monFailovers.WithLabelValues(c.Namespace)))
+}
+
+func TestMonReconcileDurationMetrics(t *testing.T) {
+	namespace := "duration-ns"
+	context := newTestStartCluster(namespace)
+	defer os.RemoveAll(context.ConfigDir)
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	phases := []string{reconcilePhaseTotal, reconcilePhaseScheduling, reconcilePhaseCreation, reconcilePhasePersistence}
+	for _, phase := range phases {
+		assert.Equal(t, uint64(0), histogramCount(t, monReconcileDuration.WithLabelValues(namespace, phase)))
+	}
+
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.Nil(t, err)
+
+	// the reconcile is timed once and each of the three mons is created
+	assert.Equal(t, uint64(1), histogramCount(t, monReconcileDuration.WithLabelValues(namespace, reconcilePhaseTotal)))
+	assert.Equal(t, uint64(1), histogramCount(t, monReconcileDuration.WithLabelValues(namespace, reconcilePhaseScheduling)))
+	assert.True(t, histogramCount(t, monReconcileDuration.WithLabelValues(namespace, reconcilePhaseCreation)) >= 3)
+	assert.True(t, histogramCount(t, monReconcileDuration.WithLabelValues(namespace, reconcilePhasePersistence)) >= 3)
+
+	// the quorum is not awaited in the unit tests
+	assert.Equal(t, uint64(0), histogramCount(t, monReconcileDuration.WithLabelValues(namespace, reconcilePhaseQuorumWait)))
+
+	// the histogram is served by the operator
+	assert.Contains(t, scrapeMetrics(t), `rook_ceph_mon_reconcile_duration_seconds_count{namespace="duration-ns",phase="total"} 1`)
+}
@@ -20,8 +20,10 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -43,6 +45,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -50,15 +53,23 @@ const (
 	EndpointConfigMapName = "rook-ceph-mon-endpoints"
 	// EndpointDataKey is the name of the key inside the mon configmap to get the endpoints
 	EndpointDataKey = "data"
+	// EndpointAddrVecDataKey is the name of the key inside the mon configmap to get the endpoints with
+	// both the msgr2 and msgr1 addresses on nautilus and newer. The endpoint data key keeps the
+	// endpoints with only the msgr1 address, since the daemons and agents of previous versions of rook
+	// cannot parse the address vectors.
+	EndpointAddrVecDataKey = "addrvec"
 	// MaxMonIDKey is the name of the max mon id used
 	MaxMonIDKey = "maxMonId"
 	// MappingKey is the name of the mapping for the mon->node and node->port
 	MappingKey = "mapping"
+	// ShuffleSeedKey is the name of the counter that seeds the order of the shuffled mon endpoints
+	ShuffleSeedKey = "shuffleSeed"
 
 	// AppName is the name of the secret storing cluster mon.admin key, fsid and name
 	AppName           = "rook-ceph-mon"
 	monNodeAttr       = "mon_node"
 	monClusterAttr    = "mon_cluster"
+	monDaemonAttr     = "mon"
 	tprName           = "mon.rook.io"
 	fsidSecretName    = "fsid"
 	monSecretName     = "mon-secret"
@@ -69,6 +80,9 @@ const (
 	DefaultMonCount = 3
 	// MaxMonCount Maximum allowed mon count for a cluster
 	MaxMonCount = 9
+	// DefaultMaxMonChangeRate is the default number of mons added or removed at once when the mon
+	// count changes
+	DefaultMaxMonChangeRate = 1
 
 	// DefaultMsgr1Port is the default port Ceph mons use to communicate amongst themselves prior
 	// to Ceph Nautilus.
@@ -98,6 +112,7 @@ type Cluster struct {
 	Keyring             string
 	rookVersion         string
 	orchestrationMutex  sync.Mutex
+	clusterInfoMutex    sync.RWMutex
 	Port                int32
 	HostNetwork         bool
 	maxMonID            int
@@ -106,9 +121,26 @@ type Cluster struct {
 	monPodRetryInterval time.Duration
 	monPodTimeout       time.Duration
 	monTimeoutList      map[string]time.Time
+	degradedMons        map[string]string
+	corruptedMons       map[string]string
+	foreignStoreMons    map[string]string
+	displacedMons       map[string]string
+	monProbeFailures    map[string]int
+	monReplacements     map[string][]time.Time
+	monDebugSections    map[string]int
+	monKeyVersion       string
+	monScores           map[string]*MonScore
+	healthReport        MonHealthReport
+	healthReportMutex   sync.RWMutex
+	monEvents           map[string]map[string]*monEvent
+	lastZoneProbe       time.Time
+	lastMonStatsCheck   time.Time
+	lastMonScore        time.Time
+	pausedSpecChange    *pausedSpecChange
 	mapping             *Mapping
 	ownerRef            metav1.OwnerReference
 	csiConfigMutex      *sync.Mutex
+	recorder            record.EventRecorder
 }
 
 // monConfig for a single monitor
@@ -124,6 +156,11 @@ type monConfig struct {
 	// DataPathMap is the mapping relationship between mon data stored on the host and mon data
 	// stored in containers.
 	DataPathMap *config.DataPathMap
+	// PreferredZone is the failure domain zone to place the mon in if the zone has a valid node
+	PreferredZone string
+	// Replacement is set on a mon that replaces a failed mon, which may use the nodes kept free as
+	// failover headroom
+	Replacement bool
 }
 
 // Mapping is mon node and port mapping
@@ -137,6 +174,8 @@ type NodeInfo struct {
 	Name     string
 	Hostname string
 	Address  string
+	// Zone is the failure domain zone of the node
+	Zone string `json:",omitempty"`
 }
 
 // New creates an instance of a mon cluster
@@ -150,6 +189,15 @@ func New(context *clusterd.Context, namespace, dataDirHostPath string, hostNetwo
 		monPodRetryInterval: 6 * time.Second,
 		monPodTimeout:       5 * time.Minute,
 		monTimeoutList:      map[string]time.Time{},
+		degradedMons:        map[string]string{},
+		corruptedMons:       map[string]string{},
+		foreignStoreMons:    map[string]string{},
+		displacedMons:       map[string]string{},
+		monProbeFailures:    map[string]int{},
+		monReplacements:     map[string][]time.Time{},
+		monDebugSections:    map[string]int{},
+		monScores:           map[string]*MonScore{},
+		monEvents:           map[string]map[string]*monEvent{},
 		HostNetwork:         hostNetwork,
 		mapping: &Mapping{
 			Node: map[string]*NodeInfo{},
@@ -167,36 +215,153 @@ func (c *Cluster) Start(clusterInfo *cephconfig.ClusterInfo, rookVersion string,
 	c.acquireOrchestrationLock()
 	defer c.releaseOrchestrationLock()
 
-	c.ClusterInfo = clusterInfo
+	if c.monReconcilePaused() {
+		msg := fmt.Sprintf("the mon reconcile is paused by the %q annotation of the cluster. the mons are not updated", monReconcilePausedAnnotation)
+		logger.Warningf(msg)
+		c.recordEvent(v1.EventTypeWarning, "MonReconcilePaused", msg)
+		return clusterInfo, nil
+	}
+	// the full orchestration applies the mon settings changed while the reconcile was paused
+	c.pausedSpecChange = nil
+
+	start := time.Now()
+	c.setClusterInfo(clusterInfo)
 	c.rookVersion = rookVersion
 	c.spec = spec
-
-	// fail if we were instructed to deploy more than one mon on the same machine with host networking
-	if c.HostNetwork && c.spec.Mon.AllowMultiplePerNode && c.spec.Mon.Count > 1 {
-		return nil, fmt.Errorf("refusing to deploy %d monitors on the same host since hostNetwork is %v and allowMultiplePerNode is %v. Only one monitor per node is allowed", c.spec.Mon.Count, c.HostNetwork, c.spec.Mon.AllowMultiplePerNode)
+	// the existing mons keep their store until they are moved to the new dataDirHostPath after the
+	// mons are started
+	if spec.DataDirHostPath != "" && spec.DataDirHostPath != c.dataDirHostPath {
+		logger.Infof("dataDirHostPath changed from %s to %s", c.dataDirHostPath, spec.DataDirHostPath)
+		c.dataDirHostPath = spec.DataDirHostPath
 	}
 
-	// Validate pod's memory if specified
-	err := opspec.CheckPodMemory(cephv1.GetMonResources(c.spec.Resources), cephMonPodMinimumMemory)
-	if err != nil {
-		return nil, fmt.Errorf("%v", err)
+	if err := c.validateSpec(); err != nil {
+		return nil, err
 	}
+	if err := validateMonSpecCephVersion(c.spec.Mon, cephVersion); err != nil {
+		return nil, err
+	}
+	reportReconcileProgress(progressPhaseValidation, start)
 
 	logger.Infof("start running mons")
+	defer c.observeReconcilePhase(reconcilePhaseTotal, start)
 
 	logger.Debugf("establishing ceph cluster info")
+	if err := c.defaultMonRunAsNonRoot(); err != nil {
+		return nil, err
+	}
 	if err := c.initClusterInfo(cephVersion); err != nil {
 		return nil, fmt.Errorf("failed to initialize ceph cluster info. %+v", err)
 	}
+	reportReconcileProgress(progressPhaseClusterInfo, start)
+
+	if err := c.validateMonCount(); err != nil {
+		return nil, err
+	}
 
 	targetCount, msg, err := c.getTargetMonCount()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get target mon count. %+v", err)
 	}
 	logger.Infof(msg)
+	targetCount = c.limitMonCountChange(len(c.ClusterInfo.Monitors), targetCount)
+	reportReconcileProgress(progressPhaseMonCount, start)
 
 	// create the mons for a new cluster or ensure mons are running in an existing cluster
-	return c.ClusterInfo, c.startMons(targetCount)
+	if err := c.startMons(targetCount); err != nil {
+		return c.ClusterInfo, err
+	}
+	reportReconcileProgress(progressPhaseMons, start)
+
+	if err := c.migrateMonDataDirs(); err != nil {
+		return c.ClusterInfo, err
+	}
+	reportReconcileProgress(progressPhaseDataDirMigration, start)
+	return c.ClusterInfo, nil
+}
+
+// validateSpec checks that the mon settings in the cluster spec can be applied
+func (c *Cluster) validateSpec() error {
+	// Validate pod's memory if specified
+	err := opspec.CheckPodMemory(cephv1.GetMonResources(c.spec.Resources), cephMonPodMinimumMemory)
+	if err != nil {
+		return fmt.Errorf("%v", err)
+	}
+
+	if c.spec.Mon.CPUSet != "" {
+		if _, err := cpuSetSize(c.spec.Mon.CPUSet); err != nil {
+			return fmt.Errorf("invalid mon cpuset. %+v", err)
+		}
+	}
+
+	if c.spec.Mon.MaxMonChangeRate < 0 {
+		return fmt.Errorf("invalid max mon change rate %d. the rate must be at least 1", c.spec.Mon.MaxMonChangeRate)
+	}
+
+	if c.spec.Mon.FailoverHeadroomNodes < 0 {
+		return fmt.Errorf("invalid mon failover headroom of %d nodes", c.spec.Mon.FailoverHeadroomNodes)
+	}
+	if c.spec.Mon.FailoverHeadroomPercent < 0 || c.spec.Mon.FailoverHeadroomPercent > 100 {
+		return fmt.Errorf("invalid mon failover headroom of %d percent. the percentage must be between 0 and 100", c.spec.Mon.FailoverHeadroomPercent)
+	}
+
+	if c.spec.Mon.FailoverTimeoutMinutes < 0 {
+		return fmt.Errorf("invalid mon failover timeout of %d minutes. the timeout must be at least 1 minute", c.spec.Mon.FailoverTimeoutMinutes)
+	}
+
+	if c.spec.Mon.QuorumLivenessTimeoutMinutes < 0 {
+		return fmt.Errorf("invalid mon quorum liveness timeout of %d minutes. the timeout must be at least 1 minute", c.spec.Mon.QuorumLivenessTimeoutMinutes)
+	}
+
+	if err := validateSeedMonHosts(c.spec.Mon.SeedMonHosts); err != nil {
+		return err
+	}
+
+	if err := validateMonDebugSections(c.spec.Mon.DebugSections); err != nil {
+		return err
+	}
+
+	if err := validateRocksDBCacheSize(c.spec.Mon.RocksDBCacheSizeMB, cephv1.GetMonResources(c.spec.Resources)); err != nil {
+		return err
+	}
+
+	if err := validateMonProbeTimeout(c.spec.Mon.ProbeTimeoutMs); err != nil {
+		return err
+	}
+
+	if err := validateMaxPGPerOSD(c.spec.MaxPGPerOSD); err != nil {
+		return err
+	}
+
+	if err := c.validatePodSecurityStandard(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateMonCount checks that the mon count of the spec gives the mons a quorum that tolerates
+// failures. An even count is rejected unless the spec allows it, since it tolerates no more failed
+// mons than the odd count below it. The cluster is allowed to keep the even count it already runs
+// so that a restart of the operator is not blocked.
+func (c *Cluster) validateMonCount() error {
+	count := c.spec.Mon.Count
+	if count <= 0 {
+		return fmt.Errorf("invalid mon count %d. at least one mon is required", count)
+	}
+	if count%2 == 1 || c.spec.Mon.AllowEvenCount {
+		return nil
+	}
+
+	currentCount := 0
+	if c.ClusterInfo != nil {
+		currentCount = len(c.ClusterInfo.Monitors)
+	}
+	if currentCount == count {
+		logger.Warningf("mon count %d is even and tolerates no more failed mons than %d mons. keeping the existing mons", count, count-1)
+		return nil
+	}
+	return fmt.Errorf("refusing to change the mon count from %d to the even count %d, which tolerates no more failed mons than %d mons. set allowEvenCount to use an even count", currentCount, count, count-1)
 }
 
 func (c *Cluster) startMons(targetCount int) error {
@@ -204,9 +369,18 @@ func (c *Cluster) startMons(targetCount int) error {
 	existingCount, mons := c.initMonConfig(targetCount)
 
 	// Assign the mons to nodes
+	start := time.Now()
 	if err := c.assignMons(mons); err != nil {
 		return fmt.Errorf("failed to assign pods to mons. %+v", err)
 	}
+	if err := c.validateMonPorts(mons); err != nil {
+		return fmt.Errorf("invalid mon port assignment. %+v", err)
+	}
+	c.observeReconcilePhase(reconcilePhaseScheduling, start)
+
+	if err := c.backupBeforeUpgrade(); err != nil {
+		return fmt.Errorf("failed to back up the mon store before the upgrade. %+v", err)
+	}
 
 	if existingCount < len(mons) {
 		// Start the new mons one at a time
@@ -243,7 +417,34 @@ func (c *Cluster) startMons(targetCount int) error {
 		}
 	}
 
-	logger.Debugf("mon endpoints used are: %s", FlattenMonEndpoints(c.ClusterInfo.Monitors))
+	// the config set commands would only time out if none of the mons can answer them
+	if _, err := validateAdminSockets(context.Background(), c); err != nil {
+		logger.Warningf("not applying the mon config options. %+v", err)
+	} else if err := c.applyMonConfigOptions(); err != nil {
+		return err
+	}
+
+	logger.Debugf("mon endpoints used are: %s", FlattenMonEndpoints(c.ClusterInfo.Monitors, c.ClusterInfo.CephVersion))
+	return nil
+}
+
+// applyMonConfigOptions sets the options of the cluster spec in the centralized mon config
+func (c *Cluster) applyMonConfigOptions() error {
+	if err := c.applyMaxPGPerOSD(); err != nil {
+		return fmt.Errorf("failed to apply the max pg per osd. %+v", err)
+	}
+
+	if err := c.applyMonDebugSections(); err != nil {
+		return fmt.Errorf("failed to apply the mon debug sections. %+v", err)
+	}
+
+	if err := c.applyRocksDBCacheSize(); err != nil {
+		return fmt.Errorf("failed to apply the mon rocksdb cache size. %+v", err)
+	}
+
+	if err := c.applyMonProbeTimeout(); err != nil {
+		return fmt.Errorf("failed to apply the mon probe timeout. %+v", err)
+	}
 	return nil
 }
 
@@ -273,7 +474,8 @@ func (c *Cluster) ensureMonsRunning(mons []*monConfig, i, targetCount int, requi
 	}
 
 	// save the mon config after we have "initiated the IPs"
-	if err := c.saveMonConfig(); err != nil {
+	start := time.Now()
+	if _, err := c.saveMonConfig(); err != nil {
 		return fmt.Errorf("failed to save mons. %+v", err)
 	}
 
@@ -281,6 +483,12 @@ func (c *Cluster) ensureMonsRunning(mons []*monConfig, i, targetCount int, requi
 	if err := WriteConnectionConfig(c.context, c.ClusterInfo); err != nil {
 		return err
 	}
+	c.observeReconcilePhase(reconcilePhasePersistence, start)
+
+	// the other mons can only join after the bootstrap mon is running
+	if err := c.startBootstrapMon(mons[0:expectedMonCount]); err != nil {
+		return err
+	}
 
 	// Start the deployment
 	if err := c.startDeployments(mons[0:expectedMonCount], requireAllInQuorum); err != nil {
@@ -296,15 +504,21 @@ func (c *Cluster) initClusterInfo(cephVersion cephver.CephVersion) error {
 	var err error
 
 	// get the cluster info from secret
-	c.ClusterInfo, c.maxMonID, c.mapping, err = CreateOrLoadClusterInfo(c.context, c.Namespace, &c.ownerRef)
+	var clusterInfo *cephconfig.ClusterInfo
+	clusterInfo, c.maxMonID, c.mapping, err = CreateOrLoadClusterInfo(c.context, c.Namespace, &c.ownerRef)
+	c.setClusterInfo(clusterInfo)
 	c.ClusterInfo.CephVersion = cephVersion
 
 	if err != nil {
 		return fmt.Errorf("failed to get cluster info. %+v", err)
 	}
 
+	if err := c.loadMonKeyVersion(); err != nil {
+		return err
+	}
+
 	// save cluster monitor config
-	if err = c.saveMonConfig(); err != nil {
+	if _, err = c.saveMonConfig(); err != nil {
 		return fmt.Errorf("failed to save mons. %+v", err)
 	}
 
@@ -393,8 +607,16 @@ func (c *Cluster) initMonIPs(mons []*monConfig) error {
 // the unlabled nodes are actually in the set of labeled zones, and at best,
 // they are in distinct zones.
 func scheduleMonitor(mon *monConfig, nodeZones [][]NodeUsage) *NodeUsage {
+	return scheduleMonitorOnNodes(mon, nodeZones, nil)
+}
+
+// scheduleMonitorOnNodes is scheduleMonitor restricted to the valid nodes for which usable returns
+// true. All the nodes are usable if usable is nil.
+func scheduleMonitorOnNodes(mon *monConfig, nodeZones [][]NodeUsage, usable func(*NodeUsage) bool) *NodeUsage {
 	// the node choice for this monitor
 	var nodeChoice *NodeUsage
+	// the node choice for this monitor from the zones without monitors
+	var emptyZoneNodeChoice *NodeUsage
 
 	// for each zone, in order of preference. unlabeled nodes are last, by
 	// construction; see Cluster.getNodeMonusage().
@@ -417,10 +639,13 @@ func scheduleMonitor(mon *monConfig, nodeZones [][]NodeUsage) *NodeUsage {
 					nodeUsage.Node.Name)
 				continue
 			}
+			if usable != nil && !usable(nodeUsage) {
+				continue
+			}
 
 			// make a "best" choice from this zone. in this case that is the
 			// node with the least amount of monitors.
-			if zoneNodeChoice == nil || nodeUsage.MonCount < zoneNodeChoice.MonCount {
+			if zoneNodeChoice == nil || preferNode(nodeUsage, zoneNodeChoice) {
 				logger.Infof("schedmon: considering node %s with mon count %d",
 					nodeUsage.Node.Name, nodeUsage.MonCount)
 				zoneNodeChoice = nodeUsage
@@ -432,16 +657,18 @@ func scheduleMonitor(mon *monConfig, nodeZones [][]NodeUsage) *NodeUsage {
 			if zoneMonCount == 0 {
 				// this zone has no monitors, which implies that
 				// zoneNodeChoice will be a node without any monitors
-				// currently assigned. choose this node.
-				logger.Infof("schedmon: considering node %s from empty zone",
-					zoneNodeChoice.Node.Name)
-				nodeChoice = zoneNodeChoice
-				break
+				// currently assigned. choose the node with the highest
+				// weight from the empty zones.
+				if emptyZoneNodeChoice == nil || zoneNodeChoice.Weight > emptyZoneNodeChoice.Weight {
+					logger.Infof("schedmon: considering node %s from empty zone",
+						zoneNodeChoice.Node.Name)
+					emptyZoneNodeChoice = zoneNodeChoice
+				}
 			} else {
 				// this zone already has a monitor in it. but keep the
 				// choice as a backup; we may find that all zones already
 				// have a monitor and still need to make an assignment.
-				if nodeChoice == nil || zoneNodeChoice.MonCount < nodeChoice.MonCount {
+				if nodeChoice == nil || preferNode(zoneNodeChoice, nodeChoice) {
 					logger.Infof("schedmon: considering node %s with mon count %d",
 						zoneNodeChoice.Node.Name, zoneNodeChoice.MonCount)
 					nodeChoice = zoneNodeChoice
@@ -450,6 +677,10 @@ func scheduleMonitor(mon *monConfig, nodeZones [][]NodeUsage) *NodeUsage {
 		}
 	}
 
+	if emptyZoneNodeChoice != nil {
+		nodeChoice = emptyZoneNodeChoice
+	}
+
 	if nodeChoice != nil {
 		logger.Infof("schedmon: scheduling mon %s on node %s",
 			mon.DaemonName, nodeChoice.Node.Name)
@@ -461,6 +692,222 @@ func scheduleMonitor(mon *monConfig, nodeZones [][]NodeUsage) *NodeUsage {
 	return nodeChoice
 }
 
+// scheduleMonitorWithHeadroom is scheduleMonitor, but it keeps the given number of valid nodes
+// without mons as headroom for the failover of mons. When no more empty nodes can be used without
+// cutting into the headroom, the mon is placed on a node that already has mons. Nil is returned if
+// there is no such node.
+func scheduleMonitorWithHeadroom(mon *monConfig, nodeZones [][]NodeUsage, headroom int) *NodeUsage {
+	emptyNodes := 0
+	for zi := range nodeZones {
+		for _, nodeUsage := range nodeZones[zi] {
+			if nodeUsage.MonValid && nodeUsage.MonCount == 0 {
+				emptyNodes++
+			}
+		}
+	}
+	if emptyNodes > headroom {
+		return scheduleMonitor(mon, nodeZones)
+	}
+
+	logger.Infof("schedmon: keeping %d empty nodes as failover headroom. placing mon %s on a node with mons", emptyNodes, mon.DaemonName)
+	return scheduleMonitorOnNodes(mon, nodeZones, func(nodeUsage *NodeUsage) bool {
+		return nodeUsage.MonCount > 0
+	})
+}
+
+// failoverHeadroom returns the number of valid nodes to keep without mons for failovers: the larger
+// of the headroom node count and the headroom percentage of the valid nodes, rounded up
+func (c *Cluster) failoverHeadroom(nodeZones [][]NodeUsage) int {
+	validNodes := 0
+	for zi := range nodeZones {
+		for _, nodeUsage := range nodeZones[zi] {
+			if nodeUsage.MonValid {
+				validNodes++
+			}
+		}
+	}
+
+	headroom := c.spec.Mon.FailoverHeadroomNodes
+	if percent := c.spec.Mon.FailoverHeadroomPercent; percent > 0 {
+		if fromPercent := (validNodes*percent + 99) / 100; fromPercent > headroom {
+			headroom = fromPercent
+		}
+	}
+	return headroom
+}
+
+// preferNode returns whether the node is a better choice for a new mon than the other node: the node
+// with fewer mons, or the node with the higher weight when both have the same number of mons
+func preferNode(node, other *NodeUsage) bool {
+	if node.MonCount != other.MonCount {
+		return node.MonCount < other.MonCount
+	}
+	return node.Weight > other.Weight
+}
+
+// SchedulingRelaxation is a mon placement constraint that can be dropped when no node satisfies
+// all the constraints
+type SchedulingRelaxation string
+
+const (
+	// RelaxAntiAffinity allows the mon in a zone that already has a mon
+	RelaxAntiAffinity SchedulingRelaxation = "anti-affinity"
+	// RelaxMultiplePerNode allows the mon on a node that already has a mon
+	RelaxMultiplePerNode SchedulingRelaxation = "multiple-per-node"
+	// RelaxZone allows the mon outside of its preferred zone
+	RelaxZone SchedulingRelaxation = "zone"
+)
+
+// schedulingConstraints are the constraints a node must satisfy to be chosen for a mon
+type schedulingConstraints struct {
+	// only choose a node in a zone without mons
+	spreadZones bool
+	// allow choosing a node that already has a mon
+	allowMultiplePerNode bool
+	// only choose a node in this zone if not empty
+	zone string
+}
+
+func (s *schedulingConstraints) relax(relaxation SchedulingRelaxation) {
+	switch relaxation {
+	case RelaxAntiAffinity:
+		s.spreadZones = false
+	case RelaxMultiplePerNode:
+		s.allowMultiplePerNode = true
+	case RelaxZone:
+		s.zone = ""
+	}
+}
+
+// scheduleMonitorWithRetry finds a node for the mon with the strictest constraints first: a node
+// without mons in a zone without mons, in the preferred zone of the mon if it has one. If no node
+// satisfies the constraints, the relaxation steps are applied in order until a node is found. Nil is
+// returned if no node is found after all the steps.
+func scheduleMonitorWithRetry(mon *monConfig, nodeZones [][]NodeUsage, relaxationSteps []SchedulingRelaxation) *NodeUsage {
+	constraints := schedulingConstraints{spreadZones: true, zone: mon.PreferredZone}
+	if nodeChoice := scheduleMonitorWithConstraints(mon, nodeZones, constraints); nodeChoice != nil {
+		return nodeChoice
+	}
+
+	for _, relaxation := range relaxationSteps {
+		logger.Infof("schedmon: no suitable node found for mon %s. retrying without the %s constraint", mon.DaemonName, relaxation)
+		constraints.relax(relaxation)
+		if nodeChoice := scheduleMonitorWithConstraints(mon, nodeZones, constraints); nodeChoice != nil {
+			return nodeChoice
+		}
+	}
+
+	logger.Infof("schedmon: no suitable node found for mon %s after relaxing constraints %v", mon.DaemonName, relaxationSteps)
+	return nil
+}
+
+// scheduleMonitorWithConstraints chooses the valid node with the fewest mons that satisfies the
+// constraints, preferring nodes in zones without mons. Like scheduleMonitor, the unlabeled nodes are
+// considered last and are each treated as their own zone.
+func scheduleMonitorWithConstraints(mon *monConfig, nodeZones [][]NodeUsage, constraints schedulingConstraints) *NodeUsage {
+	var nodeChoice, emptyZoneNodeChoice *NodeUsage
+	for zi := range nodeZones {
+		zoneMonCount := 0
+		labeledZone := false
+		var zoneNodeChoice *NodeUsage
+		for ni := range nodeZones[zi] {
+			nodeUsage := &nodeZones[zi][ni]
+			zoneMonCount += nodeUsage.MonCount
+			zone := nodeUsage.Node.Labels[zoneLabel]
+			labeledZone = zone != ""
+			if !nodeUsage.MonValid {
+				continue
+			}
+			if constraints.zone != "" && zone != constraints.zone {
+				continue
+			}
+			if nodeUsage.MonCount > 0 && !constraints.allowMultiplePerNode {
+				continue
+			}
+			if zoneNodeChoice == nil || preferNode(nodeUsage, zoneNodeChoice) {
+				zoneNodeChoice = nodeUsage
+			}
+		}
+
+		if zoneNodeChoice == nil {
+			continue
+		}
+		emptyZone := zoneMonCount == 0 || !labeledZone
+		if constraints.spreadZones && !emptyZone {
+			continue
+		}
+		if emptyZone && zoneNodeChoice.MonCount == 0 {
+			// no better choice than an empty node in an empty zone, except for one with a higher weight
+			if emptyZoneNodeChoice == nil || zoneNodeChoice.Weight > emptyZoneNodeChoice.Weight {
+				emptyZoneNodeChoice = zoneNodeChoice
+			}
+			continue
+		}
+		if nodeChoice == nil || preferNode(zoneNodeChoice, nodeChoice) {
+			nodeChoice = zoneNodeChoice
+		}
+	}
+	if emptyZoneNodeChoice != nil {
+		nodeChoice = emptyZoneNodeChoice
+	}
+
+	if nodeChoice != nil {
+		logger.Infof("schedmon: scheduling mon %s on node %s", mon.DaemonName, nodeChoice.Node.Name)
+	}
+	return nodeChoice
+}
+
+// scheduleMonitorWithArbiter finds a node for the mon in a stretch cluster with two data zones and
+// an arbiter zone that hosts exactly one tiebreaker mon. The mon is placed in the arbiter zone if the
+// zone has no mon yet, or if it replaces the tiebreaker mon. Otherwise the mon is placed with
+// scheduleMonitor in the data zone with the fewest mons. The mons on invalid nodes count toward the
+// mons of their zone. Nil is returned if no data zone has a valid node, even if the arbiter zone has
+// one.
+func scheduleMonitorWithArbiter(mon *monConfig, nodeZones [][]NodeUsage, arbiterZone string) *NodeUsage {
+	zoneMonCount := func(zone []NodeUsage) int {
+		count := 0
+		for _, nodeUsage := range zone {
+			count += nodeUsage.MonCount
+		}
+		return count
+	}
+
+	// the zones keep pointing to the node usages of nodeZones so that the choice can be updated
+	var arbiter []NodeUsage
+	dataZones := [][]NodeUsage{}
+	for zi := range nodeZones {
+		if len(nodeZones[zi]) > 0 && nodeZones[zi][0].Node.Labels[zoneLabel] == arbiterZone {
+			arbiter = nodeZones[zi]
+		} else {
+			dataZones = append(dataZones, nodeZones[zi])
+		}
+	}
+
+	if arbiter != nil {
+		arbiterMonCount := zoneMonCount(arbiter)
+		// the tiebreaker mon that is failed over is still counted until it is removed
+		replacingTiebreaker := mon.PreferredZone == arbiterZone && arbiterMonCount == 1
+		if arbiterMonCount == 0 || replacingTiebreaker {
+			if nodeChoice := scheduleMonitor(mon, [][]NodeUsage{arbiter}); nodeChoice != nil {
+				return nodeChoice
+			}
+		}
+	}
+
+	// balance the mons across the data zones
+	sort.SliceStable(dataZones, func(i, j int) bool {
+		return zoneMonCount(dataZones[i]) < zoneMonCount(dataZones[j])
+	})
+	for _, zone := range dataZones {
+		if nodeChoice := scheduleMonitor(mon, [][]NodeUsage{zone}); nodeChoice != nil {
+			return nodeChoice
+		}
+	}
+
+	logger.Infof("schedmon: no suitable node found for mon %s outside of the arbiter zone %s", mon.DaemonName, arbiterZone)
+	return nil
+}
+
 func (c *Cluster) assignMons(mons []*monConfig) error {
 
 	// retrieve the set of cluster nodes and their monitor usage info
@@ -472,6 +919,7 @@ func (c *Cluster) assignMons(mons []*monConfig) error {
 	// ensure all monitors have a node assignment. note that this isn't
 	// necessarily optimal: it does not try to move existing monitors which is
 	// handled by the periodic monitor health checks.
+	assigned := []*monConfig{}
 	for _, mon := range mons {
 
 		// monitor is already assigned to a node. nothing to do
@@ -480,17 +928,25 @@ func (c *Cluster) assignMons(mons []*monConfig) error {
 			continue
 		}
 
-		nodeChoice := scheduleMonitor(mon, nodeZones)
-
+		var nodeChoice *NodeUsage
+		if c.spec.Mon.ArbiterZone != "" {
+			nodeChoice = scheduleMonitorWithArbiter(mon, nodeZones, c.spec.Mon.ArbiterZone)
+		} else {
+			// the headroom can only be kept by placing several mons on a node, and the mons replacing
+			// failed mons are free to use it
+			if headroom := c.failoverHeadroom(nodeZones); headroom > 0 && c.spec.Mon.AllowMultiplePerNode && !mon.Replacement {
+				nodeChoice = scheduleMonitorWithHeadroom(mon, nodeZones, headroom)
+			}
+			if nodeChoice == nil {
+				nodeChoice = scheduleMonitorWithRetry(mon, nodeZones, c.schedulingRelaxations())
+			}
+		}
 		if nodeChoice == nil {
 			return fmt.Errorf("assignmon: no valid nodes available for mon placement")
 		}
 
-		// note that we do not need to worry about a false negative here (i.e. a
-		// node exists that _would_ pass this check) due to the search landing in a
-		// local minima because if a node had existed with a mon count of zero,
-		// scheduleMonitor would have chosen it over any node with a positive
-		// mon count.
+		// scheduleMonitorWithRetry only chooses a node that already has a mon
+		// when multiple mons per node are allowed, so this is only a safety net.
 		if nodeChoice.MonCount > 0 && !c.spec.Mon.AllowMultiplePerNode {
 			return fmt.Errorf("assignmon: no empty nodes available for mon placement")
 		}
@@ -507,12 +963,128 @@ func (c *Cluster) assignMons(mons []*monConfig) error {
 		}
 
 		c.mapping.Node[mon.DaemonName] = nodeInfo
+		assigned = append(assigned, mon)
+
+		// with host networking the mon listens directly on the node, so each mon on a node
+		// needs its own port
+		if c.HostNetwork {
+			mon.Port = c.allocateMonPort(nodeInfo.Name)
+			logger.Infof("assignmon: mon %s will listen on port %d on node %s", mon.DaemonName, mon.Port, nodeInfo.Name)
+		}
+	}
+
+	// safety net in case the scheduling above placed a mon on a node that already has a mon
+	if err := c.validateMonNodes(assigned); err != nil {
+		return fmt.Errorf("invalid mon node assignment. %+v", err)
 	}
 
 	logger.Debug("assignmons: mons have been assigned to nodes")
 	return nil
 }
 
+// schedulingRelaxations returns the constraints to drop in order when a mon cannot be scheduled.
+// Multiple mons are only put on the same node if the cluster allows it.
+func (c *Cluster) schedulingRelaxations() []SchedulingRelaxation {
+	relaxations := []SchedulingRelaxation{RelaxAntiAffinity}
+	if c.spec.Mon.AllowMultiplePerNode {
+		relaxations = append(relaxations, RelaxMultiplePerNode)
+	}
+	return append(relaxations, RelaxZone)
+}
+
+// validateMonNodes returns an error if any of the mons is assigned to the same node as another mon
+// when multiple mons per node are not allowed
+func (c *Cluster) validateMonNodes(mons []*monConfig) error {
+	if c.spec.Mon.AllowMultiplePerNode {
+		return nil
+	}
+
+	names := []string{}
+	for name := range c.mapping.Node {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, m := range mons {
+		node, ok := c.mapping.Node[m.DaemonName]
+		if !ok {
+			continue
+		}
+		for _, other := range names {
+			if other != m.DaemonName && c.mapping.Node[other].Name == node.Name {
+				return fmt.Errorf("mons %s and %s are both assigned to node %s but multiple mons per node are not allowed", other, m.DaemonName, node.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// allocateMonPort returns the port for a new mon on the given node when host networking is
+// enabled. The mapping keeps the highest port handed out on each node, so the first mon on a node
+// gets the default port and each additional mon gets the next port up.
+func (c *Cluster) allocateMonPort(nodeName string) int32 {
+	port, ok := c.mapping.Port[nodeName]
+	if !ok {
+		port = DefaultMsgr1Port
+	} else {
+		port++
+	}
+	c.mapping.Port[nodeName] = port
+	return port
+}
+
+// monMsgr2Port returns the msgr2 port of a mon listening on the given msgr1 port. A mon on a
+// non-default port with host networking must not bind the default msgr2 port of another mon on the
+// node, so its msgr2 port is offset from the default msgr2 port as far as its msgr1 port is from
+// the default msgr1 port.
+func monMsgr2Port(msgr1Port int32) int32 {
+	return DefaultMsgr2Port + msgr1Port - DefaultMsgr1Port
+}
+
+// validateMonPorts returns an error if two mons assigned to the same node would listen on the same
+// port with host networking. The existing mons are checked along with the mons to be created. On
+// Nautilus and newer the msgr2 ports of the mons are checked as well.
+func (c *Cluster) validateMonPorts(mons []*monConfig) error {
+	if !c.HostNetwork {
+		return nil
+	}
+
+	ports := map[string]int32{}
+	for name, m := range c.ClusterInfo.Monitors {
+		ports[name] = cephutil.GetPortFromEndpoint(m.Endpoint)
+	}
+	for _, m := range mons {
+		ports[m.DaemonName] = m.Port
+	}
+
+	names := []string{}
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	used := map[string]string{}
+	for _, name := range names {
+		node, ok := c.mapping.Node[name]
+		if !ok {
+			continue
+		}
+		monPorts := []int32{ports[name]}
+		if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
+			monPorts = append(monPorts, monMsgr2Port(ports[name]))
+		}
+		for _, port := range monPorts {
+			nodePort := fmt.Sprintf("%s:%d", node.Name, port)
+			if other, ok := used[nodePort]; ok {
+				return fmt.Errorf("mons %s and %s are both assigned to port %d on node %s", other, name, port, node.Name)
+			}
+			used[nodePort] = name
+		}
+	}
+
+	return nil
+}
+
 func (c *Cluster) startDeployments(mons []*monConfig, requireAllInQuorum bool) error {
 	if len(mons) == 0 {
 		return fmt.Errorf("cannot start 0 mons")
@@ -521,10 +1093,12 @@ func (c *Cluster) startDeployments(mons []*monConfig, requireAllInQuorum bool) e
 	// Ensure each of the mons have been created. If already created, it will be a no-op.
 	for i := 0; i < len(mons); i++ {
 		node, _ := c.mapping.Node[mons[i].DaemonName]
+		start := time.Now()
 		err := c.startMon(mons[i], node.Hostname)
 		if err != nil {
 			return fmt.Errorf("failed to create mon %s. %+v", mons[i].DaemonName, err)
 		}
+		c.observeReconcilePhase(reconcilePhaseCreation, start)
 		// For the initial deployment (first creation) it's expected to not have all the monitors in quorum
 		// However, in an event of an update, it's crucial to proceed monitors by monitors
 		// At the end of the method we perform one last check where all the monitors must be in quorum
@@ -554,6 +1128,7 @@ func (c *Cluster) waitForMonsToJoin(mons []*monConfig, requireAllInQuorum bool)
 	if !c.waitForStart {
 		return nil
 	}
+	defer c.observeReconcilePhase(reconcilePhaseQuorumWait, time.Now())
 
 	starting := []string{}
 	for _, m := range mons {
@@ -567,10 +1142,18 @@ func (c *Cluster) waitForMonsToJoin(mons []*monConfig, requireAllInQuorum bool)
 		return fmt.Errorf("failed to wait for mon quorum. %+v", err)
 	}
 
+	// the new mons may not be known yet by all the mons
+	if err := waitForMonGossipConsistency(context.Background(), c); err != nil {
+		logger.Warningf("%+v", err)
+	}
+
 	return nil
 }
 
-func (c *Cluster) saveMonConfig() error {
+// saveMonConfig saves the mon endpoints and mappings to the endpoint config map and writes the
+// configs that depend on them. The change of the endpoints since the config map was last saved is
+// returned.
+func (c *Cluster) saveMonConfig() (*MonEndpointsDiff, error) {
 	configMap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      EndpointConfigMapName,
@@ -581,34 +1164,74 @@ func (c *Cluster) saveMonConfig() error {
 
 	monMapping, err := json.Marshal(c.mapping)
 	if err != nil {
-		return fmt.Errorf("failed to marshal mon mapping. %+v", err)
+		return nil, fmt.Errorf("failed to marshal mon mapping. %+v", err)
 	}
 
 	csiConfigValue, err := csi.FormatCsiClusterConfig(
 		c.Namespace, c.ClusterInfo.Monitors)
 	if err != nil {
-		return fmt.Errorf("failed to format csi config: %+v", err)
+		return nil, fmt.Errorf("failed to format csi config: %+v", err)
 	}
 
+	// the endpoints saved previously are compared with the current endpoints
+	previous := map[string]*cephconfig.MonInfo{}
+	existing, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	if err == nil {
+		previous = ParseMonEndpoints(existing.Data[EndpointDataKey])
+	} else if errors.IsNotFound(err) {
+		existing = nil
+	} else {
+		return nil, fmt.Errorf("failed to get mon endpoint config map. %+v", err)
+	}
+	if err := c.backupMonConfig(existing); err != nil {
+		logger.Warningf("failed to back up the mon config. %+v", err)
+	}
+	diff := diffMonEndpoints(previous, c.ClusterInfo.Monitors)
+
+	// clients try the healthiest mons first unless the endpoints are shuffled
+	endpoints := sortMonEndpointsByScore(c.ClusterInfo.Monitors, c.monScores, c.ClusterInfo.CephVersion)
+	seed := int64(-1)
+	if c.spec.Mon.ShuffleEndpoints {
+		// bump the seed on every save so that clients see a new endpoint order each time. The seed is
+		// kept in the data since the metadata generation of a configmap is not set by the api server.
+		seed = 0
+		if existing != nil {
+			if previousSeed, err := strconv.ParseInt(existing.Data[ShuffleSeedKey], 10, 64); err == nil {
+				seed = previousSeed + 1
+			}
+		}
+		endpoints = shuffleMonEndpoints(c.ClusterInfo.Monitors, c.ClusterInfo.CephVersion, seed)
+	}
+	endpoints = orderMonEndpointsByIPVersion(endpoints, c.spec.Network.PreferIPv6)
+
 	configMap.Data = map[string]string{
-		EndpointDataKey: FlattenMonEndpoints(c.ClusterInfo.Monitors),
+		EndpointDataKey: legacyMonEndpoints(endpoints),
 		MaxMonIDKey:     strconv.Itoa(c.maxMonID),
 		MappingKey:      string(monMapping),
 		csi.ConfigKey:   csiConfigValue,
 	}
+	if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
+		configMap.Data[EndpointAddrVecDataKey] = endpoints
+	}
+	if seed >= 0 {
+		configMap.Data[ShuffleSeedKey] = strconv.FormatInt(seed, 10)
+	}
 
 	if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(configMap); err != nil {
 		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("failed to create mon endpoint config map. %+v", err)
+			return nil, fmt.Errorf("failed to create mon endpoint config map. %+v", err)
 		}
 
 		logger.Debugf("updating config map %s that already exists", configMap.Name)
 		if _, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Update(configMap); err != nil {
-			return fmt.Errorf("failed to update mon endpoint config map. %+v", err)
+			return nil, fmt.Errorf("failed to update mon endpoint config map. %+v", err)
 		}
 	}
 
 	logger.Infof("saved mon endpoints to config map %+v", configMap.Data)
+	if !diff.Empty() {
+		logger.Infof("mon endpoints changed. added: %v, removed: %v, changed: %v", diff.Added, diff.Removed, diff.Changed)
+	}
 
 	// Every time the mon config is updated, must also update the global config so that all daemons
 	// have the most updated version if they restart.
@@ -616,14 +1239,14 @@ func (c *Cluster) saveMonConfig() error {
 
 	// write the latest config to the config dir
 	if err := WriteConnectionConfig(c.context, c.ClusterInfo); err != nil {
-		return fmt.Errorf("failed to write connection config for new mons. %+v", err)
+		return nil, fmt.Errorf("failed to write connection config for new mons. %+v", err)
 	}
 
 	if err := csi.SaveClusterConfig(c.context.Clientset, c.Namespace, c.ClusterInfo, c.csiConfigMutex); err != nil {
-		return fmt.Errorf("failed to update csi cluster config: %+v", err)
+		return nil, fmt.Errorf("failed to update csi cluster config: %+v", err)
 	}
 
-	return nil
+	return diff, nil
 }
 
 var updateDeploymentAndWait = UpdateCephDeploymentAndWait
@@ -644,7 +1267,16 @@ func (c *Cluster) updateMon(m *monConfig, d *apps.Deployment) error {
 		cephVersionToUse = currentCephVersion
 	}
 
+	previousImage := ""
+	if existing, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(d.Name, metav1.GetOptions{}); err == nil {
+		previousImage = monDeploymentImage(existing)
+	}
+
 	err = updateDeploymentAndWait(c.context, d, c.Namespace, daemonType, m.DaemonName, cephVersionToUse)
+	// a new image that breaks the quorum is rolled back before the other mons are updated
+	if rollbackErr := c.rollbackMonUpdateOnQuorumLoss(m.DaemonName, previousImage, monDeploymentImage(d), currentCephVersion); rollbackErr != nil {
+		return rollbackErr
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update mon deployment %s. %+v", m.ResourceName, err)
 	}
@@ -659,6 +1291,12 @@ func (c *Cluster) startMon(m *monConfig, hostname string) error {
 	pvcExists := false
 	deploymentExists := false
 	d := c.makeDeployment(m, hostname)
+	// keep the tolerations of the taints the mon node got after the mon was placed on it
+	if info, ok := c.mapping.Node[m.DaemonName]; ok && info != nil {
+		if node, err := c.context.Clientset.CoreV1().Nodes().Get(info.Name, metav1.GetOptions{}); err == nil {
+			d.Spec.Template.Spec.Tolerations = c.monTolerations(node)
+		}
+	}
 	existingDeployment, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(d.Name, metav1.GetOptions{})
 	if err == nil {
 		deploymentExists = true
@@ -674,24 +1312,62 @@ func (c *Cluster) startMon(m *monConfig, hostname string) error {
 	// we add in PVC or HostPath storage based on an existing deployment OR on
 	// the current state of the CRD.
 	if pvcExists || (!deploymentExists && c.spec.Mon.VolumeClaimTemplate != nil) {
+		// the pvc is named after the mon so that the same claim is attached after an operator restart.
+		// an existing mon keeps its claim, which differs if the mon store was moved to another pvc.
 		pvcName := m.ResourceName
+		if pvcExists {
+			if volume, ok := monDataVolume(existingDeployment); ok && volume.PersistentVolumeClaim != nil {
+				pvcName = volume.PersistentVolumeClaim.ClaimName
+			}
+		}
 		d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, opspec.DaemonVolumesDataPVC(pvcName))
 		opspec.AddVolumeMountSubPath(&d.Spec.Template.Spec, "ceph-daemon-data")
 		logger.Debugf("adding pvc volume source %s to mon deployment %s", pvcName, d.Name)
+
+		// the mon data follows the pod to any node, so the mon is only pinned to its node when the mon
+		// endpoint is the node ip
+		if !c.HostNetwork {
+			delete(d.Spec.Template.Spec.NodeSelector, v1.LabelHostname)
+			if len(d.Spec.Template.Spec.NodeSelector) == 0 {
+				d.Spec.Template.Spec.NodeSelector = nil
+			}
+		}
 	} else {
+		if c.spec.Mon.PodSecurityStandard == PodSecurityStandardRestricted {
+			return fmt.Errorf("mon %s stores its data on the host, which the %q pod security standard does not allow. move the mon store to a pvc first", m.DaemonName, PodSecurityStandardRestricted)
+		}
 		d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, opspec.DaemonVolumesDataHostPath(m.DataPathMap)...)
+		// an existing mon keeps its store dir, which differs if the store was moved to another dir
+		if deploymentExists {
+			if volume, ok := monDataVolume(existingDeployment); ok && volume.HostPath != nil {
+				useMonDataHostPath(&d.Spec.Template.Spec, volume.HostPath.Path)
+			}
+		}
 		logger.Debugf("adding host path volume source to mon deployment %s", d.Name)
 	}
 
 	if deploymentExists {
+		keepRejectedMonImage(existingDeployment, d)
+		if monConfigHashMatches(existingDeployment, d) {
+			logger.Debugf("mon deployment %s is up to date", d.Name)
+			return nil
+		}
 		return c.updateMon(m, d)
 	}
 
+	// a new mon may not be able to discover the existing mons on an isolated network
+	c.addSeedMonHosts(&d.Spec.Template.Spec)
+
 	if c.spec.Mon.VolumeClaimTemplate != nil {
 		pvc, err := c.makeDeploymentPVC(m)
 		if err != nil {
 			return fmt.Errorf("failed to make mon %s pvc. %+v", d.Name, err)
 		}
+		if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+			if err := c.validateMonStorageClass(context.Background(), *pvc.Spec.StorageClassName); err != nil {
+				return fmt.Errorf("cannot create mon %s pvc. %+v", d.Name, err)
+			}
+		}
 		_, err = c.context.Clientset.CoreV1().PersistentVolumeClaims(c.Namespace).Create(pvc)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
@@ -711,16 +1387,65 @@ func (c *Cluster) startMon(m *monConfig, hostname string) error {
 	return nil
 }
 
+// QuorumTimeoutError is returned when the mons do not reach quorum before the retries are exhausted
+type QuorumTimeoutError struct {
+	// ExpectedMons are the mons that were expected in quorum
+	ExpectedMons []string
+	// ObservedMons are the mons in quorum in the last mon status, if any mon status was received
+	ObservedMons []string
+	// MissingMons are the expected mons that were not observed in quorum
+	MissingMons []string
+	// Attempts is the number of times the quorum was checked
+	Attempts int
+	// RequireAllInQuorum is whether all the expected mons were required in quorum. Otherwise any
+	// mon status from the quorum was enough.
+	RequireAllInQuorum bool
+}
+
+func (e *QuorumTimeoutError) Error() string {
+	if !e.RequireAllInQuorum {
+		return fmt.Sprintf("exceeded max retry count waiting for monitors to reach quorum. no quorum of the mons %v was found after %d attempts",
+			e.ExpectedMons, e.Attempts)
+	}
+	return fmt.Sprintf("exceeded max retry count waiting for monitors to reach quorum. mons %v did not join the quorum %v after %d attempts",
+		e.MissingMons, e.ObservedMons, e.Attempts)
+}
+
+// newQuorumTimeoutError diffs the expected mons against the quorum of the last mon status
+func newQuorumTimeoutError(mons []string, lastStatus *client.MonStatusResponse, attempts int, requireAllInQuorum bool) *QuorumTimeoutError {
+	e := &QuorumTimeoutError{
+		ExpectedMons:       mons,
+		ObservedMons:       []string{},
+		MissingMons:        []string{},
+		Attempts:           attempts,
+		RequireAllInQuorum: requireAllInQuorum,
+	}
+	if lastStatus != nil {
+		for _, m := range lastStatus.MonMap.Mons {
+			if monFoundInQuorum(m.Name, *lastStatus) {
+				e.ObservedMons = append(e.ObservedMons, m.Name)
+			}
+		}
+	}
+	for _, name := range mons {
+		if lastStatus == nil || !monFoundInQuorum(name, *lastStatus) {
+			e.MissingMons = append(e.MissingMons, name)
+		}
+	}
+	return e
+}
+
 func waitForQuorumWithMons(context *clusterd.Context, clusterName string, mons []string, sleepTime int, requireAllInQuorum bool) error {
 	logger.Infof("waiting for mon quorum with %v", mons)
 
 	// wait for monitors to establish quorum
 	retryCount := 0
 	retryMax := 30
+	var lastStatus *client.MonStatusResponse
 	for {
 		retryCount++
 		if retryCount > retryMax {
-			return fmt.Errorf("exceeded max retry count waiting for monitors to reach quorum")
+			return newQuorumTimeoutError(mons, lastStatus, retryMax, requireAllInQuorum)
 		}
 
 		if retryCount > 1 {
@@ -732,7 +1457,7 @@ func waitForQuorumWithMons(context *clusterd.Context, clusterName string, mons [
 		allPodsRunning := true
 		var runningMonNames []string
 		for _, m := range mons {
-			running, err := k8sutil.PodsRunningWithLabel(context.Clientset, clusterName, fmt.Sprintf("app=%s,mon=%s", AppName, m))
+			running, err := k8sutil.PodsRunningWithLabel(context.Clientset, clusterName, MonLabelSelector(clusterName, m).String())
 			if err != nil {
 				logger.Infof("failed to query mon pod status, trying again. %+v", err)
 				continue
@@ -757,6 +1482,7 @@ func waitForQuorumWithMons(context *clusterd.Context, clusterName string, mons [
 			logger.Debugf("failed to get mon_status, err: %+v", err)
 			continue
 		}
+		lastStatus = &monStatusResp
 
 		if !requireAllInQuorum {
 			logQuorumMembers(monStatusResp)
@@ -820,6 +1546,14 @@ func monFoundInQuorum(name string, monStatusResp client.MonStatusResponse) bool
 	return false
 }
 
+// setClusterInfo replaces the cluster info. The cluster info is also read by the mon health gate
+// without the orchestration lock.
+func (c *Cluster) setClusterInfo(clusterInfo *cephconfig.ClusterInfo) {
+	c.clusterInfoMutex.Lock()
+	defer c.clusterInfoMutex.Unlock()
+	c.ClusterInfo = clusterInfo
+}
+
 func (c *Cluster) acquireOrchestrationLock() {
 	logger.Debugf("Acquiring lock for mon orchestration")
 	c.orchestrationMutex.Lock()
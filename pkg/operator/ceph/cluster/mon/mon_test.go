@@ -38,6 +38,7 @@ import (
 	cephtest "github.com/rook/rook/pkg/daemon/ceph/test"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	"github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/util"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
@@ -120,6 +121,14 @@ func newCluster(context *clusterd.Context, namespace string, hostNetwork bool, a
 		monPodRetryInterval: 10 * time.Millisecond,
 		monPodTimeout:       1 * time.Second,
 		monTimeoutList:      map[string]time.Time{},
+		degradedMons:        map[string]string{},
+		corruptedMons:       map[string]string{},
+		displacedMons:       map[string]string{},
+		monProbeFailures:    map[string]int{},
+		monReplacements:     map[string][]time.Time{},
+		monDebugSections:    map[string]int{},
+		monScores:           map[string]*MonScore{},
+		monEvents:           map[string]map[string]*monEvent{},
 		mapping: &Mapping{
 			Node: map[string]*NodeInfo{},
 			Port: map[string]int32{},
@@ -185,6 +194,70 @@ func TestOperatorRestart(t *testing.T) {
 	validateStart(t, c)
 }
 
+func TestStartValidatesMonCount(t *testing.T) {
+	namespace := "ns"
+	startWithCount := func(count int, allowEven bool) (*Cluster, error) {
+		c := newCluster(newTestStartCluster(namespace), namespace, false, true, v1.ResourceRequirements{})
+		c.spec.Mon.Count = count
+		c.spec.Mon.AllowEvenCount = allowEven
+		_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+		return c, err
+	}
+	monDeployments := func(c *Cluster) int {
+		deployments, err := c.context.Clientset.AppsV1().Deployments(namespace).List(metav1.ListOptions{})
+		assert.NoError(t, err)
+		return len(deployments.Items)
+	}
+
+	// no mons are created for an invalid count
+	c, err := startWithCount(0, false)
+	assert.Error(t, err)
+	assert.Equal(t, 0, monDeployments(c))
+
+	c, err = startWithCount(2, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "even count 2")
+	assert.Equal(t, 0, monDeployments(c))
+
+	// an even count can be allowed
+	c, err = startWithCount(2, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, monDeployments(c))
+
+	c, err = startWithCount(3, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, monDeployments(c))
+}
+
+func TestValidateMonCount(t *testing.T) {
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+
+	c.spec.Mon.Count = 0
+	assert.Error(t, c.validateMonCount())
+	c.spec.Mon.Count = -1
+	assert.Error(t, c.validateMonCount())
+	c.spec.Mon.Count = 3
+	assert.NoError(t, c.validateMonCount())
+
+	// a new cluster can only have an even count if allowed
+	c.spec.Mon.Count = 2
+	assert.Error(t, c.validateMonCount())
+	c.spec.Mon.AllowEvenCount = true
+	assert.NoError(t, c.validateMonCount())
+	c.spec.Mon.AllowEvenCount = false
+
+	// a cluster keeps the even count it already has, but does not change to another even count
+	c.ClusterInfo = test.CreateConfigDir(4)
+	c.spec.Mon.Count = 4
+	assert.NoError(t, c.validateMonCount())
+	c.ClusterInfo = test.CreateConfigDir(3)
+	assert.Error(t, c.validateMonCount())
+	c.ClusterInfo = test.CreateConfigDir(5)
+	assert.Error(t, c.validateMonCount())
+	c.spec.Mon.Count = 3
+	assert.NoError(t, c.validateMonCount())
+}
+
 // safety check that if hostNetwork is used no changes occur on an operator restart
 func TestOperatorRestartHostNetwork(t *testing.T) {
 
@@ -213,6 +286,57 @@ func TestOperatorRestartHostNetwork(t *testing.T) {
 	validateStart(t, c)
 }
 
+func TestOperatorRestartPVC(t *testing.T) {
+
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+
+	// start a fresh cluster with the mons on pvcs
+	info, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.Nil(t, err)
+	assert.True(t, info.IsInitialized())
+	validateStart(t, c)
+	validateMonPVCs(t, c, []string{"rook-ceph-mon-a", "rook-ceph-mon-b", "rook-ceph-mon-c"})
+
+	// the existing claims are attached again after a restart of the operator
+	c = newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+	info, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.Nil(t, err)
+	assert.True(t, info.IsInitialized())
+	validateStart(t, c)
+	validateMonPVCs(t, c, []string{"rook-ceph-mon-a", "rook-ceph-mon-b", "rook-ceph-mon-c"})
+}
+
+func validateMonPVCs(t *testing.T, c *Cluster, names []string) {
+	pvcs, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(c.Namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	pvcNames := []string{}
+	for _, pvc := range pvcs.Items {
+		pvcNames = append(pvcNames, pvc.Name)
+	}
+	assert.ElementsMatch(t, names, pvcNames)
+
+	for _, name := range names {
+		d, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		claims := []string{}
+		for _, volume := range d.Spec.Template.Spec.Volumes {
+			if volume.Name == "ceph-daemon-data" {
+				assert.Nil(t, volume.HostPath)
+				assert.NotNil(t, volume.PersistentVolumeClaim)
+				claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		assert.Equal(t, []string{name}, claims)
+
+		// the mon is not pinned to a node when its data is on a pvc
+		assert.Empty(t, d.Spec.Template.Spec.NodeSelector)
+	}
+}
+
 func validateStart(t *testing.T, c *Cluster) {
 	s, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
 	assert.NoError(t, err) // there shouldn't be an error due the secret existing
@@ -235,7 +359,7 @@ func TestSaveMonEndpoints(t *testing.T) {
 	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
 
 	// create the initial config map
-	err := c.saveMonConfig()
+	_, err := c.saveMonConfig()
 	assert.Nil(t, err)
 
 	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
@@ -253,7 +377,7 @@ func TestSaveMonEndpoints(t *testing.T) {
 		Hostname: "myhost",
 	}
 	c.mapping.Port["node0"] = int32(12345)
-	err = c.saveMonConfig()
+	_, err = c.saveMonConfig()
 	assert.Nil(t, err)
 
 	cm, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
@@ -263,6 +387,183 @@ func TestSaveMonEndpoints(t *testing.T) {
 	assert.Equal(t, "2", cm.Data[MaxMonIDKey])
 }
 
+func TestSaveMonEndpointsDiff(t *testing.T) {
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: configDir}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+
+	// all the mons are added when the config map is created
+	diff, err := c.saveMonConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1.2.3.1:6789", "b": "1.2.3.2:6789", "c": "1.2.3.3:6789"}, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Empty(t, diff.Changed)
+
+	// nothing changed
+	diff, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+
+	// only the mon with the new endpoint is reported
+	c.ClusterInfo.Monitors["b"].Endpoint = "2.3.4.5:6789"
+	diff, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, map[string]string{"b": "2.3.4.5:6789"}, diff.Changed)
+
+	// a mon is replaced by a new mon
+	delete(c.ClusterInfo.Monitors, "c")
+	c.ClusterInfo.Monitors["d"] = cephconfig.NewMonInfo("d", "1.2.3.4", DefaultMsgr1Port)
+	diff, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"d": "1.2.3.4:6789"}, diff.Added)
+	assert.Equal(t, map[string]string{"c": "1.2.3.3:6789"}, diff.Removed)
+	assert.Empty(t, diff.Changed)
+}
+
+func TestSaveMonEndpointsMsgr2(t *testing.T) {
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: configDir}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 1, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+
+	// mimic only understands the legacy address
+	c.ClusterInfo.CephVersion = cephver.Mimic
+	_, err := c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "a=1.2.3.1:6789", cm.Data[EndpointDataKey])
+	assert.NotContains(t, cm.Data, EndpointAddrVecDataKey)
+
+	// nautilus gets both the msgr2 and legacy addresses in the address vector key, while the data key
+	// keeps the legacy addresses
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	_, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "a=[v2:1.2.3.1:3300,v1:1.2.3.1:6789]", cm.Data[EndpointAddrVecDataKey])
+	assert.Equal(t, "a=1.2.3.1:6789", cm.Data[EndpointDataKey])
+
+	// the endpoints are read back the same on an operator restart
+	for _, key := range []string{EndpointDataKey, EndpointAddrVecDataKey} {
+		parsed := ParseMonEndpoints(cm.Data[key])
+		assert.Equal(t, 1, len(parsed))
+		assert.Equal(t, "1.2.3.1:6789", parsed["a"].Endpoint)
+	}
+}
+
+// legacyParseMonEndpoints is how the daemons and agents of previous versions of rook parse the
+// endpoints of the mons
+func legacyParseMonEndpoints(input string) map[string]*cephconfig.MonInfo {
+	mons := map[string]*cephconfig.MonInfo{}
+	rawMons := strings.Split(input, ",")
+	for _, rawMon := range rawMons {
+		parts := strings.Split(rawMon, "=")
+		if len(parts) != 2 {
+			continue
+		}
+		mons[parts[0]] = &cephconfig.MonInfo{Name: parts[0], Endpoint: parts[1]}
+	}
+	return mons
+}
+
+func TestSaveMonEndpointsUpgrade(t *testing.T) {
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: configDir}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	c.ClusterInfo.Monitors["c"].Endpoint = "1.2.3.3:6790"
+
+	_, err := c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// the daemons that still run a previous version of rook read the same mons from the data key
+	// during an upgrade of the operator
+	expected := map[string]string{"a": "1.2.3.1:6789", "b": "1.2.3.2:6789", "c": "1.2.3.3:6790"}
+	for _, parse := range []func(string) map[string]*cephconfig.MonInfo{legacyParseMonEndpoints, ParseMonEndpoints} {
+		parsed := parse(cm.Data[EndpointDataKey])
+		assert.Equal(t, len(expected), len(parsed))
+		for name, endpoint := range expected {
+			assert.Equal(t, endpoint, parsed[name].Endpoint)
+		}
+	}
+
+	// the address vectors cannot be parsed by the previous versions
+	assert.NotEqual(t, "1.2.3.1:6789", legacyParseMonEndpoints(cm.Data[EndpointAddrVecDataKey])["a"].Endpoint)
+}
+
+func TestSaveDualStackMonEndpoints(t *testing.T) {
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: configDir}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	c.ClusterInfo.CephVersion = cephver.Mimic
+	c.ClusterInfo.Monitors["a"].Endpoint = "[fd00::1]:6789"
+	savedEndpoints := func() string {
+		_, err := c.saveMonConfig()
+		assert.Nil(t, err)
+		cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return cm.Data[EndpointDataKey]
+	}
+
+	assert.Equal(t, "b=1.2.3.2:6789,c=1.2.3.3:6789,a=[fd00::1]:6789", savedEndpoints())
+
+	c.spec.Network.PreferIPv6 = true
+	assert.Equal(t, "a=[fd00::1]:6789,b=1.2.3.2:6789,c=1.2.3.3:6789", savedEndpoints())
+
+	// the ipv6 endpoint is read back on an operator restart
+	parsed := ParseMonEndpoints(savedEndpoints())
+	assert.Equal(t, "[fd00::1]:6789", parsed["a"].Endpoint)
+}
+
+func TestSaveShuffledMonEndpoints(t *testing.T) {
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: configDir}, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	c.spec.Mon.ShuffleEndpoints = true
+
+	_, err := c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "0", cm.Data[ShuffleSeedKey])
+	assert.Equal(t, shuffleMonEndpoints(c.ClusterInfo.Monitors, c.ClusterInfo.CephVersion, 0), cm.Data[EndpointDataKey])
+
+	// the seed is bumped on every save and seeds the new order. The api server does not bump the
+	// generation of a configmap, so the seed must not depend on it.
+	_, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), cm.Generation)
+	assert.Equal(t, "1", cm.Data[ShuffleSeedKey])
+	assert.Equal(t, shuffleMonEndpoints(c.ClusterInfo.Monitors, c.ClusterInfo.CephVersion, 1), cm.Data[EndpointDataKey])
+	assert.Equal(t, 3, len(ParseMonEndpoints(cm.Data[EndpointDataKey])))
+
+	// the seed is not saved when the endpoints are not shuffled
+	c.spec.Mon.ShuffleEndpoints = false
+	_, err = c.saveMonConfig()
+	assert.Nil(t, err)
+	cm, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, ok := cm.Data[ShuffleSeedKey]
+	assert.False(t, ok)
+}
+
 func TestMonInQuorum(t *testing.T) {
 	entry := client.MonMapEntry{Name: "foo", Rank: 23}
 	quorum := []int{}
@@ -321,6 +622,39 @@ func TestWaitForQuorum(t *testing.T) {
 	expectedMons := []string{"a"}
 	err := waitForQuorumWithMons(context, namespace, expectedMons, 0, requireAllInQuorum)
 	assert.Nil(t, err)
+
+	// b never joins the quorum
+	quorumResponse = func() (string, error) {
+		return clienttest.MonInQuorumResponseFromMons(map[string]*cephconfig.MonInfo{"a": {}}), nil
+	}
+	context = newTestStartClusterWithQuorumResponse(namespace, quorumResponse)
+	for _, name := range []string{"a", "b"} {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-" + name, Namespace: namespace, Labels: MonLabels(namespace, name)},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		}
+		_, err = context.Clientset.CoreV1().Pods(namespace).Create(pod)
+		assert.Nil(t, err)
+	}
+	err = waitForQuorumWithMons(context, namespace, []string{"a", "b"}, 0, true)
+	quorumErr, ok := err.(*QuorumTimeoutError)
+	assert.True(t, ok, err)
+	assert.Equal(t, []string{"b"}, quorumErr.MissingMons)
+	assert.Equal(t, []string{"a"}, quorumErr.ObservedMons)
+	assert.Equal(t, []string{"a", "b"}, quorumErr.ExpectedMons)
+	assert.Equal(t, 30, quorumErr.Attempts)
+
+	// no mon status is ever received when only one mon is needed
+	quorumResponse = func() (string, error) {
+		return "", fmt.Errorf("test error")
+	}
+	context = newTestStartClusterWithQuorumResponse(namespace, quorumResponse)
+	err = waitForQuorumWithMons(context, namespace, []string{"a", "b"}, 0, false)
+	quorumErr, ok = err.(*QuorumTimeoutError)
+	assert.True(t, ok, err)
+	assert.Empty(t, quorumErr.ObservedMons)
+	assert.Equal(t, []string{"a", "b"}, quorumErr.MissingMons)
+	assert.Contains(t, quorumErr.Error(), "no quorum of the mons [a b] was found after 30 attempts")
 }
 
 func TestMonFoundInQuorum(t *testing.T) {
@@ -345,6 +679,106 @@ func TestMonFoundInQuorum(t *testing.T) {
 	assert.False(t, monFoundInQuorum("d", response))
 }
 
+func TestAssignMonsHostNetworkPorts(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", true, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(0)
+
+	// two mons on the only node must be given distinct ports
+	mons := []*monConfig{c.newMonConfig(0), c.newMonConfig(1)}
+	err := c.assignMons(mons)
+	assert.NoError(t, err)
+	assert.Equal(t, "node0", c.mapping.Node["a"].Name)
+	assert.Equal(t, "node0", c.mapping.Node["b"].Name)
+	assert.Equal(t, DefaultMsgr1Port, mons[0].Port)
+	assert.Equal(t, DefaultMsgr1Port+1, mons[1].Port)
+	assert.Equal(t, DefaultMsgr1Port+1, c.mapping.Port["node0"])
+	assert.NoError(t, c.validateMonPorts(mons))
+
+	// the same port on the same node is rejected
+	mons[1].Port = DefaultMsgr1Port
+	err = c.validateMonPorts(mons)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mons a and b are both assigned to port 6789 on node node0")
+
+	// an existing mon on the node is also considered
+	c.ClusterInfo.Monitors["a"] = cephconfig.NewMonInfo("a", "0.0.0.0", DefaultMsgr1Port)
+	err = c.validateMonPorts(mons[1:])
+	assert.Error(t, err)
+
+	// ports are not validated without host networking
+	c.HostNetwork = false
+	assert.NoError(t, c.validateMonPorts(mons))
+
+	// on nautilus the msgr2 port of a mon must not be used by another mon on the node
+	c.HostNetwork = true
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	delete(c.ClusterInfo.Monitors, "a")
+	mons[0].Port = DefaultMsgr1Port
+	mons[1].Port = DefaultMsgr2Port + 1
+	assert.NoError(t, c.validateMonPorts(mons))
+	mons[1].Port = DefaultMsgr2Port
+	err = c.validateMonPorts(mons)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mons a and b are both assigned to port 3300 on node node0")
+	assert.Equal(t, DefaultMsgr2Port+1, monMsgr2Port(DefaultMsgr1Port+1))
+}
+
+func TestValidateMonNodes(t *testing.T) {
+	c := newCluster(&clusterd.Context{Clientset: test.New(2)}, "ns", false, false, v1.ResourceRequirements{})
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0"}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1"}
+	mons := []*monConfig{testGenMonConfig("a"), testGenMonConfig("b")}
+	assert.NoError(t, c.validateMonNodes(mons))
+
+	// two mons on the same node
+	c.mapping.Node["b"].Name = "node0"
+	err := c.validateMonNodes(mons)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mons b and a are both assigned to node node0")
+
+	// multiple mons per node are allowed
+	c.spec.Mon.AllowMultiplePerNode = true
+	assert.NoError(t, c.validateMonNodes(mons))
+}
+
+func TestAssignMonsOnePerZone(t *testing.T) {
+	clientset := test.New(6)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(0)
+	c.spec.Mon.Count = 3
+
+	// three zones with two nodes each
+	for i := 0; i < 6; i++ {
+		node, err := clientset.CoreV1().Nodes().Get(fmt.Sprintf("node%d", i), metav1.GetOptions{})
+		assert.NoError(t, err)
+		node.Labels = map[string]string{"failure-domain.beta.kubernetes.io/zone": fmt.Sprintf("z%d", i%3)}
+		_, err = clientset.CoreV1().Nodes().Update(node)
+		assert.NoError(t, err)
+	}
+	zoneOf := func(mon string) string {
+		node, err := clientset.CoreV1().Nodes().Get(c.mapping.Node[mon].Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return node.Labels["failure-domain.beta.kubernetes.io/zone"]
+	}
+
+	// each mon is placed in its own zone
+	mons := []*monConfig{c.newMonConfig(0), c.newMonConfig(1), c.newMonConfig(2)}
+	err := c.assignMons(mons)
+	assert.NoError(t, err)
+	zones := util.CreateSet([]string{zoneOf("a"), zoneOf("b"), zoneOf("c")})
+	assert.Equal(t, 3, zones.Count())
+
+	// a mon that was assigned a node but whose pod was never created still
+	// counts toward its zone
+	delete(c.mapping.Node, "b")
+	delete(c.mapping.Node, "c")
+	err = c.assignMons(mons)
+	assert.NoError(t, err)
+	zones = util.CreateSet([]string{zoneOf("a"), zoneOf("b"), zoneOf("c")})
+	assert.Equal(t, 3, zones.Count())
+}
+
 // no node choice can be made when there are no nodes
 func TestScheduleMonitorEmpty(t *testing.T) {
 	nodeZones := [][]NodeUsage{}
@@ -509,3 +943,261 @@ func TestScheduleMonitor(t *testing.T) {
 	// choose the zone with zero mons
 	assert.Equal(t, &nodeZones[1][0], scheduleMonitor(mon, nodeZones))
 }
+
+func TestScheduleMonitorWithArbiter(t *testing.T) {
+	zoneNode := func(zone string, monCount int, valid bool) NodeUsage {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{zoneLabel: zone}}}
+		return NodeUsage{Node: node, MonCount: monCount, MonValid: valid}
+	}
+	mon := &monConfig{DaemonName: "a"}
+
+	// the empty arbiter zone gets the tiebreaker mon
+	nodeZones := [][]NodeUsage{
+		{zoneNode("dc1", 1, true), zoneNode("dc1", 0, true)},
+		{zoneNode("dc2", 1, true), zoneNode("dc2", 0, true)},
+		{zoneNode("arbiter", 0, true)},
+	}
+	assert.Equal(t, &nodeZones[2][0], scheduleMonitorWithArbiter(mon, nodeZones, "arbiter"))
+
+	// the arbiter zone is preferred even before the empty data zones
+	nodeZones = [][]NodeUsage{
+		{zoneNode("dc1", 0, true)},
+		{zoneNode("dc2", 0, true)},
+		{zoneNode("arbiter", 0, true)},
+	}
+	assert.Equal(t, &nodeZones[2][0], scheduleMonitorWithArbiter(mon, nodeZones, "arbiter"))
+
+	// the arbiter zone has its tiebreaker mon, so the data zone with the fewest mons is chosen even
+	// though the arbiter zone has an empty node
+	nodeZones = [][]NodeUsage{
+		{zoneNode("dc1", 1, true), zoneNode("dc1", 1, true)},
+		{zoneNode("arbiter", 1, true), zoneNode("arbiter", 0, true)},
+		{zoneNode("dc2", 1, true), zoneNode("dc2", 0, true)},
+	}
+	assert.Equal(t, &nodeZones[2][1], scheduleMonitorWithArbiter(mon, nodeZones, "arbiter"))
+
+	// a mon on an invalid node in the arbiter zone counts as the tiebreaker mon
+	nodeZones = [][]NodeUsage{
+		{zoneNode("dc1", 0, true)},
+		{zoneNode("dc2", 1, true)},
+		{zoneNode("arbiter", 1, false), zoneNode("arbiter", 0, true)},
+	}
+	assert.Equal(t, &nodeZones[0][0], scheduleMonitorWithArbiter(mon, nodeZones, "arbiter"))
+
+	// the arbiter zone is the only zone with a valid node, but it is not overfilled
+	nodeZones = [][]NodeUsage{
+		{zoneNode("dc1", 1, false)},
+		{zoneNode("dc2", 1, false)},
+		{zoneNode("arbiter", 1, true), zoneNode("arbiter", 0, true)},
+	}
+	assert.Nil(t, scheduleMonitorWithArbiter(mon, nodeZones, "arbiter"))
+
+	// the replacement of the tiebreaker mon stays in the arbiter zone
+	replacement := &monConfig{DaemonName: "d", PreferredZone: "arbiter"}
+	nodeZones = [][]NodeUsage{
+		{zoneNode("dc1", 1, true), zoneNode("dc1", 0, true)},
+		{zoneNode("dc2", 1, true), zoneNode("dc2", 0, true)},
+		{zoneNode("arbiter", 1, false), zoneNode("arbiter", 0, true)},
+	}
+	assert.Equal(t, &nodeZones[2][1], scheduleMonitorWithArbiter(replacement, nodeZones, "arbiter"))
+}
+
+func TestScheduleMonitorWithHeadroom(t *testing.T) {
+	mon := &monConfig{DaemonName: "a"}
+
+	// enough empty nodes to place the mon on one and keep the headroom
+	nodeZones := [][]NodeUsage{
+		{
+			NodeUsage{Node: &v1.Node{}, MonCount: 1, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: true},
+		},
+	}
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitorWithHeadroom(mon, nodeZones, 1))
+
+	// the last empty node is kept free even though it would be the best choice without headroom
+	nodeZones = [][]NodeUsage{
+		{
+			NodeUsage{Node: &v1.Node{}, MonCount: 2, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonCount: 1, MonValid: true},
+		},
+		{
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: true},
+		},
+	}
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitor(mon, nodeZones))
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitorWithHeadroom(mon, nodeZones, 1))
+
+	// invalid empty nodes are no headroom
+	nodeZones = [][]NodeUsage{
+		{
+			NodeUsage{Node: &v1.Node{}, MonCount: 1, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: false},
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: true},
+		},
+	}
+	assert.Equal(t, &nodeZones[0][0], scheduleMonitorWithHeadroom(mon, nodeZones, 1))
+
+	// no node with mons can take the mon
+	nodeZones = [][]NodeUsage{
+		{
+			NodeUsage{Node: &v1.Node{}, MonCount: 1, MonValid: false},
+			NodeUsage{Node: &v1.Node{}, MonCount: 0, MonValid: true},
+		},
+	}
+	assert.Nil(t, scheduleMonitorWithHeadroom(mon, nodeZones, 1))
+}
+
+func TestFailoverHeadroom(t *testing.T) {
+	nodeZones := [][]NodeUsage{
+		{
+			NodeUsage{Node: &v1.Node{}, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonValid: false},
+		},
+		{
+			NodeUsage{Node: &v1.Node{}, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonValid: true},
+			NodeUsage{Node: &v1.Node{}, MonValid: true},
+		},
+	}
+	c := &Cluster{}
+	assert.Equal(t, 0, c.failoverHeadroom(nodeZones))
+
+	c.spec.Mon.FailoverHeadroomNodes = 1
+	assert.Equal(t, 1, c.failoverHeadroom(nodeZones))
+
+	// 30% of the 5 valid nodes is rounded up
+	c.spec.Mon.FailoverHeadroomPercent = 30
+	assert.Equal(t, 2, c.failoverHeadroom(nodeZones))
+
+	// the node count applies if it is larger
+	c.spec.Mon.FailoverHeadroomNodes = 3
+	assert.Equal(t, 3, c.failoverHeadroom(nodeZones))
+
+	c.spec.Mon.FailoverHeadroomPercent = 101
+	assert.Error(t, c.validateSpec())
+}
+
+func TestAssignMonsWithHeadroom(t *testing.T) {
+	c := newCluster(&clusterd.Context{Clientset: test.New(3)}, "ns", false, true, v1.ResourceRequirements{})
+	c.spec.Mon.FailoverHeadroomNodes = 1
+
+	// the new mons leave one node free
+	mons := []*monConfig{{DaemonName: "a"}, {DaemonName: "b"}, {DaemonName: "c"}}
+	assert.NoError(t, c.assignMons(mons))
+	nodes := map[string]bool{}
+	for _, mon := range mons {
+		nodes[c.mapping.Node[mon.DaemonName].Name] = true
+	}
+	assert.Equal(t, 2, len(nodes))
+
+	// the replacement of a failed mon may use the free node
+	replacement := &monConfig{DaemonName: "d", Replacement: true}
+	assert.NoError(t, c.assignMons([]*monConfig{replacement}))
+	assert.False(t, nodes[c.mapping.Node["d"].Name])
+}
+
+func TestScheduleMonitorWithRetry(t *testing.T) {
+	zoneNode := func(zone string, monCount int) NodeUsage {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{zoneLabel: zone}}}
+		return NodeUsage{Node: node, MonCount: monCount, MonValid: true}
+	}
+	mon := &monConfig{DaemonName: "a"}
+	allSteps := []SchedulingRelaxation{RelaxAntiAffinity, RelaxMultiplePerNode, RelaxZone}
+
+	// no relaxation needed: an empty node in a zone without mons
+	nodeZones := [][]NodeUsage{
+		{zoneNode("a", 1), zoneNode("a", 0)},
+		{zoneNode("b", 0)},
+	}
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, nil))
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, allSteps))
+
+	// all the zones have mons, so the anti-affinity must be relaxed to use the empty node
+	nodeZones = [][]NodeUsage{
+		{zoneNode("a", 1), zoneNode("a", 0)},
+		{zoneNode("b", 1)},
+	}
+	assert.Nil(t, scheduleMonitorWithRetry(mon, nodeZones, nil))
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitorWithRetry(mon, nodeZones, []SchedulingRelaxation{RelaxAntiAffinity}))
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitorWithRetry(mon, nodeZones, allSteps))
+
+	// all the nodes have mons, so multiple mons per node must be allowed
+	nodeZones = [][]NodeUsage{
+		{zoneNode("a", 2)},
+		{zoneNode("b", 1)},
+	}
+	assert.Nil(t, scheduleMonitorWithRetry(mon, nodeZones, []SchedulingRelaxation{RelaxAntiAffinity, RelaxZone}))
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, allSteps))
+
+	// the preferred zone only has a node with a mon, so the zone must be relaxed
+	mon.PreferredZone = "b"
+	nodeZones = [][]NodeUsage{
+		{zoneNode("a", 0)},
+		{zoneNode("b", 1)},
+	}
+	assert.Nil(t, scheduleMonitorWithRetry(mon, nodeZones, []SchedulingRelaxation{RelaxAntiAffinity}))
+	assert.Equal(t, &nodeZones[0][0], scheduleMonitorWithRetry(mon, nodeZones, []SchedulingRelaxation{RelaxAntiAffinity, RelaxZone}))
+	// the steps are applied in order, so the preferred zone is kept if multiple mons per node are allowed first
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, allSteps))
+
+	// the preferred zone is used when it has an empty node
+	nodeZones = [][]NodeUsage{
+		{zoneNode("a", 0)},
+		{zoneNode("b", 0)},
+	}
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, nil))
+}
+
+func TestScheduleMonitorHardwareClassWeight(t *testing.T) {
+	weightedNode := func(zone string, monCount, weight int) NodeUsage {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{zoneLabel: zone}}}
+		return NodeUsage{Node: node, MonCount: monCount, MonValid: true, Weight: weight}
+	}
+	mon := &monConfig{DaemonName: "a"}
+
+	// two nodes with the same mon count in the same zone
+	nodeZones := [][]NodeUsage{
+		{weightedNode("a", 0, 1), weightedNode("a", 0, 10)},
+	}
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitor(mon, nodeZones))
+	assert.Equal(t, &nodeZones[0][1], scheduleMonitorWithRetry(mon, nodeZones, nil))
+
+	// two empty zones
+	nodeZones = [][]NodeUsage{
+		{weightedNode("a", 0, 0)},
+		{weightedNode("b", 0, 10)},
+	}
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitor(mon, nodeZones))
+	assert.Equal(t, &nodeZones[1][0], scheduleMonitorWithRetry(mon, nodeZones, nil))
+
+	// the mon count is more important than the weight
+	nodeZones = [][]NodeUsage{
+		{weightedNode("a", 1, 0), weightedNode("a", 2, 10)},
+	}
+	assert.Equal(t, &nodeZones[0][0], scheduleMonitor(mon, nodeZones))
+	assert.Equal(t, &nodeZones[0][0], scheduleMonitorWithRetry(mon, nodeZones, []SchedulingRelaxation{RelaxAntiAffinity, RelaxMultiplePerNode}))
+}
+
+func TestNodeMonUsageWeight(t *testing.T) {
+	clientset := test.New(2)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	c.spec.Mon.HardwareClassWeights = map[string]int{"gen2": 10}
+
+	node, err := clientset.CoreV1().Nodes().Get("node0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	node.Labels = map[string]string{hardwareClassLabel: "gen2"}
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.Nil(t, err)
+
+	nodeZones, err := c.getNodeMonUsage()
+	assert.Nil(t, err)
+	weights := map[string]int{}
+	for _, zone := range nodeZones {
+		for _, nodeUsage := range zone {
+			weights[nodeUsage.Node.Name] = nodeUsage.Weight
+		}
+	}
+	assert.Equal(t, map[string]int{"node0": 10, "node1": 0}, weights)
+}
@@ -26,15 +26,24 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// the label of the failure domain zone of a node
+const zoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+// the label of the hardware class of a node that is weighted with the hardwareClassWeights mon setting
+const hardwareClassLabel = "rook.io/hardware-class"
+
 // NodeUsage is a mapping between a Node and computed metadata about the node
 // that is used in monitor pod scheduling.
 type NodeUsage struct {
 	Node *v1.Node
-	// The number of monitor pods assigned to the node
+	// The number of monitors assigned to the node
 	MonCount int
 	// The node is available for scheduling monitor pods. This is equivalent to
 	// evaluating k8sutil.ValidNode(node, cephv1.GetMonPlacement(c.spec.Placement))
 	MonValid bool
+	// The weight of the hardware class of the node. Nodes with a higher weight are preferred when
+	// nodes have the same number of monitors.
+	Weight int
 }
 
 // getMonNodes detects the nodes that are available for new mons to start.
@@ -95,7 +104,7 @@ func (c *Cluster) getAvailableMonNodes() ([]v1.Node, *v1.NodeList, error) {
 
 func (c *Cluster) getNodesWithMons(nodes *v1.NodeList) (*util.Set, error) {
 	// get the mon pods and their node affinity
-	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
+	options := metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()}
 	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(options)
 	if err != nil {
 		return nil, err
@@ -139,6 +148,32 @@ func (c *Cluster) getTargetMonCount() (int, string, error) {
 	return target, msg, nil
 }
 
+// limitMonCountChange returns the mon count to target in this reconcile to change the mon count
+// from the current count towards the target count by at most the max mon change rate of the spec.
+// The mons that are not added or removed yet are handled by the following health checks. The mon
+// count of a new cluster is not limited.
+func (c *Cluster) limitMonCountChange(current, target int) int {
+	rate := c.spec.Mon.MaxMonChangeRate
+	if rate <= 0 {
+		rate = DefaultMaxMonChangeRate
+	}
+	if current == 0 {
+		return target
+	}
+
+	limited := target
+	if target > current+rate {
+		limited = current + rate
+	} else if target < current-rate {
+		limited = current - rate
+	}
+	if limited != target {
+		logger.Infof("changing the mon count from %d to %d instead of %d since at most %d mons are changed at once. the remaining mons are changed by the next health checks",
+			current, limited, target, rate)
+	}
+	return limited
+}
+
 func calcTargetMonCount(nodes int, spec cephv1.MonSpec) (int, string) {
 	minTarget := spec.Count
 	preferredTarget := spec.PreferredCount
@@ -187,6 +222,7 @@ func getNodeInfoFromNode(n v1.Node) (*NodeInfo, error) {
 	nr := &NodeInfo{
 		Name:     n.Name,
 		Hostname: n.Labels[v1.LabelHostname],
+		Zone:     n.Labels[zoneLabel],
 	}
 
 	for _, ip := range n.Status.Addresses {
@@ -217,19 +253,23 @@ func (c *Cluster) getNodeMonUsage() ([][]NodeUsage, error) {
 	}
 
 	// get all pod objects labeled as a monitor
-	podOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
+	podOptions := metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()}
 	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(podOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	// generate a list of nodes that includes the number of monitor pods
-	// assigned to each node. this is equivalent to:
+	// generate a list of nodes that includes the number of monitors assigned
+	// to each node. this is equivalent to:
 	//
-	//   SELECT Node, Count(*) FROM Nodes
-	//   JOIN Pods ON Nodes.Hostname == Pods.Hostname
+	//   SELECT Node, Count(DISTINCT Mon) FROM Nodes
+	//   JOIN (Pods UNION Mapping) ON Nodes.Hostname == Hostname
 	//   GROUP BY Node
 	//
+	// mons in the node mapping are counted even if their pod does not exist
+	// yet (e.g. the operator restarted while creating the mons). otherwise the
+	// zone of such a mon would look empty and the next mon could be placed in
+	// the same zone.
 	nodeUsages := []NodeUsage{}
 	for i, node := range nodes.Items {
 		valid, err := k8sutil.ValidNode(node, cephv1.GetMonPlacement(c.spec.Placement))
@@ -237,13 +277,28 @@ func (c *Cluster) getNodeMonUsage() ([][]NodeUsage, error) {
 			logger.Warning("failed to validate node %s %v", node.Name, err)
 			continue
 		}
-		nodeUsage := NodeUsage{Node: &nodes.Items[i], MonCount: 0, MonValid: valid}
+		nodeMons := util.NewSet()
 		for _, pod := range pods.Items {
 			hostname := pod.Spec.NodeSelector[v1.LabelHostname]
 			if node.Name == hostname || node.Labels[v1.LabelHostname] == hostname {
-				nodeUsage.MonCount++
+				monName, ok := pod.Labels[monDaemonAttr]
+				if !ok {
+					monName = pod.Name
+				}
+				nodeMons.Add(monName)
 			}
 		}
+		for monName, nodeInfo := range c.mapping.Node {
+			if node.Name == nodeInfo.Name {
+				nodeMons.Add(monName)
+			}
+		}
+		nodeUsage := NodeUsage{
+			Node:     &nodes.Items[i],
+			MonCount: nodeMons.Count(),
+			MonValid: valid,
+			Weight:   c.spec.Mon.HardwareClassWeights[node.Labels[hardwareClassLabel]],
+		}
 		nodeUsages = append(nodeUsages, nodeUsage)
 	}
 
@@ -252,7 +307,7 @@ func (c *Cluster) getNodeMonUsage() ([][]NodeUsage, error) {
 	// for nodes without a zone annotation.
 	nodesByZone := make(map[string][]NodeUsage)
 	for _, nodeUsage := range nodeUsages {
-		zone, ok := nodeUsage.Node.Labels["failure-domain.beta.kubernetes.io/zone"]
+		zone, ok := nodeUsage.Node.Labels[zoneLabel]
 		if !ok {
 			zone = ""
 		}
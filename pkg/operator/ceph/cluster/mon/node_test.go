@@ -17,6 +17,7 @@ limitations under the License.
 package mon
 
 import (
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
@@ -25,7 +26,9 @@ import (
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
 	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -186,18 +189,59 @@ func TestNodeAffinity(t *testing.T) {
 	assert.Equal(t, 2, validCount)
 }
 
-// this tests can 3 mons with hostnetworking on the same host is rejected
+// this tests that 3 mons with hostnetworking on the same host listen on distinct ports
 func TestHostNetworkSameNode(t *testing.T) {
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
+	context.Clientset = test.New(1)
+	executor := context.Executor.(*exectest.MockExecutor)
+	executeWithOutput := executor.MockExecuteCommandWithOutput
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		version := "ceph version 14.2.1 (d555a9489eb35f84f2e1ef49b77e19da9d113972) nautilus (stable)"
+		for _, arg := range args {
+			if arg == "version" {
+				return version, nil
+			}
+			if arg == "versions" {
+				return fmt.Sprintf(`{"mon":{"%s":3}}`, version), nil
+			}
+		}
+		return executeWithOutput(debug, actionName, command, args...)
+	}
 
 	// cluster host networking
 	c := newCluster(context, namespace, true, true, v1.ResourceRequirements{})
-	c.ClusterInfo = test.CreateConfigDir(1)
 
 	// start a basic cluster
-	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
-	assert.Error(t, err)
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Nautilus, c.spec)
+	assert.NoError(t, err)
+
+	expectedAddrs := map[string]string{
+		"a": "0.0.0.0",
+		"b": "[v2:0.0.0.0:3301,v1:0.0.0.0:6790]",
+		"c": "[v2:0.0.0.0:3302,v1:0.0.0.0:6791]",
+	}
+	expectedPorts := map[string][]int32{
+		"a": {6789, 3300},
+		"b": {6790, 3301},
+		"c": {6791, 3302},
+	}
+	for name := range expectedAddrs {
+		d, err := context.Clientset.AppsV1().Deployments(namespace).Get(resourceName(name), metav1.GetOptions{})
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, "node0", c.mapping.Node[name].Name)
+		container := d.Spec.Template.Spec.Containers[0]
+		val, message := extractArgValue(container.Args, "--public-addr")
+		assert.Equal(t, expectedAddrs[name], val, message)
+		ports := []int32{}
+		for _, port := range container.Ports {
+			ports = append(ports, port.ContainerPort)
+		}
+		assert.Equal(t, expectedPorts[name], ports)
+	}
 }
 
 func TestPodMemory(t *testing.T) {
@@ -400,6 +444,30 @@ func TestTargetMonCount(t *testing.T) {
 	logger.Infof(msg)
 }
 
+func TestLimitMonCountChange(t *testing.T) {
+	c := &Cluster{}
+
+	// a new cluster is not limited
+	assert.Equal(t, 3, c.limitMonCountChange(0, 3))
+
+	// one mon is added or removed at a time by default
+	assert.Equal(t, 2, c.limitMonCountChange(1, 7))
+	assert.Equal(t, 6, c.limitMonCountChange(7, 1))
+	assert.Equal(t, 4, c.limitMonCountChange(3, 4))
+	assert.Equal(t, 3, c.limitMonCountChange(3, 3))
+
+	// the change is limited to the rate in the spec
+	c.spec.Mon.MaxMonChangeRate = 2
+	assert.Equal(t, 3, c.limitMonCountChange(1, 7))
+	assert.Equal(t, 5, c.limitMonCountChange(7, 1))
+	assert.Equal(t, 5, c.limitMonCountChange(3, 5))
+
+	// a rate higher than the change does not limit it
+	c.spec.Mon.MaxMonChangeRate = 6
+	assert.Equal(t, 7, c.limitMonCountChange(1, 7))
+	assert.Equal(t, 1, c.limitMonCountChange(7, 1))
+}
+
 // mon node usage should return no zones if there are no nodes
 func TestGetNodeMonUsageNoNodes(t *testing.T) {
 	clientset := test.New(0)
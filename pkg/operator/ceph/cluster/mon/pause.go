@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monReconcilePausedAnnotation on the CephCluster stops the operator from changing the mons, e.g.
+	// during a manual maintenance of the mons
+	monReconcilePausedAnnotation = "rook.io/mon-reconcile-paused"
+)
+
+// monReconcilePaused returns whether the CephCluster that owns the mons has the annotation that
+// pauses the reconcile of the mons. The reconcile is not paused if the cluster cannot be read.
+func (c *Cluster) monReconcilePaused() bool {
+	if c.context.RookClientset == nil {
+		return false
+	}
+
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get cluster %s to check if the mon reconcile is paused. %+v", c.ownerRef.Name, err)
+		return false
+	}
+	return cluster.Annotations[monReconcilePausedAnnotation] == "true"
+}
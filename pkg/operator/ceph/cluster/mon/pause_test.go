@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMonReconcilePaused(t *testing.T) {
+	rookClientset := rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	_, err := rookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.NoError(t, err)
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset, RookClientset: rookClientset}, "ns", false, true, v1.ResourceRequirements{})
+	c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	setPaused := func(value string) {
+		cluster, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		cluster.Annotations = map[string]string{monReconcilePausedAnnotation: value}
+		_, err = rookClientset.CephV1().CephClusters("ns").Update(cluster)
+		assert.NoError(t, err)
+	}
+
+	// not paused without the annotation or if the cluster is not found
+	assert.False(t, c.monReconcilePaused())
+	c.ownerRef.Name = "other"
+	assert.False(t, c.monReconcilePaused())
+	c.ownerRef.Name = "rook-ceph"
+	setPaused("false")
+	assert.False(t, c.monReconcilePaused())
+
+	// the mons are not started while paused
+	setPaused("true")
+	assert.True(t, c.monReconcilePaused())
+	info := test.CreateConfigDir(1)
+	result, err := c.Start(info, c.rookVersion, cephver.Nautilus, c.spec)
+	assert.NoError(t, err)
+	assert.Equal(t, info, result)
+	assert.Nil(t, c.ClusterInfo)
+	deployments, err := clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, deployments.Items)
+	assert.Contains(t, <-recorder.Events, "MonReconcilePaused")
+
+	// the health check does not connect to the mons while paused
+	assert.NoError(t, c.checkHealth())
+}
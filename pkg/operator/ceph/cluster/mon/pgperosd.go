@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	maxPGPerOSDKey = "mon_max_pg_per_osd"
+	// the range of the PG per OSD limit that can be set in the cluster spec
+	minMaxPGPerOSD = 100
+	maxMaxPGPerOSD = 1000
+	// a warning is raised when the PGs per OSD exceed this percentage of the limit
+	pgPerOSDWarningPercent = 80
+)
+
+// validateMaxPGPerOSD checks that the PG per OSD limit of the cluster spec is in the allowed range.
+// A limit of zero keeps the ceph default.
+func validateMaxPGPerOSD(limit int) error {
+	if limit == 0 {
+		return nil
+	}
+	if limit < minMaxPGPerOSD || limit > maxMaxPGPerOSD {
+		return fmt.Errorf("invalid max pg per osd %d. the limit must be between %d and %d", limit, minMaxPGPerOSD, maxMaxPGPerOSD)
+	}
+	return nil
+}
+
+// applyMaxPGPerOSD sets the PG per OSD limit of the cluster spec in the ceph config
+func (c *Cluster) applyMaxPGPerOSD() error {
+	if c.spec.MaxPGPerOSD == 0 {
+		return nil
+	}
+	value := strconv.Itoa(c.spec.MaxPGPerOSD)
+	if err := client.GlobalSetConfig(c.context, c.ClusterInfo.Name, maxPGPerOSDKey, value); err != nil {
+		return err
+	}
+	logger.Infof("set %s=%s", maxPGPerOSDKey, value)
+	return nil
+}
+
+// checkPGPerOSD raises a warning event if the average number of PGs per OSD exceeds the warning
+// percentage of the PG per OSD limit. The average is returned.
+func (c *Cluster) checkPGPerOSD() (float64, error) {
+	if c.spec.MaxPGPerOSD == 0 {
+		return 0, nil
+	}
+
+	usage, err := client.GetOSDUsage(c.context, c.ClusterInfo.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the pgs per osd. %+v", err)
+	}
+	if len(usage.OSDNodes) == 0 {
+		return 0, nil
+	}
+
+	totalPGs := int64(0)
+	for _, osd := range usage.OSDNodes {
+		pgs, err := osd.Pgs.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid pg count %q of osd %d. %+v", osd.Pgs, osd.ID, err)
+		}
+		totalPGs += pgs
+	}
+	average := float64(totalPGs) / float64(len(usage.OSDNodes))
+
+	threshold := float64(c.spec.MaxPGPerOSD*pgPerOSDWarningPercent) / 100
+	if average > threshold {
+		msg := fmt.Sprintf("the osds have %.1f pgs on average, more than %d%% of the max pg per osd limit of %d", average, pgPerOSDWarningPercent, c.spec.MaxPGPerOSD)
+		logger.Warningf(msg)
+		c.recordEvent(v1.EventTypeWarning, "TooManyPGsPerOSD", msg)
+	}
+	return average, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestValidateMaxPGPerOSD(t *testing.T) {
+	assert.NoError(t, validateMaxPGPerOSD(0))
+	assert.NoError(t, validateMaxPGPerOSD(100))
+	assert.NoError(t, validateMaxPGPerOSD(250))
+	assert.NoError(t, validateMaxPGPerOSD(1000))
+	assert.Error(t, validateMaxPGPerOSD(99))
+	assert.Error(t, validateMaxPGPerOSD(1001))
+	assert.Error(t, validateMaxPGPerOSD(-1))
+
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	c.spec.MaxPGPerOSD = 50
+	assert.Error(t, c.validateSpec())
+	c.spec.MaxPGPerOSD = 500
+	assert.NoError(t, c.validateSpec())
+}
+
+func TestApplyMaxPGPerOSD(t *testing.T) {
+	var setArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				setArgs = args[:5]
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// nothing is set without a limit in the spec
+	assert.NoError(t, c.applyMaxPGPerOSD())
+	assert.Nil(t, setArgs)
+
+	c.spec.MaxPGPerOSD = 300
+	assert.NoError(t, c.applyMaxPGPerOSD())
+	assert.Equal(t, []string{"config", "set", "global", "mon_max_pg_per_osd", "300"}, setArgs)
+}
+
+func TestCheckPGPerOSD(t *testing.T) {
+	osdDF := ""
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "df" {
+				return osdDF, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	osdDFWithPGs := func(pgs ...int) string {
+		nodes := ""
+		for i, count := range pgs {
+			if i > 0 {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"id":%d,"name":"osd.%d","pgs":%d}`, i, i, count)
+		}
+		return fmt.Sprintf(`{"nodes":[%s],"summary":{"total_kb":0}}`, nodes)
+	}
+
+	// the pgs are not checked without a limit in the spec
+	average, err := c.checkPGPerOSD()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), average)
+
+	// below 80% of the limit
+	c.spec.MaxPGPerOSD = 200
+	osdDF = osdDFWithPGs(150, 170, 160)
+	average, err = c.checkPGPerOSD()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(160), average)
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// above 80% of the limit on average, even if some osds are below
+	osdDF = osdDFWithPGs(150, 180, 171)
+	average, err = c.checkPGPerOSD()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(167), average)
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "Warning TooManyPGsPerOSD the osds have 167.0 pgs on average")
+
+	// no osds yet
+	osdDF = osdDFWithPGs()
+	average, err = c.checkPGPerOSD()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), average)
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// the osd df fails
+	osdDF = "not json"
+	_, err = c.checkPGPerOSD()
+	assert.Error(t, err)
+	assert.Equal(t, 0, len(recorder.Events))
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReconcilePlan describes what the orchestration of the mons would do for a cluster spec. It is
+// serialized to JSON for previews of a spec change.
+type ReconcilePlan struct {
+	// DesiredMonCount is the number of mons the spec asks for on the current nodes
+	DesiredMonCount int `json:"desiredMonCount"`
+	// TargetMonCount is the number of mons after this reconcile, which is limited by the max mon
+	// change rate
+	TargetMonCount int `json:"targetMonCount"`
+	// Create are the mons that would be created
+	Create []PlannedMonChange `json:"create"`
+	// Remove are the mons that would be removed
+	Remove []PlannedMonChange `json:"remove"`
+	// Roll are the existing mons whose deployment would be updated, which restarts the mon
+	Roll []PlannedMonChange `json:"roll"`
+	// Endpoints are the mon endpoints that would be added or removed
+	Endpoints []PlannedEndpointChange `json:"endpoints"`
+	// Placement is the node of each mon after this reconcile
+	Placement []PlannedMonPlacement `json:"placement"`
+	// Blocked explains why a change needed for the spec would not be made in this reconcile
+	Blocked []string `json:"blocked,omitempty"`
+}
+
+// PlannedMonChange is a mon that would be created, removed or rolled
+type PlannedMonChange struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// PlannedEndpointChange is an endpoint that would be added to or removed from the mon endpoints. The
+// endpoint of a new mon without host networking is the ip of its service, which is only known once
+// the service is created.
+type PlannedEndpointChange struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Added    bool   `json:"added"`
+}
+
+// PlannedMonPlacement is the node a mon is assigned to
+type PlannedMonPlacement struct {
+	Name   string `json:"name"`
+	Node   string `json:"node"`
+	Zone   string `json:"zone,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// PlanReconcile reports what the orchestration of the mons would do if the cluster spec was applied,
+// without changing the mons. New mons are scheduled like they would be by the orchestration, so the
+// placement of the new mons is only a prediction if the nodes change in the meantime.
+func (c *Cluster) PlanReconcile(spec cephv1.ClusterSpec) (*ReconcilePlan, error) {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	// the planning works on a copy of the settings and the mapping that are restored afterwards
+	previousSpec, previousMapping, previousMaxMonID := c.spec, c.mapping, c.maxMonID
+	defer func() {
+		c.spec, c.mapping, c.maxMonID = previousSpec, previousMapping, previousMaxMonID
+	}()
+	c.spec = spec
+	c.mapping = copyMapping(previousMapping)
+
+	if err := c.validateSpec(); err != nil {
+		return nil, err
+	}
+	if err := c.validateMonCount(); err != nil {
+		return nil, err
+	}
+	desiredCount, _, err := c.getTargetMonCount()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target mon count. %+v", err)
+	}
+	status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mon status. %+v", err)
+	}
+
+	currentCount := len(c.ClusterInfo.Monitors)
+	plan := &ReconcilePlan{
+		DesiredMonCount: desiredCount,
+		TargetMonCount:  c.limitMonCountChange(currentCount, desiredCount),
+		Create:          []PlannedMonChange{},
+		Remove:          []PlannedMonChange{},
+		Roll:            []PlannedMonChange{},
+		Endpoints:       []PlannedEndpointChange{},
+		Placement:       []PlannedMonPlacement{},
+	}
+	if plan.TargetMonCount != desiredCount {
+		plan.Blocked = append(plan.Blocked, fmt.Sprintf("the mon count changes from %d to %d in this reconcile to limit the mon change rate", currentCount, plan.TargetMonCount))
+	}
+
+	size := currentCount
+	if plan.TargetMonCount > size {
+		size = plan.TargetMonCount
+	}
+	existingCount, mons := c.initMonConfig(size)
+	if err := c.assignMons(mons); err != nil {
+		return nil, fmt.Errorf("failed to assign the new mons to nodes. %+v", err)
+	}
+
+	for _, m := range mons[existingCount:] {
+		plan.Create = append(plan.Create, PlannedMonChange{
+			Name:   m.DaemonName,
+			Reason: fmt.Sprintf("%d mons exist and %d are desired", currentCount, desiredCount),
+		})
+		endpoint := ""
+		if c.HostNetwork {
+			endpoint = net.JoinHostPort(c.mapping.Node[m.DaemonName].Address, strconv.Itoa(int(m.Port)))
+		}
+		plan.Endpoints = append(plan.Endpoints, PlannedEndpointChange{Name: m.DaemonName, Endpoint: endpoint, Added: true})
+	}
+
+	removed := c.planMonRemoval(plan, status)
+	for _, m := range mons[:existingCount] {
+		if removed[m.DaemonName] {
+			continue
+		}
+		reason, err := c.planMonRoll(m)
+		if err != nil {
+			return nil, err
+		}
+		// the mon endpoints are part of the config of the mons
+		if reason == "" && len(plan.Endpoints) > 0 {
+			reason = "the mon endpoints change"
+		}
+		if reason != "" {
+			plan.Roll = append(plan.Roll, PlannedMonChange{Name: m.DaemonName, Reason: reason})
+		}
+	}
+
+	for _, m := range mons {
+		if removed[m.DaemonName] {
+			continue
+		}
+		node := c.mapping.Node[m.DaemonName]
+		if node == nil {
+			continue
+		}
+		reason := "the mon is already assigned to the node"
+		if _, ok := previousMapping.Node[m.DaemonName]; !ok {
+			reason = "the node is chosen by the mon placement for the new mon"
+		}
+		plan.Placement = append(plan.Placement, PlannedMonPlacement{Name: m.DaemonName, Node: node.Name, Zone: node.Zone, Reason: reason})
+	}
+	sort.Slice(plan.Roll, func(i, j int) bool { return plan.Roll[i].Name < plan.Roll[j].Name })
+	sort.Slice(plan.Placement, func(i, j int) bool { return plan.Placement[i].Name < plan.Placement[j].Name })
+	return plan, nil
+}
+
+// planMonRemoval adds the mons that would be removed to the plan. Like in the health check, the
+// extra mons are removed in the order of the mon map and only when all the mons are in quorum.
+func (c *Cluster) planMonRemoval(plan *ReconcilePlan, status client.MonStatusResponse) map[string]bool {
+	removed := map[string]bool{}
+	mons := len(status.MonMap.Mons)
+	if mons <= plan.TargetMonCount {
+		return removed
+	}
+	if len(status.Quorum) != mons {
+		plan.Blocked = append(plan.Blocked, fmt.Sprintf("extra mons are only removed when all the mons are in quorum. %d of %d mons are in quorum", len(status.Quorum), mons))
+		return removed
+	}
+	if plan.TargetMonCount < 2 && mons == 2 {
+		plan.Blocked = append(plan.Blocked, "the mon quorum size cannot be reduced from 2 to 1")
+		return removed
+	}
+
+	for _, mon := range status.MonMap.Mons[:mons-plan.TargetMonCount] {
+		removed[mon.Name] = true
+		plan.Remove = append(plan.Remove, PlannedMonChange{
+			Name:   mon.Name,
+			Reason: fmt.Sprintf("%d mons exist and %d are desired", mons, plan.DesiredMonCount),
+		})
+		endpoint := ""
+		if info, ok := c.ClusterInfo.Monitors[mon.Name]; ok {
+			endpoint = info.Endpoint
+		}
+		plan.Endpoints = append(plan.Endpoints, PlannedEndpointChange{Name: mon.Name, Endpoint: endpoint, Added: false})
+	}
+	return removed
+}
+
+// planMonRoll returns why the deployment of an existing mon would be updated, or an empty string if
+// the deployment is up to date
+func (c *Cluster) planMonRoll(m *monConfig) (string, error) {
+	existing, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "the deployment of the mon does not exist", nil
+		}
+		return "", fmt.Errorf("failed to get mon deployment %s. %+v", m.ResourceName, err)
+	}
+	node, ok := c.mapping.Node[m.DaemonName]
+	if !ok || node == nil {
+		return "", fmt.Errorf("mon %s is not assigned to a node", m.DaemonName)
+	}
+	if !monConfigHashMatches(existing, c.makeDeployment(m, node.Hostname)) {
+		return "the settings the deployment of the mon is generated from changed", nil
+	}
+	return "", nil
+}
+
+// copyMapping returns a copy of the mapping that can be changed without changing the mapping
+func copyMapping(mapping *Mapping) *Mapping {
+	copied := &Mapping{Node: map[string]*NodeInfo{}, Port: map[string]int32{}}
+	for name, info := range mapping.Node {
+		if info == nil {
+			copied.Node[name] = nil
+			continue
+		}
+		nodeInfo := *info
+		copied.Node[name] = &nodeInfo
+	}
+	for node, port := range mapping.Port {
+		copied.Port[node] = port
+	}
+	return copied
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanReconcile(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	c.context.Executor = &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return `{"quorum":[0,1,2],"monmap":{"mons":[{"name":"c","rank":0},{"name":"a","rank":1},{"name":"b","rank":2}]}}`, nil
+		},
+	}
+	c.ClusterInfo = test.CreateConfigDir(3)
+	_, mons := c.initMonConfig(3)
+	for _, m := range mons {
+		assert.NoError(t, c.startMon(m, c.mapping.Node[m.DaemonName].Hostname))
+	}
+	spec := c.spec
+	spec.Mon.MaxMonChangeRate = 2
+
+	// nothing changes for the current spec
+	plan, err := c.PlanReconcile(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, plan.TargetMonCount)
+	assert.Empty(t, plan.Create)
+	assert.Empty(t, plan.Remove)
+	assert.Empty(t, plan.Roll)
+	assert.Empty(t, plan.Endpoints)
+	assert.Equal(t, 3, len(plan.Placement))
+	assert.Equal(t, PlannedMonPlacement{Name: "a", Node: "node0", Zone: "zone0", Reason: "the mon is already assigned to the node"}, plan.Placement[0])
+
+	// two mons are created and the existing mons are rolled for the new endpoints
+	spec.Mon.Count = 5
+	plan, err = c.PlanReconcile(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, plan.TargetMonCount)
+	assert.Equal(t, []string{"d", "e"}, plannedMonNames(plan.Create))
+	assert.Equal(t, []string{"a", "b", "c"}, plannedMonNames(plan.Roll))
+	assert.Equal(t, "the mon endpoints change", plan.Roll[0].Reason)
+	assert.Equal(t, 5, len(plan.Placement))
+	assert.Equal(t, "the node is chosen by the mon placement for the new mon", plan.Placement[3].Reason)
+	data, err := json.Marshal(plan)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"create":[{"name":"d","reason":"3 mons exist and 5 are desired"},{"name":"e","reason":"3 mons exist and 5 are desired"}]`)
+	assert.Contains(t, string(data), `"remove":[]`)
+
+	// the planning does not change the mons
+	assert.Equal(t, 3, len(c.mapping.Node))
+	assert.Equal(t, 2, c.maxMonID)
+	assert.Equal(t, 3, c.spec.Mon.Count)
+
+	// the extra mons are removed in the order of the mon map
+	spec.Mon.Count = 1
+	plan, err = c.PlanReconcile(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, plannedMonNames(plan.Remove))
+	assert.Equal(t, []string{"b"}, plannedMonNames(plan.Roll))
+	data, err = json.Marshal(plan)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"remove":[{"name":"c","reason":"3 mons exist and 1 are desired"},{"name":"a","reason":"3 mons exist and 1 are desired"}]`)
+	assert.Contains(t, string(data), `"create":[]`)
+	assert.Contains(t, string(data), `{"name":"c","endpoint":"1.2.3.3:6789","added":false}`)
+
+	// the count change is limited by the change rate
+	spec.Mon.Count = 5
+	spec.Mon.MaxMonChangeRate = 1
+	plan, err = c.PlanReconcile(spec)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, plan.TargetMonCount)
+	assert.Equal(t, []string{"d"}, plannedMonNames(plan.Create))
+	assert.Equal(t, 1, len(plan.Blocked))
+
+	// an invalid spec is not planned
+	spec.Mon.Count = 0
+	_, err = c.PlanReconcile(spec)
+	assert.Error(t, err)
+}
+
+func plannedMonNames(changes []PlannedMonChange) []string {
+	names := []string{}
+	for _, change := range changes {
+		names = append(names, change.Name)
+	}
+	return names
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PodSecurityStandardBaseline runs the mons without privileges and without host networking
+	PodSecurityStandardBaseline = "baseline"
+	// PodSecurityStandardRestricted additionally runs the mons as the ceph user without any
+	// capabilities and without volumes on the host
+	PodSecurityStandardRestricted = "restricted"
+
+	// the uid and gid of the ceph user in the ceph images
+	cephUserID int64 = 167
+	// the api of kubernetes 1.14 has no seccomp field in the security context
+	seccompPodAnnotation  = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompRuntimeDefault = "runtime/default"
+)
+
+// validatePodSecurityStandard checks that the mons can be run with the pod security standard
+func (c *Cluster) validatePodSecurityStandard() error {
+	switch c.spec.Mon.PodSecurityStandard {
+	case "":
+		return nil
+	case PodSecurityStandardBaseline, PodSecurityStandardRestricted:
+	default:
+		return fmt.Errorf("invalid mon pod security standard %q. the standard must be %q or %q", c.spec.Mon.PodSecurityStandard, PodSecurityStandardBaseline, PodSecurityStandardRestricted)
+	}
+
+	if c.HostNetwork {
+		return fmt.Errorf("the mons cannot use host networking with the %q pod security standard", c.spec.Mon.PodSecurityStandard)
+	}
+	if c.spec.Mon.PodSecurityStandard == PodSecurityStandardRestricted && c.spec.Mon.VolumeClaimTemplate == nil {
+		return fmt.Errorf("the mons must store their data on a pvc with the %q pod security standard. set the mon volumeClaimTemplate", PodSecurityStandardRestricted)
+	}
+	return nil
+}
+
+// monSecurityContext returns the security context of the mon containers
+func (c *Cluster) monSecurityContext() *v1.SecurityContext {
+	var securityContext *v1.SecurityContext
+	switch c.spec.Mon.PodSecurityStandard {
+	case PodSecurityStandardBaseline:
+		privileged := false
+		securityContext = &v1.SecurityContext{Privileged: &privileged}
+	case PodSecurityStandardRestricted:
+		privileged := false
+		allowPrivilegeEscalation := false
+		runAsNonRoot := true
+		securityContext = &v1.SecurityContext{
+			Privileged:               &privileged,
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			RunAsNonRoot:             &runAsNonRoot,
+			Capabilities: &v1.Capabilities{
+				Drop: []v1.Capability{"ALL"},
+			},
+		}
+	default:
+		securityContext = PodSecurityContext()
+	}
+
+	if monRunAsNonRoot(c.spec.Mon) {
+		user := cephUserID
+		runAsNonRoot := true
+		securityContext.RunAsUser = &user
+		securityContext.RunAsGroup = &user
+		securityContext.RunAsNonRoot = &runAsNonRoot
+	}
+	return securityContext
+}
+
+// chownSecurityContext returns the security context of the init container that chowns the mon data
+// dir, which must run as root even if the mons run as the ceph user
+func (c *Cluster) chownSecurityContext() *v1.SecurityContext {
+	securityContext := c.monSecurityContext()
+	if monRunAsNonRoot(c.spec.Mon) {
+		root := int64(0)
+		runAsNonRoot := false
+		securityContext.RunAsUser = &root
+		securityContext.RunAsGroup = &root
+		securityContext.RunAsNonRoot = &runAsNonRoot
+	}
+	return securityContext
+}
+
+// monRunAsNonRoot returns whether the mons run as the ceph user. The setting is only unset for an
+// existing cluster, whose mons keep running as root.
+func monRunAsNonRoot(spec cephv1.MonSpec) bool {
+	return spec.RunAsNonRoot != nil && *spec.RunAsNonRoot
+}
+
+// defaultMonRunAsNonRoot enables runAsNonRoot for a new cluster if it is not set. The default is
+// saved in the cluster CR before the cluster is created, so that the mons keep running as the ceph
+// user after the operator restarts while the mons of the existing clusters keep running as root.
+func (c *Cluster) defaultMonRunAsNonRoot() error {
+	if c.spec.Mon.RunAsNonRoot != nil {
+		return nil
+	}
+	_, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+
+	runAsNonRoot := true
+	if c.context.RookClientset != nil {
+		cluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.ownerRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get cluster %s to enable runAsNonRoot. %+v", c.ownerRef.Name, err)
+		}
+		cluster.Spec.Mon.RunAsNonRoot = &runAsNonRoot
+		if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cluster); err != nil {
+			return fmt.Errorf("failed to enable runAsNonRoot in cluster %s. %+v", c.ownerRef.Name, err)
+		}
+	}
+	logger.Infof("running the mons of the new cluster as the ceph user")
+	c.spec.Mon.RunAsNonRoot = &runAsNonRoot
+	return nil
+}
+
+// monDataOwner returns the owner that the mon data dir is chowned to. The uid is used when the mons
+// run as the ceph user so that the owner is the user that the containers run as.
+func (c *Cluster) monDataOwner() string {
+	if monRunAsNonRoot(c.spec.Mon) {
+		return fmt.Sprintf("%d:%d", cephUserID, cephUserID)
+	}
+	return "ceph:ceph"
+}
+
+// applyRestrictedPodSecurity runs the mon pod as the ceph user. The data volume is owned by the
+// ceph group, so the data dir does not need to be chowned by a root init container. The logs are
+// not kept on the host.
+func applyRestrictedPodSecurity(pod *v1.Pod) {
+	user := cephUserID
+	runAsNonRoot := true
+	pod.Spec.SecurityContext = &v1.PodSecurityContext{
+		RunAsUser:    &user,
+		RunAsGroup:   &user,
+		RunAsNonRoot: &runAsNonRoot,
+		FSGroup:      &user,
+	}
+
+	initContainers := []v1.Container{}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name != chownContainerName {
+			initContainers = append(initContainers, container)
+		}
+	}
+	pod.Spec.InitContainers = initContainers
+
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].HostPath != nil {
+			pod.Spec.Volumes[i].VolumeSource = v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+		}
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[seccompPodAnnotation] = seccompRuntimeDefault
+}
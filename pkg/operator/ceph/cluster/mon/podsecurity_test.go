@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"sync"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMonPodSecurityStandards(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// the mons keep their security settings by default
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	pod := d.Spec.Template.Spec
+	assert.Nil(t, pod.SecurityContext)
+	assert.Equal(t, chownContainerName, pod.InitContainers[0].Name)
+	assert.Equal(t, PodSecurityContext(), pod.Containers[0].SecurityContext)
+
+	// baseline mons are never privileged
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardBaseline
+	assert.NoError(t, c.validateSpec())
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	pod = d.Spec.Template.Spec
+	assert.Nil(t, pod.SecurityContext)
+	for _, container := range append(pod.InitContainers, pod.Containers...) {
+		assert.False(t, *container.SecurityContext.Privileged, container.Name)
+	}
+
+	// restricted mons run as the ceph user without capabilities and without host volumes
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	pod = d.Spec.Template.Spec
+	assert.Equal(t, cephUserID, *pod.SecurityContext.RunAsUser)
+	assert.Equal(t, cephUserID, *pod.SecurityContext.RunAsGroup)
+	assert.Equal(t, cephUserID, *pod.SecurityContext.FSGroup)
+	assert.True(t, *pod.SecurityContext.RunAsNonRoot)
+	assert.Equal(t, seccompRuntimeDefault, d.Spec.Template.Annotations[seccompPodAnnotation])
+	for _, container := range append(pod.InitContainers, pod.Containers...) {
+		assert.NotEqual(t, chownContainerName, container.Name)
+		sc := container.SecurityContext
+		assert.False(t, *sc.Privileged, container.Name)
+		assert.False(t, *sc.AllowPrivilegeEscalation, container.Name)
+		assert.True(t, *sc.RunAsNonRoot, container.Name)
+		assert.Equal(t, []v1.Capability{"ALL"}, sc.Capabilities.Drop, container.Name)
+	}
+	for _, volume := range pod.Volumes {
+		assert.Nil(t, volume.HostPath, volume.Name)
+	}
+}
+
+func TestValidatePodSecurityStandard(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	assert.NoError(t, c.validatePodSecurityStandard())
+
+	c.spec.Mon.PodSecurityStandard = "privileged"
+	assert.Error(t, c.validateSpec())
+
+	// restricted mons cannot store their data on the host
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	assert.Error(t, c.validatePodSecurityStandard())
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+	assert.NoError(t, c.validatePodSecurityStandard())
+
+	// neither standard allows host networking
+	c.HostNetwork = true
+	assert.Error(t, c.validatePodSecurityStandard())
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardBaseline
+	assert.Error(t, c.validatePodSecurityStandard())
+}
+
+func TestStartRestrictedMonOnHostPath(t *testing.T) {
+	clientset := testop.New(1)
+	c := New(&clusterd.Context{Clientset: clientset}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	m := testGenMonConfig("a")
+	assert.NoError(t, c.startMon(m, "node0"))
+
+	// an existing mon on the host path is not moved to a pvc when the standard changes
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+	err := c.startMon(m, "node0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stores its data on the host")
+}
+
+func TestMonRunAsNonRoot(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// the mons run as root by default and keep a store owned by root
+	pod := c.makeDeployment(testGenMonConfig("a"), "node0").Spec.Template.Spec
+	assert.Nil(t, pod.Containers[0].SecurityContext.RunAsUser)
+	assert.Contains(t, pod.InitContainers[0].Args, "ceph:ceph")
+	assert.Contains(t, pod.Containers[0].Args, "--setuser-match-path=/var/lib/ceph/mon/ceph-a/store.db")
+
+	// the mon containers run as the ceph user after the data dir is chowned by root
+	runAsNonRoot := true
+	c.spec.Mon.RunAsNonRoot = &runAsNonRoot
+	pod = c.makeDeployment(testGenMonConfig("a"), "node0").Spec.Template.Spec
+	chown := pod.InitContainers[0]
+	assert.Equal(t, chownContainerName, chown.Name)
+	assert.Equal(t, []string{"--verbose", "--recursive", "167:167", "/var/lib/ceph/mon/ceph-a", "/var/log/ceph"}, chown.Args)
+	assert.Equal(t, int64(0), *chown.SecurityContext.RunAsUser)
+	assert.False(t, *chown.SecurityContext.RunAsNonRoot)
+	for _, container := range append(pod.InitContainers[1:], pod.Containers...) {
+		sc := container.SecurityContext
+		assert.Equal(t, cephUserID, *sc.RunAsUser, container.Name)
+		assert.Equal(t, cephUserID, *sc.RunAsGroup, container.Name)
+		assert.True(t, *sc.RunAsNonRoot, container.Name)
+		assert.False(t, *sc.Privileged, container.Name)
+	}
+	for _, arg := range pod.Containers[0].Args {
+		assert.NotContains(t, arg, "setuser-match-path")
+	}
+
+	// restricted mons do not need the chown
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	pod = c.makeDeployment(testGenMonConfig("a"), "node0").Spec.Template.Spec
+	for _, container := range append(pod.InitContainers, pod.Containers...) {
+		assert.NotEqual(t, chownContainerName, container.Name)
+		assert.Equal(t, cephUserID, *container.SecurityContext.RunAsUser, container.Name)
+	}
+}
+
+func TestDefaultMonRunAsNonRoot(t *testing.T) {
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	context.RookClientset = rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: namespace}}
+	_, err := context.RookClientset.CephV1().CephClusters(namespace).Create(cluster)
+	assert.NoError(t, err)
+	newTestCluster := func(runAsNonRoot *bool) *Cluster {
+		c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+		c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+		c.spec.Mon.Count = 1
+		c.spec.Mon.RunAsNonRoot = runAsNonRoot
+		return c
+	}
+
+	// the mons of a new cluster run as the ceph user and the default is saved in the cluster CR
+	c := newTestCluster(nil)
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	assert.True(t, *c.spec.Mon.RunAsNonRoot)
+	cluster, err = context.RookClientset.CephV1().CephClusters(namespace).Get("rook-ceph", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, *cluster.Spec.Mon.RunAsNonRoot)
+	d, err := context.Clientset.AppsV1().Deployments(namespace).Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, cephUserID, *d.Spec.Template.Spec.Containers[0].SecurityContext.RunAsUser)
+
+	// the mons of an existing cluster keep running as root if the setting is not set
+	c = newTestCluster(nil)
+	assert.NoError(t, c.defaultMonRunAsNonRoot())
+	assert.Nil(t, c.spec.Mon.RunAsNonRoot)
+	assert.False(t, monRunAsNonRoot(c.spec.Mon))
+
+	// the setting of a new cluster is kept if it is set
+	context = newTestStartCluster(namespace)
+	runAsRoot := false
+	c = newTestCluster(&runAsRoot)
+	assert.NoError(t, c.defaultMonRunAsNonRoot())
+	assert.False(t, *c.spec.Mon.RunAsNonRoot)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+var (
+	// MonConnectionFailureThreshold is the number of consecutive health checks a mon endpoint must
+	// fail to accept a connection before the mon is considered unreachable
+	MonConnectionFailureThreshold = 2
+	// monProbeTimeout is the time to wait for a mon to accept a connection
+	monProbeTimeout = 5 * time.Second
+	// probeMonEndpoint checks whether a mon endpoint accepts connections. It is a variable so the
+	// unit tests can avoid connecting to the fake mon endpoints.
+	probeMonEndpoint = dialMonEndpoint
+)
+
+// dialMonEndpoint opens and closes a TCP connection to the mon endpoint
+func dialMonEndpoint(ctx context.Context, endpoint string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// detectMonConnectionFailure probes the mon endpoint and tracks the number of consecutive failed
+// probes in consecutiveFailures. It returns true when the number of consecutive failures exceeds the
+// threshold.
+func detectMonConnectionFailure(ctx context.Context, endpoint string, consecutiveFailures *int, threshold int) bool {
+	if err := probeMonEndpoint(ctx, endpoint); err != nil {
+		*consecutiveFailures++
+		logger.Debugf("failed to connect to mon endpoint %s (%d consecutive failures). %+v", endpoint, *consecutiveFailures, err)
+	} else {
+		*consecutiveFailures = 0
+	}
+	return *consecutiveFailures > threshold
+}
+
+// probeMons connects to each mon directly, which detects mons that cannot be reached faster than the
+// ceph commands that have to wait for a timeout. An error is returned if none of the mons can be
+// reached.
+func (c *Cluster) probeMons() error {
+	unreachable := []string{}
+	for name, mon := range c.ClusterInfo.Monitors {
+		failures := c.monProbeFailures[name]
+		ctx, cancel := context.WithTimeout(context.Background(), monProbeTimeout)
+		failed := detectMonConnectionFailure(ctx, mon.Endpoint, &failures, MonConnectionFailureThreshold)
+		cancel()
+		c.monProbeFailures[name] = failures
+		if failed {
+			logger.Warningf("mon %s at %s cannot be reached (%d consecutive failures)", name, mon.Endpoint, failures)
+			unreachable = append(unreachable, name)
+		}
+	}
+
+	// forget about mons that were removed
+	for name := range c.monProbeFailures {
+		if _, ok := c.ClusterInfo.Monitors[name]; !ok {
+			delete(c.monProbeFailures, name)
+		}
+	}
+
+	if len(unreachable) > 0 && len(unreachable) == len(c.ClusterInfo.Monitors) {
+		return fmt.Errorf("none of the mons %v can be reached", unreachable)
+	}
+	return nil
+}
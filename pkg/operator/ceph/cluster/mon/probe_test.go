@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	// the mon endpoints in the unit tests are not real, so don't wait for connections to them
+	probeMonEndpoint = func(ctx context.Context, endpoint string) error { return nil }
+}
+
+func TestDetectMonConnectionFailure(t *testing.T) {
+	probeMonEndpoint = dialMonEndpoint
+	defer func() { probeMonEndpoint = func(ctx context.Context, endpoint string) error { return nil } }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	endpoint := listener.Addr().String()
+
+	// a successful probe resets the failure count
+	failures := 1
+	assert.False(t, detectMonConnectionFailure(context.Background(), endpoint, &failures, 1))
+	assert.Equal(t, 0, failures)
+
+	// the failure is detected when the threshold is exceeded
+	listener.Close()
+	assert.False(t, detectMonConnectionFailure(context.Background(), endpoint, &failures, 1))
+	assert.Equal(t, 1, failures)
+	assert.True(t, detectMonConnectionFailure(context.Background(), endpoint, &failures, 1))
+	assert.Equal(t, 2, failures)
+}
+
+func TestProbeMons(t *testing.T) {
+	reachable := map[string]bool{}
+	probeMonEndpoint = func(ctx context.Context, endpoint string) error {
+		if !reachable[endpoint] {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+	defer func() { probeMonEndpoint = func(ctx context.Context, endpoint string) error { return nil } }()
+
+	c := newCluster(&clusterd.Context{Clientset: test.New(1)}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(2)
+	reachable[c.ClusterInfo.Monitors["a"].Endpoint] = true
+
+	// one unreachable mon is not an error
+	for i := 0; i <= MonConnectionFailureThreshold; i++ {
+		assert.NoError(t, c.probeMons())
+	}
+	assert.Equal(t, 0, c.monProbeFailures["a"])
+	assert.Equal(t, MonConnectionFailureThreshold+1, c.monProbeFailures["b"])
+
+	// all mons are unreachable once each of them exceeds the threshold
+	reachable = map[string]bool{}
+	for i := 0; i < MonConnectionFailureThreshold; i++ {
+		assert.NoError(t, c.probeMons())
+	}
+	assert.Error(t, c.probeMons())
+
+	// removed mons are forgotten
+	delete(c.ClusterInfo.Monitors, "b")
+	c.probeMons()
+	assert.NotContains(t, c.monProbeFailures, "b")
+}
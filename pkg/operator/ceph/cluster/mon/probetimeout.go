@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	monProbeTimeoutKey = "mon_probe_timeout"
+	// the range of the probe timeout that can be set in the mon spec
+	minMonProbeTimeoutMs = 100
+	maxMonProbeTimeoutMs = 30000
+)
+
+// validateMonProbeTimeout checks that the probe timeout of the mon spec is in the allowed range. A
+// timeout of zero keeps the ceph default.
+func validateMonProbeTimeout(timeoutMs int) error {
+	if timeoutMs == 0 {
+		return nil
+	}
+	if timeoutMs < minMonProbeTimeoutMs || timeoutMs > maxMonProbeTimeoutMs {
+		return fmt.Errorf("invalid mon probe timeout %dms. the timeout must be between %dms and %dms", timeoutMs, minMonProbeTimeoutMs, maxMonProbeTimeoutMs)
+	}
+	return nil
+}
+
+// applyMonProbeTimeout sets the probe timeout of the mon spec in the mon config. Ceph expects the
+// timeout in seconds.
+func (c *Cluster) applyMonProbeTimeout() error {
+	if c.spec.Mon.ProbeTimeoutMs == 0 {
+		return nil
+	}
+	value := strconv.FormatFloat(float64(c.spec.Mon.ProbeTimeoutMs)/1000, 'f', -1, 64)
+	if err := client.MonSetConfig(c.context, c.ClusterInfo.Name, monProbeTimeoutKey, value); err != nil {
+		return err
+	}
+	logger.Infof("set mon %s=%s", monProbeTimeoutKey, value)
+	return nil
+}
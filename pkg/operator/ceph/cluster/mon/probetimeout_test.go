@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestValidateMonProbeTimeout(t *testing.T) {
+	assert.NoError(t, validateMonProbeTimeout(0))
+	assert.NoError(t, validateMonProbeTimeout(100))
+	assert.NoError(t, validateMonProbeTimeout(2000))
+	assert.NoError(t, validateMonProbeTimeout(30000))
+	assert.Error(t, validateMonProbeTimeout(99))
+	assert.Error(t, validateMonProbeTimeout(30001))
+	assert.Error(t, validateMonProbeTimeout(-1))
+
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	c.spec.Mon.ProbeTimeoutMs = 50
+	assert.Error(t, c.validateSpec())
+	c.spec.Mon.ProbeTimeoutMs = 5000
+	assert.NoError(t, c.validateSpec())
+}
+
+func TestApplyMonProbeTimeout(t *testing.T) {
+	var setArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				setArgs = args[:5]
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// nothing is set without a timeout in the spec
+	assert.NoError(t, c.applyMonProbeTimeout())
+	assert.Nil(t, setArgs)
+
+	// the timeout is set in seconds
+	c.spec.Mon.ProbeTimeoutMs = 5000
+	assert.NoError(t, c.applyMonProbeTimeout())
+	assert.Equal(t, []string{"config", "set", "mon", "mon_probe_timeout", "5"}, setArgs)
+	c.spec.Mon.ProbeTimeoutMs = 250
+	assert.NoError(t, c.applyMonProbeTimeout())
+	assert.Equal(t, []string{"config", "set", "mon", "mon_probe_timeout", "0.25"}, setArgs)
+}
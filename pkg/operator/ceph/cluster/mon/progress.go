@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// the phases of Cluster.Start in the order they complete
+const (
+	progressPhaseValidation       = "validation"
+	progressPhaseClusterInfo      = "cluster_info"
+	progressPhaseMonCount         = "mon_count"
+	progressPhaseMons             = "mons"
+	progressPhaseDataDirMigration = "data_dir_migration"
+)
+
+var reconcileProgressPhases = []string{
+	progressPhaseValidation,
+	progressPhaseClusterInfo,
+	progressPhaseMonCount,
+	progressPhaseMons,
+	progressPhaseDataDirMigration,
+}
+
+// ReconcileProgress is logged when a phase of the mon reconcile completes so that the progress of a
+// long reconcile can be followed in the operator log
+type ReconcileProgress struct {
+	// Phase is the phase that completed
+	Phase string `json:"phase"`
+	// Step is the number of the phase that completed, starting at 1
+	Step int `json:"step"`
+	// TotalSteps is the number of phases of the reconcile
+	TotalSteps int `json:"totalSteps"`
+	// ElapsedMs is the time since the reconcile started in milliseconds
+	ElapsedMs int64 `json:"elapsedMs"`
+}
+
+// logReconcileProgress writes the progress to the operator log. It is a variable so the unit tests
+// can check the progress that is logged.
+var logReconcileProgress = func(progress ReconcileProgress) {
+	// the json entry is for log aggregators that track the progress
+	entry, _ := json.Marshal(progress)
+	logger.Infof("mon reconcile completed phase %s (step %d of %d) after %dms. %s",
+		progress.Phase, progress.Step, progress.TotalSteps, progress.ElapsedMs, string(entry))
+}
+
+// reportReconcileProgress logs that a phase of the reconcile that started at the given time completed
+func reportReconcileProgress(phase string, start time.Time) {
+	step := 0
+	for i, p := range reconcileProgressPhases {
+		if p == phase {
+			step = i + 1
+			break
+		}
+	}
+	logReconcileProgress(ReconcileProgress{
+		Phase:      phase,
+		Step:       step,
+		TotalSteps: len(reconcileProgressPhases),
+		ElapsedMs:  int64(time.Since(start) / time.Millisecond),
+	})
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"testing"
+
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestReconcileProgress(t *testing.T) {
+	original := logReconcileProgress
+	defer func() { logReconcileProgress = original }()
+	logged := []ReconcileProgress{}
+	logReconcileProgress = func(progress ReconcileProgress) {
+		logged = append(logged, progress)
+	}
+
+	namespace := "ns"
+	c := newCluster(newTestStartCluster(namespace), namespace, false, true, v1.ResourceRequirements{})
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+
+	// each phase is logged in order when it completes
+	assert.Equal(t, 5, len(logged))
+	phases := []string{}
+	for i, progress := range logged {
+		phases = append(phases, progress.Phase)
+		assert.Equal(t, i+1, progress.Step)
+		assert.Equal(t, 5, progress.TotalSteps)
+		if i > 0 {
+			assert.True(t, progress.ElapsedMs >= logged[i-1].ElapsedMs)
+		}
+	}
+	assert.Equal(t, []string{"validation", "cluster_info", "mon_count", "mons", "data_dir_migration"}, phases)
+
+	// the phases after a failure are not logged
+	logged = []ReconcileProgress{}
+	c = newCluster(newTestStartCluster(namespace), namespace, false, true, v1.ResourceRequirements{})
+	c.spec.Mon.Count = 0
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.Error(t, err)
+	assert.Equal(t, 2, len(logged))
+	assert.Equal(t, "cluster_info", logged[1].Phase)
+
+	// the structured entry has all the fields
+	entry, err := json.Marshal(ReconcileProgress{Phase: "mons", Step: 4, TotalSteps: 5, ElapsedMs: 1500})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"phase":"mons","step":4,"totalSteps":5,"elapsedMs":1500}`, string(entry))
+}
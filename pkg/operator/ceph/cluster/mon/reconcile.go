@@ -0,0 +1,242 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"reflect"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// monSpecChanges are the parts of the mon settings that differ between two cluster specs
+type monSpecChanges struct {
+	// the number of mons changed
+	count bool
+	// the mon deployments must be updated
+	deployments bool
+	// the endpoints saved in the endpoint configmap must be updated
+	endpoints bool
+	// the debug log levels in the mon config must be updated
+	debugSections bool
+	// the rocksdb cache size in the mon config must be updated and the mons restarted
+	rocksDBCacheSize bool
+	// the probe timeout in the mon config must be updated
+	probeTimeout bool
+}
+
+// diffMonSpec computes what changed in the mon settings between the old and new cluster spec
+func diffMonSpec(oldSpec, newSpec cephv1.ClusterSpec) monSpecChanges {
+	oldMon, newMon := oldSpec.Mon, newSpec.Mon
+	changes := monSpecChanges{
+		count:            oldMon.Count != newMon.Count || oldMon.PreferredCount != newMon.PreferredCount,
+		endpoints:        oldMon.ShuffleEndpoints != newMon.ShuffleEndpoints,
+		debugSections:    !reflect.DeepEqual(oldMon.DebugSections, newMon.DebugSections),
+		rocksDBCacheSize: oldMon.RocksDBCacheSizeMB != newMon.RocksDBCacheSizeMB,
+		probeTimeout:     oldMon.ProbeTimeoutMs != newMon.ProbeTimeoutMs,
+	}
+
+	changes.deployments = unhandledMonSpecChanged(oldMon, newMon) ||
+		oldMon.AllowMultiplePerNode != newMon.AllowMultiplePerNode ||
+		oldMon.CPUSet != newMon.CPUSet ||
+		oldMon.CheckStoreOnStart != newMon.CheckStoreOnStart ||
+		oldMon.RecoverFSIDMismatch != newMon.RecoverFSIDMismatch ||
+		oldMon.QuorumLivenessTimeoutMinutes != newMon.QuorumLivenessTimeoutMinutes ||
+		oldMon.PodSecurityStandard != newMon.PodSecurityStandard ||
+		monRunAsNonRoot(oldMon) != monRunAsNonRoot(newMon) ||
+		!reflect.DeepEqual(oldMon.VolumeClaimTemplate, newMon.VolumeClaimTemplate) ||
+		!reflect.DeepEqual(oldMon.DNSPolicy, newMon.DNSPolicy) ||
+		!reflect.DeepEqual(oldMon.DNSConfig, newMon.DNSConfig) ||
+		!reflect.DeepEqual(cephv1.GetMonResources(oldSpec.Resources), cephv1.GetMonResources(newSpec.Resources)) ||
+		!reflect.DeepEqual(cephv1.GetMonAnnotations(oldSpec.Annotations), cephv1.GetMonAnnotations(newSpec.Annotations)) ||
+		!reflect.DeepEqual(cephv1.GetMonPlacement(oldSpec.Placement), cephv1.GetMonPlacement(newSpec.Placement))
+
+	return changes
+}
+
+// unhandledMonSpecChanged returns whether a mon setting changed that diffMonSpec does not reconcile
+// with its own step, in which case the mons are started again to apply it. The settings that are
+// only read from the spec by the health checks (e.g. the paxos latency threshold) do not need a
+// reconcile.
+func unhandledMonSpecChanged(oldMon, newMon cephv1.MonSpec) bool {
+	ignore := func(m cephv1.MonSpec) cephv1.MonSpec {
+		// the settings with their own reconcile step
+		m.Count = 0
+		m.PreferredCount = 0
+		m.ShuffleEndpoints = false
+		m.DebugSections = nil
+		m.RocksDBCacheSizeMB = 0
+		m.ProbeTimeoutMs = 0
+		// unset is the same as disabled for an existing cluster
+		m.RunAsNonRoot = nil
+		// the settings read by the health checks or only when the mons are updated or their count changes
+		m.PaxosLatencyThresholdMs = 0
+		m.BackupBeforeUpgrade = false
+		m.Autopilot = false
+		m.VolumeExpansionMinFreePercent = 0
+		m.FailoverTimeoutMinutes = 0
+		m.RebalanceOnZoneRecovery = false
+		m.MaxMonChangeRate = 0
+		m.AllowEvenCount = false
+		m.DisableReplicaCorrection = false
+		return m
+	}
+	return !reflect.DeepEqual(ignore(oldMon), ignore(newMon))
+}
+
+// OnlyMonSpecChanged returns whether the only differences between the cluster specs are in the mon
+// settings, in which case the change can be applied with ReconcileSpec instead of a full
+// orchestration of the cluster.
+func OnlyMonSpecChanged(oldSpec, newSpec cephv1.ClusterSpec) bool {
+	return reflect.DeepEqual(withoutMonSettings(oldSpec), withoutMonSettings(newSpec))
+}
+
+// withoutMonSettings returns a copy of the cluster spec with the settings that only apply to the mons removed
+func withoutMonSettings(spec cephv1.ClusterSpec) *cephv1.ClusterSpec {
+	s := spec.DeepCopy()
+	s.Mon = cephv1.MonSpec{}
+	delete(s.Resources, cephv1.ResourcesKeyMon)
+	delete(s.Annotations, cephv1.KeyMon)
+	delete(s.Placement, cephv1.KeyMon)
+	// an empty map is the same as no map
+	if len(s.Resources) == 0 {
+		s.Resources = nil
+	}
+	if len(s.Annotations) == 0 {
+		s.Annotations = nil
+	}
+	if len(s.Placement) == 0 {
+		s.Placement = nil
+	}
+	return s
+}
+
+// pausedSpecChange is a change to the mon settings that was not applied since the mon reconcile was
+// paused
+type pausedSpecChange struct {
+	oldSpec cephv1.ClusterSpec
+	newSpec cephv1.ClusterSpec
+}
+
+// ReconcileSpec applies a change to the mon settings of the cluster spec. Only the reconcile steps
+// affected by the change are run. If the mon reconcile is paused, the change is applied by the first
+// health check after the reconcile is resumed.
+func (c *Cluster) ReconcileSpec(oldSpec, newSpec cephv1.ClusterSpec) error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	if c.monReconcilePaused() {
+		msg := fmt.Sprintf("the mon reconcile is paused by the %q annotation of the cluster. the mon settings are applied when the reconcile is resumed", monReconcilePausedAnnotation)
+		logger.Warningf(msg)
+		c.recordEvent(v1.EventTypeWarning, "MonReconcilePaused", msg)
+		// the changes made while paused are applied together
+		if c.pausedSpecChange == nil {
+			c.pausedSpecChange = &pausedSpecChange{oldSpec: oldSpec}
+		}
+		c.pausedSpecChange.newSpec = newSpec
+		return nil
+	}
+	return c.reconcileSpec(oldSpec, newSpec)
+}
+
+// reconcilePausedSpecChange applies the change to the mon settings made while the mon reconcile was
+// paused
+func (c *Cluster) reconcilePausedSpecChange() error {
+	if c.pausedSpecChange == nil {
+		return nil
+	}
+	change := c.pausedSpecChange
+	c.pausedSpecChange = nil
+	logger.Infof("applying the mon settings changed while the mon reconcile was paused")
+	return c.reconcileSpec(change.oldSpec, change.newSpec)
+}
+
+// validateNewSpec validates the new spec before it replaces the spec of the mons, so that a rejected
+// spec is not used by the health checks. The validation runs on a cluster with only the settings the
+// validation reads.
+func (c *Cluster) validateNewSpec(newSpec cephv1.ClusterSpec, countChanged bool) error {
+	candidate := &Cluster{ClusterInfo: c.ClusterInfo, spec: newSpec, HostNetwork: c.HostNetwork}
+	if err := candidate.validateSpec(); err != nil {
+		return err
+	}
+	if countChanged {
+		return candidate.validateMonCount()
+	}
+	return nil
+}
+
+func (c *Cluster) reconcileSpec(oldSpec, newSpec cephv1.ClusterSpec) error {
+	changes := diffMonSpec(oldSpec, newSpec)
+	if err := c.validateNewSpec(newSpec, changes.count); err != nil {
+		return err
+	}
+	c.spec = newSpec
+
+	// the sections of the old spec are reset if they were removed, even if they were applied before
+	// the operator restarted
+	for section, level := range oldSpec.Mon.DebugSections {
+		if _, ok := c.monDebugSections[section]; !ok {
+			c.monDebugSections[section] = level
+		}
+	}
+
+	if changes.count || changes.deployments {
+		logger.Infof("mon settings changed, updating the mons")
+		targetCount, msg, err := c.getTargetMonCount()
+		if err != nil {
+			return fmt.Errorf("failed to get target mon count. %+v", err)
+		}
+		logger.Infof(msg)
+		targetCount = c.limitMonCountChange(len(c.ClusterInfo.Monitors), targetCount)
+		// starting the mons also saves the endpoints
+		return c.startMons(targetCount)
+	}
+
+	if changes.endpoints {
+		logger.Infof("mon endpoint settings changed, saving the mon endpoints")
+		if _, err := c.saveMonConfig(); err != nil {
+			return fmt.Errorf("failed to save mon endpoints. %+v", err)
+		}
+	}
+
+	if changes.debugSections {
+		logger.Infof("mon debug sections changed, updating the mon config")
+		if err := c.applyMonDebugSections(); err != nil {
+			return fmt.Errorf("failed to apply the mon debug sections. %+v", err)
+		}
+	}
+
+	if changes.rocksDBCacheSize && newSpec.Mon.RocksDBCacheSizeMB != 0 {
+		logger.Infof("mon rocksdb cache size changed, restarting the mons to apply it")
+		if err := c.applyRocksDBCacheSize(); err != nil {
+			return fmt.Errorf("failed to apply the mon rocksdb cache size. %+v", err)
+		}
+		if err := c.rollMons(); err != nil {
+			return fmt.Errorf("failed to restart the mons to apply the rocksdb cache size. %+v", err)
+		}
+	}
+
+	if changes.probeTimeout {
+		logger.Infof("mon probe timeout changed, updating the mon config")
+		if err := c.applyMonProbeTimeout(); err != nil {
+			return fmt.Errorf("failed to apply the mon probe timeout. %+v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDiffMonSpec(t *testing.T) {
+	oldSpec := cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 3}}
+
+	// no changes
+	assert.Equal(t, monSpecChanges{}, diffMonSpec(oldSpec, *oldSpec.DeepCopy()))
+
+	// count
+	newSpec := *oldSpec.DeepCopy()
+	newSpec.Mon.PreferredCount = 5
+	assert.Equal(t, monSpecChanges{count: true}, diffMonSpec(oldSpec, newSpec))
+
+	// endpoints
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.ShuffleEndpoints = true
+	assert.Equal(t, monSpecChanges{endpoints: true}, diffMonSpec(oldSpec, newSpec))
+
+	// resources
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Resources = rookalpha.ResourceSpec{"mon": v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(2, resource.DecimalSI)},
+	}}
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// annotations, including the ones for all daemons
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Annotations = rookalpha.AnnotationsSpec{"all": {"key": "value"}}
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// placement
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Placement = rookalpha.PlacementSpec{"mon": rookalpha.Placement{Tolerations: []v1.Toleration{{Key: "mon"}}}}
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// dns settings of the pods
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.DNSConfig = &v1.PodDNSConfig{Searches: []string{"example.com"}}
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// the quorum liveness probe of the mon containers
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.QuorumLivenessTimeoutMinutes = 5
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// the pod security standard of the mon pods
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.PodSecurityStandard = PodSecurityStandardBaseline
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// the user of the mon containers
+	newSpec = *oldSpec.DeepCopy()
+	runAsNonRoot := true
+	newSpec.Mon.RunAsNonRoot = &runAsNonRoot
+	assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec))
+
+	// debug sections
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.DebugSections = map[string]int{"paxos": 10}
+	assert.Equal(t, monSpecChanges{debugSections: true}, diffMonSpec(oldSpec, newSpec))
+
+	// rocksdb cache size
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.RocksDBCacheSizeMB = 512
+	assert.Equal(t, monSpecChanges{rocksDBCacheSize: true}, diffMonSpec(oldSpec, newSpec))
+
+	// probe timeout
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.ProbeTimeoutMs = 5000
+	assert.Equal(t, monSpecChanges{probeTimeout: true}, diffMonSpec(oldSpec, newSpec))
+
+	// settings that are only read by the health checks
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.PaxosLatencyThresholdMs = 100
+	assert.Equal(t, monSpecChanges{}, diffMonSpec(oldSpec, newSpec))
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.FailoverTimeoutMinutes = 20
+	assert.Equal(t, monSpecChanges{}, diffMonSpec(oldSpec, newSpec))
+
+	// the mons are started again for the settings without their own reconcile step
+	for _, update := range []func(*cephv1.MonSpec){
+		func(m *cephv1.MonSpec) { m.SeedMonHosts = []string{"10.0.0.1:6789"} },
+		func(m *cephv1.MonSpec) { m.HardwareClassWeights = map[string]int{"ssd": 10} },
+		func(m *cephv1.MonSpec) { m.ArbiterZone = "zone-c" },
+		func(m *cephv1.MonSpec) { m.FailoverHeadroomNodes = 1 },
+		func(m *cephv1.MonSpec) { m.FailoverHeadroomPercent = 10 },
+	} {
+		newSpec = *oldSpec.DeepCopy()
+		update(&newSpec.Mon)
+		assert.Equal(t, monSpecChanges{deployments: true}, diffMonSpec(oldSpec, newSpec), newSpec.Mon)
+	}
+}
+
+func TestOnlyMonSpecChanged(t *testing.T) {
+	oldSpec := cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 3}, DataDirHostPath: "/var/lib/rook"}
+
+	newSpec := *oldSpec.DeepCopy()
+	newSpec.Mon.Count = 5
+	newSpec.Resources = rookalpha.ResourceSpec{"mon": v1.ResourceRequirements{}}
+	newSpec.Annotations = rookalpha.AnnotationsSpec{"mon": {"key": "value"}}
+	assert.True(t, OnlyMonSpecChanged(oldSpec, newSpec))
+
+	// settings of other daemons
+	newSpec.Resources["mgr"] = v1.ResourceRequirements{}
+	assert.False(t, OnlyMonSpecChanged(oldSpec, newSpec))
+
+	// settings for all daemons
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Annotations = rookalpha.AnnotationsSpec{"all": {"key": "value"}}
+	assert.False(t, OnlyMonSpecChanged(oldSpec, newSpec))
+
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.DataDirHostPath = "/var/lib/other"
+	assert.False(t, OnlyMonSpecChanged(oldSpec, newSpec))
+}
+
+func TestReconcileSpec(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.spec.Mon.Count = 1
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+
+	getEndpointsSeed := func() string {
+		cm, err := context.Clientset.CoreV1().ConfigMaps(namespace).Get(EndpointConfigMapName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return cm.Data[ShuffleSeedKey]
+	}
+
+	// only the endpoints are saved when shuffling is enabled
+	oldSpec := c.spec
+	newSpec := *oldSpec.DeepCopy()
+	newSpec.Mon.ShuffleEndpoints = true
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.Equal(t, "0", getEndpointsSeed())
+	assert.Equal(t, 0, len(testopk8s.DeploymentNamesUpdated(deploymentsUpdated)))
+
+	// nothing is done for settings used by the health checks
+	oldSpec = newSpec
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.PaxosLatencyThresholdMs = 100
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.Equal(t, "0", getEndpointsSeed())
+	assert.Equal(t, 0, len(testopk8s.DeploymentNamesUpdated(deploymentsUpdated)))
+	assert.Equal(t, 100, c.spec.Mon.PaxosLatencyThresholdMs)
+
+	// the deployments are updated when the resources change
+	oldSpec = newSpec
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Resources = rookalpha.ResourceSpec{"mon": v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(2, resource.DecimalSI)},
+	}}
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.ElementsMatch(t, []string{"rook-ceph-mon-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+
+	// the liveness probe is added to the deployments when the quorum liveness timeout is set
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+	oldSpec = newSpec
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.QuorumLivenessTimeoutMinutes = 5
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.ElementsMatch(t, []string{"rook-ceph-mon-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+	assert.NotNil(t, (*deploymentsUpdated)[0].Spec.Template.Spec.Containers[0].LivenessProbe)
+
+	// invalid settings are rejected
+	oldSpec = newSpec
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.CPUSet = "a"
+	assert.Error(t, c.ReconcileSpec(oldSpec, newSpec))
+	// the rejected settings are not used by the mons
+	assert.Equal(t, oldSpec, c.spec)
+
+	newSpec = *oldSpec.DeepCopy()
+	newSpec.Mon.Count = 4
+	assert.Error(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.Equal(t, oldSpec.Mon.Count, c.spec.Mon.Count)
+}
+
+func TestReconcileSpecPodSecurityStandard(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.spec.Mon.Count = 1
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+	c.spec.Mon.PodSecurityStandard = PodSecurityStandardBaseline
+	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+
+	// the mons run as the ceph user without the chown init container once they are restricted
+	oldSpec := c.spec
+	newSpec := *oldSpec.DeepCopy()
+	newSpec.Mon.PodSecurityStandard = PodSecurityStandardRestricted
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.ElementsMatch(t, []string{"rook-ceph-mon-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+	podSpec := (*deploymentsUpdated)[0].Spec.Template.Spec
+	assert.NotNil(t, podSpec.SecurityContext)
+	assert.True(t, *podSpec.SecurityContext.RunAsNonRoot)
+	for _, container := range podSpec.InitContainers {
+		assert.NotEqual(t, chownContainerName, container.Name)
+	}
+}
+
+func TestReconcileSpecPaused(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	context.RookClientset = rookfake.NewSimpleClientset()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: namespace}}
+	_, err := context.RookClientset.CephV1().CephClusters(namespace).Create(cluster)
+	assert.NoError(t, err)
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+	c.ownerRef = metav1.OwnerReference{Name: "rook-ceph"}
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	c.spec.Mon.Count = 1
+	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NoError(t, err)
+	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
+
+	setPaused := func(value string) {
+		cluster, err := context.RookClientset.CephV1().CephClusters(namespace).Get("rook-ceph", metav1.GetOptions{})
+		assert.NoError(t, err)
+		cluster.Annotations = map[string]string{monReconcilePausedAnnotation: value}
+		_, err = context.RookClientset.CephV1().CephClusters(namespace).Update(cluster)
+		assert.NoError(t, err)
+	}
+
+	// the mons are not updated while paused
+	setPaused("true")
+	oldSpec := c.spec
+	newSpec := *oldSpec.DeepCopy()
+	newSpec.Resources = rookalpha.ResourceSpec{"mon": v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(2, resource.DecimalSI)},
+	}}
+	assert.NoError(t, c.ReconcileSpec(oldSpec, newSpec))
+	assert.Equal(t, 0, len(testopk8s.DeploymentNamesUpdated(deploymentsUpdated)))
+	assert.Equal(t, oldSpec, c.spec)
+	assert.Contains(t, <-recorder.Events, "Warning MonReconcilePaused")
+
+	// the changes made while paused are applied together
+	pausedSpec := newSpec
+	newSpec = *pausedSpec.DeepCopy()
+	newSpec.Mon.DebugSections = map[string]int{"paxos": 10}
+	assert.NoError(t, c.ReconcileSpec(pausedSpec, newSpec))
+	assert.Equal(t, oldSpec, c.pausedSpecChange.oldSpec)
+	assert.Equal(t, newSpec, c.pausedSpecChange.newSpec)
+
+	// the health check still skips the mons while paused
+	assert.NoError(t, c.checkHealth())
+	assert.Equal(t, 0, len(testopk8s.DeploymentNamesUpdated(deploymentsUpdated)))
+
+	// the first health check after the reconcile is resumed applies the changes
+	setPaused("false")
+	c.checkHealth()
+	assert.Nil(t, c.pausedSpecChange)
+	assert.Equal(t, newSpec, c.spec)
+	assert.ElementsMatch(t, []string{"rook-ceph-mon-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+var (
+	// MaxReplacementsBeforeAlert is the number of times a mon may be replaced within the replacement
+	// loop window before the operator stops replacing it
+	MaxReplacementsBeforeAlert = 3
+	// ReplacementLoopWindow is the time in which the replacements of a mon are counted
+	ReplacementLoopWindow = time.Hour
+)
+
+// recordMonReplacement remembers that the operator started to replace the mon
+func (c *Cluster) recordMonReplacement(name string) {
+	c.monReplacements[name] = append(recentMonReplacements(c.monReplacements[name]), time.Now())
+}
+
+// inheritMonReplacements passes the replacements of a mon on to the mon that replaced it. A
+// replacement that cannot join the quorum is replaced in turn, so the replacements of all the mons
+// in the chain count towards the loop.
+func (c *Cluster) inheritMonReplacements(name, newName string) {
+	c.monReplacements[newName] = c.monReplacements[name]
+	delete(c.monReplacements, name)
+}
+
+// detectMonReplacementLoop returns whether the mon was replaced more than MaxReplacementsBeforeAlert
+// times within the ReplacementLoopWindow. A mon that keeps being replaced will not get into quorum
+// by replacing it again, so the replacement is stopped and a critical event is reported. The mon is
+// replaced again when the earlier replacements are older than the window.
+func detectMonReplacementLoop(cluster *Cluster, monID string) bool {
+	replacements := recentMonReplacements(cluster.monReplacements[monID])
+	cluster.monReplacements[monID] = replacements
+	if len(replacements) <= MaxReplacementsBeforeAlert {
+		return false
+	}
+
+	msg := fmt.Sprintf("CRITICAL: mon %s was replaced %d times in the last %s. the mon will not be replaced again until the cause is fixed",
+		monID, len(replacements), ReplacementLoopWindow)
+	logger.Errorf(msg)
+	cluster.recordEvent(v1.EventTypeWarning, "MonReplacementLoop", msg)
+	return true
+}
+
+// recentMonReplacements returns the replacements within the replacement loop window
+func recentMonReplacements(replacements []time.Time) []time.Time {
+	recent := []time.Time{}
+	for _, t := range replacements {
+		if time.Since(t) <= ReplacementLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDetectMonReplacementLoop(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	defer os.RemoveAll(c.context.ConfigDir)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	// a mon that was never replaced
+	assert.False(t, detectMonReplacementLoop(c, "a"))
+
+	// the replacements up to the max are allowed
+	for i := 0; i < MaxReplacementsBeforeAlert; i++ {
+		c.recordMonReplacement("a")
+	}
+	assert.False(t, detectMonReplacementLoop(c, "a"))
+	assert.Empty(t, recorder.Events)
+
+	// one more replacement is a loop
+	c.recordMonReplacement("a")
+	assert.True(t, detectMonReplacementLoop(c, "a"))
+	assert.Contains(t, <-recorder.Events, "MonReplacementLoop")
+
+	// the replacements before the window are not counted
+	for i := range c.monReplacements["a"] {
+		c.monReplacements["a"][i] = time.Now().Add(-ReplacementLoopWindow - time.Minute)
+	}
+	assert.False(t, detectMonReplacementLoop(c, "a"))
+	assert.Empty(t, c.monReplacements["a"])
+}
+
+func TestFailoverStopsOnReplacementLoop(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	defer os.RemoveAll(c.context.ConfigDir)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	// each replacement of c is replaced in turn
+	name := "c"
+	for i := 0; i <= MaxReplacementsBeforeAlert; i++ {
+		assert.NoError(t, c.failoverMon(name))
+		assert.NotContains(t, c.ClusterInfo.Monitors, name)
+		assert.NotContains(t, c.monReplacements, name)
+		name = c.newMonConfig(c.maxMonID).DaemonName
+		assert.Contains(t, c.ClusterInfo.Monitors, name)
+		assert.Equal(t, i+1, len(c.monReplacements[name]))
+	}
+
+	// the replacement of the last mon is stopped
+	err := c.failoverMon(name)
+	assert.Error(t, err)
+	assert.Contains(t, c.ClusterInfo.Monitors, name)
+	assert.Contains(t, <-recorder.Events, "MonReplacementLoop")
+
+	// the other mons are still replaced
+	assert.NoError(t, c.failoverMon("a"))
+	assert.NotContains(t, c.ClusterInfo.Monitors, "a")
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// checkMonReplicas scales the mon deployments back to one replica if their replicas were changed
+// outside of the operator. A mon scaled to zero is out of quorum, and two pods of the same mon would
+// run with the same identity. The replicas are only reported if the correction is disabled.
+func (c *Cluster) checkMonReplicas(deployments []apps.Deployment) {
+	for i := range deployments {
+		d := &deployments[i]
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		if replicas == 1 {
+			continue
+		}
+
+		name := d.Labels[monDaemonAttr]
+		if c.spec.Mon.DisableReplicaCorrection {
+			msg := fmt.Sprintf("mon %s has %d replicas instead of 1. not correcting the replicas since the replica correction is disabled", name, replicas)
+			logger.Warningf(msg)
+			c.recordEvent(v1.EventTypeWarning, "MonReplicasChanged", msg)
+			continue
+		}
+
+		msg := fmt.Sprintf("mon %s had %d replicas instead of 1. scaling the mon back to 1 replica", name, replicas)
+		logger.Warningf(msg)
+		if err := c.scaleMonDeployment(d, 1); err != nil {
+			logger.Warningf("failed to correct the replicas of mon %s. %+v", name, err)
+			continue
+		}
+		c.recordEvent(v1.EventTypeWarning, "MonReplicasCorrected", msg)
+	}
+}
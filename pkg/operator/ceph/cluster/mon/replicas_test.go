@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+func setMonReplicas(t *testing.T, clientset kubernetes.Interface, name string, replicas int32) {
+	d, err := clientset.AppsV1().Deployments("ns").Get(resourceName(name), metav1.GetOptions{})
+	assert.NoError(t, err)
+	d.Spec.Replicas = &replicas
+	_, err = clientset.AppsV1().Deployments("ns").Update(d)
+	assert.NoError(t, err)
+}
+
+func monReplicas(t *testing.T, clientset kubernetes.Interface, name string) int32 {
+	d, err := clientset.AppsV1().Deployments("ns").Get(resourceName(name), metav1.GetOptions{})
+	assert.NoError(t, err)
+	return *d.Spec.Replicas
+}
+
+func monDeployments(t *testing.T, clientset kubernetes.Interface) []apps.Deployment {
+	deployments, err := clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{LabelSelector: MonLabelSelector("ns", "").String()})
+	assert.NoError(t, err)
+	return deployments.Items
+}
+
+func TestCheckMonReplicas(t *testing.T) {
+	clientset := test.New(3)
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: &exectest.MockExecutor{}}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	for _, name := range []string{"a", "b", "c"} {
+		assert.NoError(t, c.startMon(testGenMonConfig(name), "node0"))
+	}
+
+	// nothing to correct
+	c.checkMonReplicas(monDeployments(t, clientset))
+	assert.Empty(t, recorder.Events)
+
+	// mon a was scaled down and mon b up
+	setMonReplicas(t, clientset, "a", 0)
+	setMonReplicas(t, clientset, "b", 2)
+	c.checkMonReplicas(monDeployments(t, clientset))
+	assert.Equal(t, int32(1), monReplicas(t, clientset, "a"))
+	assert.Equal(t, int32(1), monReplicas(t, clientset, "b"))
+	assert.Equal(t, int32(1), monReplicas(t, clientset, "c"))
+	assert.Contains(t, <-recorder.Events, "MonReplicasCorrected")
+	assert.Contains(t, <-recorder.Events, "MonReplicasCorrected")
+	assert.Empty(t, recorder.Events)
+
+	// the replicas are only reported if the correction is disabled
+	c.spec.Mon.DisableReplicaCorrection = true
+	setMonReplicas(t, clientset, "a", 0)
+	c.checkMonReplicas(monDeployments(t, clientset))
+	assert.Equal(t, int32(0), monReplicas(t, clientset, "a"))
+	assert.Contains(t, <-recorder.Events, "MonReplicasChanged")
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonSummary is the state of the mons in the mon map
+type MonSummary struct {
+	Mons []MonSummaryEntry
+}
+
+// MonSummaryEntry is the state of a single mon in the mon map
+type MonSummaryEntry struct {
+	Name     string
+	Endpoint string
+	InQuorum bool
+}
+
+// NewMonSummary summarizes the mons in the mon status, sorted by name
+func NewMonSummary(status client.MonStatusResponse) *MonSummary {
+	summary := &MonSummary{Mons: []MonSummaryEntry{}}
+	for _, mon := range status.MonMap.Mons {
+		summary.Mons = append(summary.Mons, MonSummaryEntry{
+			Name: mon.Name,
+			// the mon map address has the form <ip>:<port>/<nonce>
+			Endpoint: strings.SplitN(mon.Address, "/", 2)[0],
+			InQuorum: monInQuorum(mon, status.Quorum),
+		})
+	}
+	sort.Slice(summary.Mons, func(i, j int) bool {
+		return summary.Mons[i].Name < summary.Mons[j].Name
+	})
+	return summary
+}
+
+// FormatMonPlacementTable returns a table with the node, zone, address and quorum state of each mon.
+// The zones are the zone of each node by the node name.
+func FormatMonPlacementTable(summary *MonSummary, mapping *Mapping, zones map[string]string) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MON\tNODE\tZONE\tIP\tPORT\tIN QUORUM")
+	for _, mon := range summary.Mons {
+		node, zone := "", ""
+		if mapping != nil {
+			if nodeInfo, ok := mapping.Node[mon.Name]; ok {
+				node = nodeInfo.Name
+				zone = zones[nodeInfo.Name]
+			}
+		}
+		ip, port, err := net.SplitHostPort(mon.Endpoint)
+		if err != nil {
+			ip, port = mon.Endpoint, ""
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", mon.Name, placementValue(node), placementValue(zone), placementValue(ip), placementValue(port), mon.InQuorum)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// placementValue shows a missing value in the placement table as "-"
+func placementValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// MonPlacementReport returns the placement table of the mons of the cluster
+func (c *Cluster) MonPlacementReport() (string, error) {
+	status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get mon status. %+v", err)
+	}
+
+	nodeZones, err := c.getNodeMonUsage()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the mon nodes. %+v", err)
+	}
+	zones := map[string]string{}
+	for _, zone := range nodeZones {
+		for _, nodeUsage := range zone {
+			zones[nodeUsage.Node.Name] = nodeUsage.Node.Labels[zoneLabel]
+		}
+	}
+
+	return FormatMonPlacementTable(NewMonSummary(status), c.mapping, zones), nil
+}
+
+// LoadMonPlacementReport returns the placement table of the mons of the cluster in the namespace. The
+// mons are loaded from the mon config saved by the operator and the connection config of the cluster
+// is written to the config dir of the context, so the report does not need a running operator.
+func LoadMonPlacementReport(context *clusterd.Context, namespace string) (string, error) {
+	c := New(context, namespace, "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	var err error
+	c.ClusterInfo, c.maxMonID, c.mapping, err = LoadClusterInfo(context, namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to load the mons of cluster %s. %+v", namespace, err)
+	}
+	if err := WriteConnectionConfig(context, c.ClusterInfo); err != nil {
+		return "", err
+	}
+	return c.MonPlacementReport()
+}
+
+// ZonesWithoutMons returns the failure domain zones of the nodes that have no mon, sorted by name. A
+// zone without a mon is a failure domain whose outage the mons do not survive any better. Nodes
+// without a zone label are not counted. Nil is returned if the nodes cannot be listed.
+func (c *Cluster) ZonesWithoutMons() []string {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		logger.Warningf("failed to list the nodes to find the zones without mons. %+v", err)
+		return nil
+	}
+
+	nodeZones := map[string]string{}
+	zones := map[string]bool{}
+	for _, node := range nodes.Items {
+		if zone := node.Labels[zoneLabel]; zone != "" {
+			nodeZones[node.Name] = zone
+			zones[zone] = false
+		}
+	}
+	for _, info := range c.mapping.Node {
+		if info == nil {
+			continue
+		}
+		// the zone saved in the mapping is the zone the mon was placed in
+		zone := info.Zone
+		if zone == "" {
+			zone = nodeZones[info.Name]
+		}
+		if _, ok := zones[zone]; ok {
+			zones[zone] = true
+		}
+	}
+
+	withoutMons := []string{}
+	for zone, hasMon := range zones {
+		if !hasMon {
+			withoutMons = append(withoutMons, zone)
+		}
+	}
+	sort.Strings(withoutMons)
+	return withoutMons
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMonSummary(t *testing.T) {
+	status := client.MonStatusResponse{Quorum: []int{0, 2}}
+	status.MonMap.Mons = []client.MonMapEntry{
+		{Name: "c", Rank: 2, Address: "1.2.3.3:6789/0"},
+		{Name: "a", Rank: 0, Address: "1.2.3.1:6789/0"},
+		{Name: "b", Rank: 1, Address: "1.2.3.2:6790/0"},
+	}
+
+	summary := NewMonSummary(status)
+	assert.Equal(t, []MonSummaryEntry{
+		{Name: "a", Endpoint: "1.2.3.1:6789", InQuorum: true},
+		{Name: "b", Endpoint: "1.2.3.2:6790", InQuorum: false},
+		{Name: "c", Endpoint: "1.2.3.3:6789", InQuorum: true},
+	}, summary.Mons)
+}
+
+func TestFormatMonPlacementTable(t *testing.T) {
+	summary := &MonSummary{Mons: []MonSummaryEntry{
+		{Name: "a", Endpoint: "1.2.3.1:6789", InQuorum: true},
+		{Name: "b", Endpoint: "1.2.3.2:6790", InQuorum: false},
+		{Name: "c", Endpoint: "", InQuorum: false},
+	}}
+	mapping := &Mapping{
+		Node: map[string]*NodeInfo{
+			"a": {Name: "node0"},
+			"b": {Name: "node-with-long-name"},
+		},
+	}
+	zones := map[string]string{"node0": "zone1"}
+
+	expected := "" +
+		"MON  NODE                 ZONE   IP       PORT  IN QUORUM\n" +
+		"a    node0                zone1  1.2.3.1  6789  true\n" +
+		"b    node-with-long-name  -      1.2.3.2  6790  false\n" +
+		"c    -                    -      -        -     false\n"
+	assert.Equal(t, expected, FormatMonPlacementTable(summary, mapping, zones))
+
+	// only the header without mons
+	assert.Equal(t, "MON  NODE  ZONE  IP  PORT  IN QUORUM\n", FormatMonPlacementTable(&MonSummary{}, nil, nil))
+}
+
+func TestZonesWithoutMons(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	defer os.RemoveAll(c.context.ConfigDir)
+
+	// each of the three zones has a mon
+	assert.Equal(t, []string{}, c.ZonesWithoutMons())
+
+	// both mons are in zone0 and zone1, so zone2 has no mon
+	delete(c.mapping.Node, "c")
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "node1"}
+	assert.Equal(t, []string{"zone2"}, c.ZonesWithoutMons())
+
+	// the zone saved in the mapping is the zone of the mon
+	c.mapping.Node["b"].Zone = "zone0"
+	assert.Equal(t, []string{"zone1", "zone2"}, c.ZonesWithoutMons())
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/util/display"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	rocksDBCacheSizeKey = "rocksdb_cache_size"
+	// the rocksdb cache may use at most this percentage of the memory limit of the mon container
+	maxRocksDBCacheMemoryPercent = 50
+)
+
+// validateRocksDBCacheSize checks that the rocksdb cache of the mon spec fits in the memory limit of the
+// mon container. A size of zero keeps the ceph default.
+func validateRocksDBCacheSize(sizeMB int, resources v1.ResourceRequirements) error {
+	if sizeMB < 0 {
+		return fmt.Errorf("invalid mon rocksdb cache size %dMB", sizeMB)
+	}
+	limit := resources.Limits.Memory()
+	if sizeMB == 0 || limit.IsZero() {
+		return nil
+	}
+	maxSizeMB := display.BToMb(uint64(limit.Value())) * maxRocksDBCacheMemoryPercent / 100
+	if uint64(sizeMB) > maxSizeMB {
+		return fmt.Errorf("mon rocksdb cache size %dMB exceeds %d%% of the mon memory limit of %dMB", sizeMB, maxRocksDBCacheMemoryPercent, display.BToMb(uint64(limit.Value())))
+	}
+	return nil
+}
+
+// applyRocksDBCacheSize sets the rocksdb cache size of the mon spec in the mon config. The mons read
+// the size when they start.
+func (c *Cluster) applyRocksDBCacheSize() error {
+	if c.spec.Mon.RocksDBCacheSizeMB == 0 {
+		return nil
+	}
+	value := strconv.FormatUint(display.MbTob(uint64(c.spec.Mon.RocksDBCacheSizeMB)), 10)
+	if err := client.MonSetConfig(c.context, c.ClusterInfo.Name, rocksDBCacheSizeKey, value); err != nil {
+		return err
+	}
+	logger.Infof("set mon %s=%s", rocksDBCacheSizeKey, value)
+	return nil
+}
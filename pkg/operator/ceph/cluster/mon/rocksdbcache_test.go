@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestValidateRocksDBCacheSize(t *testing.T) {
+	limited := v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")}}
+	assert.NoError(t, validateRocksDBCacheSize(0, limited))
+	assert.NoError(t, validateRocksDBCacheSize(512, limited))
+	assert.NoError(t, validateRocksDBCacheSize(1024, limited))
+	assert.Error(t, validateRocksDBCacheSize(1025, limited))
+	assert.Error(t, validateRocksDBCacheSize(-1, limited))
+	// any size without a memory limit
+	assert.NoError(t, validateRocksDBCacheSize(4096, v1.ResourceRequirements{}))
+
+	c := newCluster(&clusterd.Context{}, "ns", false, true, limited)
+	c.spec.Mon.RocksDBCacheSizeMB = 2048
+	assert.Error(t, c.validateSpec())
+	c.spec.Mon.RocksDBCacheSizeMB = 256
+	assert.NoError(t, c.validateSpec())
+}
+
+func TestApplyRocksDBCacheSize(t *testing.T) {
+	var setArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				setArgs = args[:5]
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// nothing is set without a size in the spec
+	assert.NoError(t, c.applyRocksDBCacheSize())
+	assert.Nil(t, setArgs)
+
+	// the size is set in bytes
+	c.spec.Mon.RocksDBCacheSizeMB = 512
+	assert.NoError(t, c.applyRocksDBCacheSize())
+	assert.Equal(t, []string{"config", "set", "mon", "rocksdb_cache_size", "536870912"}, setArgs)
+}
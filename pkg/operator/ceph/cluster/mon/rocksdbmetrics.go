@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// collectRocksDBMetrics sets the rocksdb metrics of each mon from the rocksdb perf counters of the mon.
+// The metrics of the other mons are still collected if a mon fails to report its counters.
+func collectRocksDBMetrics(ctx context.Context, cluster *Cluster) error {
+	names := []string{}
+	for name := range cluster.ClusterInfo.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := []string{}
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		counters, err := client.GetMonRocksDBPerfCounters(cluster.context, cluster.ClusterInfo.Name, name, monTellTimeout)
+		if err != nil {
+			logger.Debugf("failed to collect the rocksdb metrics of mon %s. %+v", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		setMonRocksDBMetrics(cluster.Namespace, name, counters)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to collect the rocksdb metrics of mons %s", strings.Join(failed, ","))
+	}
+	return nil
+}
+
+func setMonRocksDBMetrics(namespace, name string, counters *client.MonRocksDBPerfCounters) {
+	r := counters.RocksDB
+	monRocksDBGets.WithLabelValues(namespace, name).Set(float64(r.Get))
+	monRocksDBTransactions.WithLabelValues(namespace, name).Set(float64(r.SubmitTransaction))
+	monRocksDBSyncTransactions.WithLabelValues(namespace, name).Set(float64(r.SubmitTransactionSync))
+	monRocksDBGetLatency.WithLabelValues(namespace, name).Set(r.GetLatency.AvgTime)
+	monRocksDBSubmitLatency.WithLabelValues(namespace, name).Set(r.SubmitLatency.AvgTime)
+	monRocksDBSubmitSyncLatency.WithLabelValues(namespace, name).Set(r.SubmitSyncLatency.AvgTime)
+	monRocksDBCompactions.WithLabelValues(namespace, name).Set(float64(r.Compact + r.CompactRange))
+	monRocksDBCompactQueueLength.WithLabelValues(namespace, name).Set(float64(r.CompactQueueLen))
+}
+
+// deleteMonRocksDBMetrics removes the rocksdb metrics of a mon that was removed
+func deleteMonRocksDBMetrics(namespace, name string) {
+	for _, gauge := range monRocksDBGauges {
+		gauge.DeleteLabelValues(namespace, name)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+const rocksDBPerfDump = `{"rocksdb":{"get":1200,"submit_transaction":300,"submit_transaction_sync":250,` +
+	`"get_latency":{"avgcount":1200,"sum":0.6,"avgtime":0.0005},` +
+	`"submit_latency":{"avgcount":300,"sum":0.3,"avgtime":0.001},` +
+	`"submit_sync_latency":{"avgcount":250,"sum":1.25,"avgtime":0.005},` +
+	`"compact":4,"compact_range":1,"compact_queue_merge":0,"compact_queue_len":2}}`
+
+func TestCollectRocksDBMetrics(t *testing.T) {
+	failMon := ""
+	dumped := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFileTimeout: func(debug bool, timeout time.Duration, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "tell" && args[2] == "perf" && args[3] == "dump" && args[4] == "rocksdb" {
+				dumped = append(dumped, args[1])
+				if args[1] == "mon."+failMon {
+					return "", fmt.Errorf("mon %s is down", failMon)
+				}
+				return rocksDBPerfDump, nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "rocksdb-ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	assert.NoError(t, collectRocksDBMetrics(context.Background(), c))
+	assert.Equal(t, []string{"mon.a", "mon.b", "mon.c"}, dumped)
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, float64(1200), metricValue(t, monRocksDBGets.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, float64(300), metricValue(t, monRocksDBTransactions.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, float64(250), metricValue(t, monRocksDBSyncTransactions.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, 0.0005, metricValue(t, monRocksDBGetLatency.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, 0.001, metricValue(t, monRocksDBSubmitLatency.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, 0.005, metricValue(t, monRocksDBSubmitSyncLatency.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, float64(5), metricValue(t, monRocksDBCompactions.WithLabelValues("rocksdb-ns", name)))
+		assert.Equal(t, float64(2), metricValue(t, monRocksDBCompactQueueLength.WithLabelValues("rocksdb-ns", name)))
+	}
+	// the gauges are served by the operator
+	served := scrapeMetrics(t)
+	assert.Contains(t, served, `rook_ceph_mon_rocksdb_gets{mon="a",namespace="rocksdb-ns"} 1200`)
+	assert.Contains(t, served, `rook_ceph_mon_rocksdb_submit_sync_latency_seconds{mon="c",namespace="rocksdb-ns"} 0.005`)
+
+	// the other mons are collected if a mon fails
+	failMon = "b"
+	dumped = []string{}
+	err := collectRocksDBMetrics(context.Background(), c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mons b")
+	assert.Equal(t, []string{"mon.a", "mon.b", "mon.c"}, dumped)
+
+	// nothing is collected after the context is canceled
+	dumped = []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, collectRocksDBMetrics(ctx, c))
+	assert.Empty(t, dumped)
+
+	// the metrics of a removed mon are deleted
+	deleteMonRocksDBMetrics("rocksdb-ns", "a")
+	for _, gauge := range monRocksDBGauges {
+		assert.False(t, gauge.DeleteLabelValues("rocksdb-ns", "a"))
+		assert.True(t, gauge.DeleteLabelValues("rocksdb-ns", "b"))
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// restartMonPod restarts the pod of a mon. It is a variable so the unit tests can check the order in
+// which the mons are restarted without waiting for new pods.
+var restartMonPod = deleteMonPodAndWait
+
+// ApplyMonConfig sets a config option for all the mons. If the option only takes effect when the mons
+// restart, the mons are restarted one at a time and the quorum must be restored before the next mon
+// is restarted.
+func (c *Cluster) ApplyMonConfig(key, value string, requiresRestart bool) error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	if err := client.MonSetConfig(c.context, c.ClusterInfo.Name, key, value); err != nil {
+		return fmt.Errorf("failed to apply mon config. %+v", err)
+	}
+	logger.Infof("set mon config %s=%s", key, value)
+	if !requiresRestart {
+		return nil
+	}
+
+	if err := c.rollMons(); err != nil {
+		return fmt.Errorf("failed to restart the mons to apply config %s. %+v", key, err)
+	}
+	return nil
+}
+
+// rollMons restarts all the mons one at a time. The mons must all be in quorum before the roll starts
+// and again after each restart. The leader, which is the mon in quorum with the lowest rank, is
+// restarted last so that the quorum only elects a new leader once.
+func (c *Cluster) rollMons() error {
+	status, err := c.waitForFullQuorum()
+	if err != nil {
+		return fmt.Errorf("cannot safely restart the mons. %+v", err)
+	}
+
+	mons := append([]client.MonMapEntry{}, status.MonMap.Mons...)
+	sort.Slice(mons, func(i, j int) bool { return mons[i].Rank > mons[j].Rank })
+	for _, mon := range mons {
+		logger.Infof("restarting mon %s", mon.Name)
+		if err := restartMonPod(c, mon.Name); err != nil {
+			return fmt.Errorf("failed to restart mon %s. %+v", mon.Name, err)
+		}
+		if _, err := c.waitForFullQuorum(); err != nil {
+			return fmt.Errorf("quorum not restored after restarting mon %s. %+v", mon.Name, err)
+		}
+	}
+
+	logger.Infof("restarted %d mons", len(mons))
+	return nil
+}
+
+// waitForFullQuorum waits for all the mons in the monmap to be in quorum
+func (c *Cluster) waitForFullQuorum() (client.MonStatusResponse, error) {
+	start := time.Now()
+	for {
+		status, err := client.GetMonStatus(c.context, c.ClusterInfo.Name, false)
+		if err != nil {
+			logger.Debugf("failed to get mon status. %+v", err)
+		} else if len(status.Quorum) == len(status.MonMap.Mons) {
+			return status, nil
+		} else {
+			logger.Infof("waiting for all mons to be in quorum. %d of %d mons are in quorum", len(status.Quorum), len(status.MonMap.Mons))
+		}
+
+		if time.Since(start) > c.monPodTimeout {
+			return client.MonStatusResponse{}, fmt.Errorf("timed out waiting for all mons to be in quorum")
+		}
+		<-time.After(c.monPodRetryInterval)
+	}
+}
+
+// deleteMonPodAndWait deletes the pods of the mon and waits for the mon deployment to start a new pod
+func deleteMonPodAndWait(c *Cluster, name string) error {
+	selector := MonLabelSelector(c.Namespace, name).String()
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of mon %s. %+v", name, err)
+	}
+	oldPods := map[string]bool{}
+	for _, pod := range pods.Items {
+		oldPods[pod.Name] = true
+		if err := c.context.Clientset.CoreV1().Pods(c.Namespace).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("failed to delete pod %s of mon %s. %+v", pod.Name, name, err)
+		}
+	}
+
+	start := time.Now()
+	for {
+		pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			logger.Debugf("failed to list the pods of mon %s. %+v", name, err)
+		} else {
+			for _, pod := range pods.Items {
+				if !oldPods[pod.Name] && pod.Status.Phase == v1.PodRunning {
+					logger.Infof("mon %s restarted in pod %s", name, pod.Name)
+					return nil
+				}
+			}
+		}
+
+		if time.Since(start) > c.monPodTimeout {
+			return fmt.Errorf("timed out waiting for a new pod of mon %s", name)
+		}
+		<-time.After(c.monPodRetryInterval)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyMonConfig(t *testing.T) {
+	defer func() { restartMonPod = deleteMonPodAndWait }()
+
+	// the mon being restarted is out of quorum for the first status after its restart
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2}
+	restarting := ""
+	quorum := []int{0, 1, 2}
+	events := []string{}
+	monStatus := func() string {
+		status := client.MonStatusResponse{Quorum: quorum}
+		if restarting != "" {
+			status.Quorum = []int{}
+			for _, rank := range quorum {
+				if rank != ranks[restarting] {
+					status.Quorum = append(status.Quorum, rank)
+				}
+			}
+			restarting = ""
+		}
+		status.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+		events = append(events, fmt.Sprintf("quorum %d", len(status.Quorum)))
+		serialized, _ := json.Marshal(status)
+		return string(serialized)
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				events = append(events, fmt.Sprintf("set %s %s=%s", args[2], args[3], args[4]))
+				return "", nil
+			}
+			if args[0] == "mon_status" {
+				return monStatus(), nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	restartMonPod = func(c *Cluster, name string) error {
+		events = append(events, "restart "+name)
+		restarting = name
+		return nil
+	}
+	c := newCluster(&clusterd.Context{Clientset: test.New(3), Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	// the config is only set when no restart is needed
+	err := c.ApplyMonConfig("mon_osd_down_out_interval", "900", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"set mon mon_osd_down_out_interval=900"}, events)
+
+	// the mons are restarted one at a time with the leader last, waiting for the quorum in between
+	events = []string{}
+	err = c.ApplyMonConfig("mon_memory_target", "1073741824", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"set mon mon_memory_target=1073741824",
+		"quorum 3",
+		"restart c", "quorum 2", "quorum 3",
+		"restart b", "quorum 2", "quorum 3",
+		"restart a", "quorum 2", "quorum 3",
+	}, events)
+
+	// the mons are not restarted if they are not all in quorum
+	events = []string{}
+	quorum = []int{0, 1}
+	c.monPodTimeout = 50 * time.Millisecond
+	err = c.ApplyMonConfig("mon_memory_target", "1073741824", true)
+	assert.Error(t, err)
+	assert.NotContains(t, events, "restart c")
+	assert.NotContains(t, events, "restart a")
+}
+
+func TestDeleteMonPodAndWait(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, false, v1.ResourceRequirements{})
+	c.monPodTimeout = 0
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-a-123", Namespace: "ns", Labels: MonLabels("ns", "a")}}
+	pod.Status.Phase = v1.PodRunning
+	_, err := clientset.CoreV1().Pods("ns").Create(pod)
+	assert.NoError(t, err)
+
+	// the old pod is deleted and no new pod is started in the unit test
+	err = deleteMonPodAndWait(c, "a")
+	assert.Error(t, err)
+	_, err = clientset.CoreV1().Pods("ns").Get("rook-ceph-mon-a-123", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
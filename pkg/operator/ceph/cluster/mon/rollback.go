@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monRejectedImageAnnotation is the annotation on a mon deployment with the image that the mon was
+	// rolled back from
+	monRejectedImageAnnotation = "rook.io/mon-rejected-image"
+)
+
+// monDeploymentImage returns the image of the mon daemon container of the deployment
+func monDeploymentImage(d *apps.Deployment) string {
+	for _, container := range d.Spec.Template.Spec.Containers {
+		if container.Name == "mon" {
+			return container.Image
+		}
+	}
+	return ""
+}
+
+// keepRejectedMonImage keeps the mon on its current image if the image of the new deployment is the
+// image the mon was rolled back from. The image is tried again once the image in the cluster spec
+// changes. The other settings of the new deployment are still applied.
+func keepRejectedMonImage(existing, d *apps.Deployment) {
+	rejectedImage := existing.Annotations[monRejectedImageAnnotation]
+	currentImage := monDeploymentImage(existing)
+	if rejectedImage == "" || rejectedImage != monDeploymentImage(d) || currentImage == "" {
+		return
+	}
+	logger.Warningf("not updating mon deployment %s to image %s, which the mon was rolled back from. keeping image %s", d.Name, rejectedImage, currentImage)
+	setMonDeploymentImage(d, rejectedImage, currentImage)
+	d.Annotations[monRejectedImageAnnotation] = rejectedImage
+}
+
+// setMonDeploymentImage replaces the image of the containers of the mon deployment
+func setMonDeploymentImage(d *apps.Deployment, oldImage, newImage string) {
+	setImage := func(containers []v1.Container) {
+		for i := range containers {
+			if containers[i].Image == oldImage {
+				containers[i].Image = newImage
+			}
+		}
+	}
+	setImage(d.Spec.Template.Spec.InitContainers)
+	setImage(d.Spec.Template.Spec.Containers)
+}
+
+// rollbackMonUpdateOnQuorumLoss rolls the mon back to its previous image if the mon does not rejoin
+// the quorum after its deployment was updated to a new image, for example because of a bug in the
+// new ceph version. A mon is never rolled back to another ceph major version, since the new version
+// may have converted the mon store to a format the previous version cannot read, nor if the previous
+// version is not known. An error is returned if the mon did not rejoin the quorum.
+func (c *Cluster) rollbackMonUpdateOnQuorumLoss(name, previousImage, newImage string, previousVersion cephver.CephVersion) error {
+	if !c.waitForStart || previousImage == "" || previousImage == newImage {
+		return nil
+	}
+
+	ctx := context.Background()
+	err := waitForMonInQuorum(ctx, c, name)
+	if err == nil {
+		return nil
+	}
+	msg := ""
+	if previousVersion == (cephver.CephVersion{}) {
+		msg = fmt.Sprintf("mon %s did not rejoin the quorum after the update to image %s. not rolling back to image %s since the previous ceph version of the mon is unknown",
+			name, newImage, previousImage)
+	} else if previousVersion.Major != c.ClusterInfo.CephVersion.Major {
+		msg = fmt.Sprintf("mon %s did not rejoin the quorum after the update to image %s. not rolling back to image %s with ceph major version %d from major version %d",
+			name, newImage, previousImage, previousVersion.Major, c.ClusterInfo.CephVersion.Major)
+	}
+	if msg != "" {
+		logger.Errorf("%s. %+v", msg, err)
+		c.recordEvent(v1.EventTypeWarning, "MonUpdateNotRolledBack", msg)
+		return fmt.Errorf("%s. %+v", msg, err)
+	}
+	logger.Errorf("mon %s did not rejoin the quorum after the update to image %s. rolling back to image %s. %+v", name, newImage, previousImage, err)
+	if err := rollbackMonDeployment(ctx, c, name, previousImage); err != nil {
+		return fmt.Errorf("failed to roll back mon %s to image %s. %+v", name, previousImage, err)
+	}
+	return fmt.Errorf("mon %s lost quorum with image %s and was rolled back to image %s", name, newImage, previousImage)
+}
+
+// rollbackMonDeployment sets the containers of the mon deployment back to the previous image and
+// waits for the mon to rejoin the quorum. The image the mon is rolled back from is saved in an
+// annotation of the deployment so that the next orchestrations keep the previous image until the
+// image in the cluster spec changes.
+func rollbackMonDeployment(ctx context.Context, cluster *Cluster, monID, previousImage string) error {
+	name := resourceName(monID)
+	d, err := cluster.context.Clientset.AppsV1().Deployments(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mon deployment %s. %+v", name, err)
+	}
+
+	newImage := monDeploymentImage(d)
+	setMonDeploymentImage(d, newImage, previousImage)
+	if d.Annotations == nil {
+		d.Annotations = map[string]string{}
+	}
+	d.Annotations[monRejectedImageAnnotation] = newImage
+	if _, err := cluster.context.Clientset.AppsV1().Deployments(cluster.Namespace).Update(d); err != nil {
+		return fmt.Errorf("failed to update mon deployment %s. %+v", name, err)
+	}
+	cluster.recordEvent(v1.EventTypeWarning, "MonUpdateRolledBack",
+		fmt.Sprintf("mon %s lost quorum with image %s and was rolled back to image %s", monID, newImage, previousImage))
+
+	if err := waitForMonInQuorum(ctx, cluster, monID); err != nil {
+		return fmt.Errorf("mon %s did not rejoin the quorum after the rollback. %+v", monID, err)
+	}
+	logger.Infof("mon %s rejoined the quorum after the rollback to image %s", monID, previousImage)
+	return nil
+}
+
+// waitForMonInQuorum waits for the mon to be in quorum until the mon pod timeout
+func waitForMonInQuorum(ctx context.Context, cluster *Cluster, name string) error {
+	timeout := time.After(cluster.monPodTimeout)
+	for {
+		status, err := client.GetMonStatus(cluster.context, cluster.ClusterInfo.Name, false)
+		if err != nil {
+			logger.Debugf("failed to get mon status. %+v", err)
+		} else if monFoundInQuorum(name, status) {
+			return nil
+		} else {
+			logger.Infof("waiting for mon %s to join the quorum", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for mon %s to join the quorum. %+v", name, ctx.Err())
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for mon %s to join the quorum", name)
+		case <-time.After(cluster.monPodRetryInterval):
+		}
+	}
+}
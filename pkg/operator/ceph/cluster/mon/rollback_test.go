@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRollbackMonUpdateOnQuorumLoss(t *testing.T) {
+	brokenImage := "ceph/ceph:v14.2.99"
+	runningVersion := "14.2.2"
+	clientset := test.New(1)
+	getImage := func() string {
+		d, err := clientset.AppsV1().Deployments("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return monDeploymentImage(d)
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if len(args) > 0 && args[0] == "versions" {
+				if runningVersion == "" {
+					return "", fmt.Errorf("mons not reachable")
+				}
+				return fmt.Sprintf(`{"mon":{"ceph version %s (cbff874f9007f1869bfd3821b7e33b2a6ffd4988) nautilus (stable)":1}}`, runningVersion), nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon_status" {
+				// the mon cannot join the quorum with the broken image
+				if getImage() == brokenImage {
+					return `{"quorum":[],"monmap":{"mons":[{"name":"a","rank":0}]}}`, nil
+				}
+				return clienttest.MonInQuorumResponse(), nil
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	// the deployments are updated without waiting for the rollout
+	defer func(update func(*clusterd.Context, *apps.Deployment, string, string, string, cephver.CephVersion) error) {
+		updateDeploymentAndWait = update
+	}(updateDeploymentAndWait)
+	updateDeploymentAndWait = func(context *clusterd.Context, d *apps.Deployment, namespace, daemonType, daemonName string, cephVersion cephver.CephVersion) error {
+		_, err := context.Clientset.AppsV1().Deployments(namespace).Update(d)
+		return err
+	}
+
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(1)
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	c.waitForStart = true
+	c.monPodTimeout = 50 * time.Millisecond
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	m := testGenMonConfig("a")
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.1"
+	assert.NoError(t, c.startMon(m, "node0"))
+
+	// an upgrade that keeps the quorum
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.2"
+	assert.NoError(t, c.startMon(m, "node0"))
+	assert.Equal(t, "ceph/ceph:v14.2.2", getImage())
+	assert.Empty(t, recorder.Events)
+
+	// an upgrade that breaks the quorum is rolled back
+	c.spec.CephVersion.Image = brokenImage
+	err := c.startMon(m, "node0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mon a lost quorum with image ceph/ceph:v14.2.99 and was rolled back to image ceph/ceph:v14.2.2")
+	d, err := clientset.AppsV1().Deployments("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	for _, container := range append(d.Spec.Template.Spec.InitContainers, d.Spec.Template.Spec.Containers...) {
+		assert.Equal(t, "ceph/ceph:v14.2.2", container.Image, container.Name)
+	}
+	assert.Equal(t, brokenImage, d.Annotations[monRejectedImageAnnotation])
+	assert.Contains(t, <-recorder.Events, "MonUpdateRolledBack")
+
+	// the rejected image is not tried again by the next orchestrations
+	assert.NoError(t, c.startMon(m, "node0"))
+	assert.Equal(t, "ceph/ceph:v14.2.2", getImage())
+	assert.Empty(t, recorder.Events)
+
+	// the other settings are still applied with the previous image
+	c.spec.Mon.CPUSet = "0-1"
+	assert.NoError(t, c.startMon(m, "node0"))
+	d, err = clientset.AppsV1().Deployments("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ceph/ceph:v14.2.2", monDeploymentImage(d))
+	assert.Equal(t, brokenImage, d.Annotations[monRejectedImageAnnotation])
+	assert.Equal(t, "2", d.Spec.Template.Spec.Containers[0].Resources.Limits.Cpu().String())
+	c.spec.Mon.CPUSet = ""
+
+	// the mon is updated once the image in the spec changes
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.3"
+	assert.NoError(t, c.startMon(m, "node0"))
+	d, err = clientset.AppsV1().Deployments("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ceph/ceph:v14.2.3", monDeploymentImage(d))
+	_, ok := d.Annotations[monRejectedImageAnnotation]
+	assert.False(t, ok)
+
+	// the mon is not rolled back to another ceph major version
+	runningVersion = "13.2.5"
+	c.spec.CephVersion.Image = brokenImage
+	err = c.startMon(m, "node0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not rolling back to image ceph/ceph:v14.2.3 with ceph major version 13 from major version 14")
+	assert.Equal(t, brokenImage, getImage())
+	assert.Contains(t, <-recorder.Events, "MonUpdateNotRolledBack")
+
+	// the mon is not rolled back if the version it ran before the update is unknown
+	runningVersion = "14.2.3"
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.3"
+	assert.NoError(t, c.startMon(m, "node0"))
+	runningVersion = ""
+	c.spec.CephVersion.Image = brokenImage
+	err = c.startMon(m, "node0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not rolling back to image ceph/ceph:v14.2.3 since the previous ceph version of the mon is unknown")
+	assert.NotContains(t, err.Error(), "major version")
+	assert.Equal(t, brokenImage, getImage())
+	assert.Contains(t, <-recorder.Events, "MonUpdateNotRolledBack")
+
+	// the mon is not rolled back if the mons are not waited for
+	runningVersion = "14.2.3"
+	c.waitForStart = false
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.3"
+	assert.NoError(t, c.startMon(m, "node0"))
+	c.spec.CephVersion.Image = brokenImage
+	assert.NoError(t, c.startMon(m, "node0"))
+	assert.Equal(t, brokenImage, getImage())
+}
+
+func TestRollbackMonDeploymentWithoutQuorum(t *testing.T) {
+	clientset := test.New(1)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return `{"quorum":[],"monmap":{"mons":[{"name":"a","rank":0}]}}`, nil
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, true, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(1)
+	c.monPodTimeout = 50 * time.Millisecond
+	c.recorder = record.NewFakeRecorder(10)
+	c.spec.CephVersion.Image = "ceph/ceph:v14.2.2"
+	assert.NoError(t, c.startMon(testGenMonConfig("a"), "node0"))
+
+	// the rollback fails if the mon does not rejoin the quorum with the previous image either
+	err := rollbackMonDeployment(context.Background(), c, "a", "ceph/ceph:v14.2.1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mon a did not rejoin the quorum after the rollback")
+
+	// a missing deployment
+	assert.Error(t, rollbackMonDeployment(context.Background(), c, "b", "ceph/ceph:v14.2.1"))
+}
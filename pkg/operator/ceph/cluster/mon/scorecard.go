@@ -0,0 +1,221 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+var (
+	// monScoreSamples is the number of times each mon endpoint is probed to compute its score
+	monScoreSamples = 5
+	// monScoreWindow is the time over which the probes of a mon endpoint are spread
+	monScoreWindow = 2 * time.Second
+	// monScoreLatencyScaleMs is the probe latency at which the overall score of a mon is halved
+	monScoreLatencyScaleMs = 100.0
+)
+
+const (
+	// the mons are scored less often than the health check since each mon is probed several times
+	// over the score window
+	monScoreInterval = 5 * time.Minute
+)
+
+// MonScore rates how healthy a mon endpoint looks to a client
+type MonScore struct {
+	// Availability is the fraction of the probes that connected to the mon
+	Availability float64
+	// P99LatencyMs is the 99th percentile of the time to connect to the mon. The probe timeout is
+	// used if no probe connected.
+	P99LatencyMs float64
+	// ErrorRate is the fraction of the probes that failed with an error other than a timeout, such as
+	// a refused connection
+	ErrorRate float64
+	// Overall combines the other scores between 0 (unusable) and 1 (healthy)
+	Overall float64
+}
+
+// ScoreMon probes the mon endpoint several times over the window and scores the endpoint on its
+// availability, latency and errors
+func ScoreMon(ctx context.Context, endpoint string, window time.Duration) (*MonScore, error) {
+	interval := window / time.Duration(monScoreSamples)
+	latencies := []float64{}
+	errors := 0
+	for i := 0; i < monScoreSamples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to score mon endpoint %s. %+v", endpoint, ctx.Err())
+			case <-time.After(interval):
+			}
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, monProbeTimeout)
+		start := time.Now()
+		err := probeMonEndpoint(probeCtx, endpoint)
+		latency := time.Since(start)
+		cancel()
+		if err == nil {
+			latencies = append(latencies, float64(latency)/float64(time.Millisecond))
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to score mon endpoint %s. %+v", endpoint, ctx.Err())
+		}
+		if !isTimeout(err) {
+			errors++
+		}
+		logger.Debugf("failed to connect to mon endpoint %s while scoring it. %+v", endpoint, err)
+	}
+
+	return newMonScore(latencies, errors, monScoreSamples), nil
+}
+
+// newMonScore computes the score of a mon from the latencies of the successful probes and the number
+// of probes that failed with an error
+func newMonScore(latencies []float64, errors, samples int) *MonScore {
+	score := &MonScore{
+		Availability: float64(len(latencies)) / float64(samples),
+		ErrorRate:    float64(errors) / float64(samples),
+		P99LatencyMs: float64(monProbeTimeout) / float64(time.Millisecond),
+	}
+	if len(latencies) > 0 {
+		score.P99LatencyMs = percentile(latencies, 99)
+	}
+	score.Overall = score.Availability * (1 - score.ErrorRate) / (1 + score.P99LatencyMs/monScoreLatencyScaleMs)
+	return score
+}
+
+// percentile returns the nearest-rank percentile of the values
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// checkMonScores scores the mons if they were not scored within the interval
+func (c *Cluster) checkMonScores() {
+	if time.Since(c.lastMonScore) < monScoreInterval {
+		return
+	}
+	c.lastMonScore = time.Now()
+	c.scoreMons()
+}
+
+// scoreMons scores all the mon endpoints concurrently and keeps the scores in the cluster and in the
+// mon health report. The mons that cannot be scored keep their previous score, while the mons that
+// could not be reached by the last probe get a score of zero.
+func (c *Cluster) scoreMons() {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	scores := map[string]*MonScore{}
+	for name, mon := range c.ClusterInfo.Monitors {
+		if c.monProbeFailures[name] > 0 {
+			// don't wait for the probes to time out again when the last health check already failed
+			// to connect to the mon
+			mutex.Lock()
+			scores[name] = newMonScore(nil, 0, 1)
+			mutex.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(name, endpoint string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), monScoreWindow+time.Duration(monScoreSamples)*monProbeTimeout)
+			defer cancel()
+			score, err := ScoreMon(ctx, endpoint, monScoreWindow)
+			if err != nil {
+				logger.Warningf("failed to score mon %s. %+v", name, err)
+				return
+			}
+			logger.Debugf("mon %s at %s has score %+v", name, endpoint, *score)
+			mutex.Lock()
+			scores[name] = score
+			mutex.Unlock()
+		}(name, mon.Endpoint)
+	}
+	wg.Wait()
+
+	for name, score := range scores {
+		c.monScores[name] = score
+	}
+	// forget about mons that were removed
+	for name := range c.monScores {
+		if _, ok := c.ClusterInfo.Monitors[name]; !ok {
+			delete(c.monScores, name)
+			deleteMonScoreMetrics(c.Namespace, name)
+		}
+	}
+
+	reported := map[string]MonScore{}
+	for name, score := range c.monScores {
+		reported[name] = *score
+		updateMonScoreMetrics(c.Namespace, name, *score)
+	}
+	c.healthReportMutex.Lock()
+	c.healthReport.Scores = reported
+	c.healthReportMutex.Unlock()
+}
+
+// sortMonEndpointsByScore returns the same form as FlattenMonEndpoints, but with the mons with the
+// highest overall score first so that clients try the healthiest mons first. Mons without a score are
+// last, and ties are sorted by name.
+func sortMonEndpointsByScore(mons map[string]*cephconfig.MonInfo, scores map[string]*MonScore, cephVersion cephver.CephVersion) string {
+	names := []string{}
+	for name := range mons {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		si, iok := scores[names[i]]
+		sj, jok := scores[names[j]]
+		if iok != jok {
+			return iok
+		}
+		if iok && si.Overall != sj.Overall {
+			return si.Overall > sj.Overall
+		}
+		return names[i] < names[j]
+	})
+
+	endpoints := []string{}
+	for _, name := range names {
+		endpoints = append(endpoints, formatMonEndpoint(mons[name], cephVersion))
+	}
+	return strings.Join(endpoints, ",")
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	// don't spread the probes of the fake mon endpoints over time in the unit tests
+	monScoreWindow = 0
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestScoreMon(t *testing.T) {
+	defer func() { probeMonEndpoint = func(ctx context.Context, endpoint string) error { return nil } }()
+
+	// a healthy mon
+	score, err := ScoreMon(context.Background(), "1.2.3.4:6789", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, score.Availability)
+	assert.Equal(t, 0.0, score.ErrorRate)
+	assert.True(t, score.Overall > 0.9)
+
+	// every other probe times out or fails
+	probes := 0
+	probeMonEndpoint = func(ctx context.Context, endpoint string) error {
+		probes++
+		switch probes % 4 {
+		case 1:
+			return timeoutError{}
+		case 3:
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	score, err = ScoreMon(context.Background(), "1.2.3.4:6789", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, monScoreSamples, probes)
+	// probes 2 and 4 succeed, 3 is refused, 1 and 5 time out
+	assert.Equal(t, 0.4, score.Availability)
+	assert.Equal(t, 0.2, score.ErrorRate)
+	assert.True(t, score.Overall < 0.4)
+
+	// the mon never connects
+	probeMonEndpoint = func(ctx context.Context, endpoint string) error { return timeoutError{} }
+	score, err = ScoreMon(context.Background(), "1.2.3.4:6789", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, score.Availability)
+	assert.Equal(t, float64(monProbeTimeout/time.Millisecond), score.P99LatencyMs)
+	assert.Equal(t, 0.0, score.Overall)
+
+	// scoring stops when the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ScoreMon(ctx, "1.2.3.4:6789", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestNewMonScore(t *testing.T) {
+	score := newMonScore([]float64{5, 1, 100, 3}, 1, 5)
+	assert.Equal(t, 0.8, score.Availability)
+	assert.Equal(t, 0.2, score.ErrorRate)
+	assert.Equal(t, 100.0, score.P99LatencyMs)
+	// halved by the latency
+	assert.InDelta(t, 0.8*0.8/2, score.Overall, 0.0001)
+
+	// lower latency is a better score
+	assert.True(t, newMonScore([]float64{10}, 0, 1).Overall > newMonScore([]float64{20}, 0, 1).Overall)
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{}
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+	assert.Equal(t, 99.0, percentile(values, 99))
+	assert.Equal(t, 50.0, percentile(values, 50))
+	assert.Equal(t, 1.0, percentile(values, 0))
+	assert.Equal(t, 7.0, percentile([]float64{7}, 99))
+	// the input is not modified
+	assert.Equal(t, 100.0, values[0])
+}
+
+func TestSortMonEndpointsByScore(t *testing.T) {
+	mons := map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.1.1.1:6789"},
+		"b": {Name: "b", Endpoint: "2.2.2.2:6789"},
+		"c": {Name: "c", Endpoint: "3.3.3.3:6789"},
+		"d": {Name: "d", Endpoint: "4.4.4.4:6789"},
+	}
+	scores := map[string]*MonScore{
+		"a": {Overall: 0.2},
+		"b": {Overall: 0.9},
+		"d": {Overall: 0.2},
+	}
+	assert.Equal(t, "b=2.2.2.2:6789,a=1.1.1.1:6789,d=4.4.4.4:6789,c=3.3.3.3:6789", sortMonEndpointsByScore(mons, scores, cephver.Mimic))
+
+	// sorted by name without scores
+	assert.Equal(t, "a=1.1.1.1:6789,b=2.2.2.2:6789,c=3.3.3.3:6789,d=4.4.4.4:6789", sortMonEndpointsByScore(mons, nil, cephver.Mimic))
+}
+
+func TestScoreMons(t *testing.T) {
+	c := newCluster(nil, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = &cephconfig.ClusterInfo{Monitors: map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.1.1.1:6789"},
+		"b": {Name: "b", Endpoint: "2.2.2.2:6789"},
+	}}
+	c.monProbeFailures["b"] = 1
+	c.monScores["z"] = &MonScore{Overall: 1}
+
+	c.scoreMons()
+	assert.Equal(t, 1.0, c.monScores["a"].Availability)
+	// the unreachable mon is not probed again
+	assert.Equal(t, 0.0, c.monScores["b"].Overall)
+	// removed mons are forgotten
+	assert.NotContains(t, c.monScores, "z")
+
+	// the scores are reported and served as metrics
+	report := c.MonHealthReport()
+	assert.Equal(t, 2, len(report.Scores))
+	assert.Equal(t, 1.0, report.Scores["a"].Availability)
+	assert.Equal(t, 0.0, report.Scores["b"].Overall)
+	assert.Equal(t, 1.0, metricValue(t, monEndpointAvailability.WithLabelValues("ns", "a")))
+	assert.Equal(t, 0.0, metricValue(t, monEndpointScore.WithLabelValues("ns", "b")))
+	assert.Contains(t, scrapeMetrics(t), `rook_ceph_mon_endpoint_score{mon="a",namespace="ns"}`)
+
+	// the report is a copy
+	report.Scores["a"] = MonScore{}
+	assert.Equal(t, 1.0, c.MonHealthReport().Scores["a"].Availability)
+
+	// the scores of a removed mon are removed
+	delete(c.ClusterInfo.Monitors, "b")
+	c.scoreMons()
+	assert.NotContains(t, c.MonHealthReport().Scores, "b")
+	assert.False(t, monEndpointScore.DeleteLabelValues("ns", "b"))
+}
+
+func TestCheckMonScores(t *testing.T) {
+	c := newCluster(nil, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = &cephconfig.ClusterInfo{Monitors: map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.1.1.1:6789"},
+	}}
+
+	// the mons are not scored again within the interval
+	c.lastMonScore = time.Now()
+	c.checkMonScores()
+	assert.Empty(t, c.monScores)
+	assert.Empty(t, c.MonHealthReport().Scores)
+
+	c.lastMonScore = time.Now().Add(-monScoreInterval)
+	c.checkMonScores()
+	assert.Contains(t, c.monScores, "a")
+	assert.Contains(t, c.MonHealthReport().Scores, "a")
+	assert.True(t, time.Since(c.lastMonScore) < time.Minute)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// validateSeedMonHosts checks that the seed mons are addresses that can be set in the mon_host of a
+// mon. an address may have a port and a messenger version prefix, e.g. "v1:10.0.0.1:6789".
+func validateSeedMonHosts(hosts []string) error {
+	for _, host := range hosts {
+		addr := strings.TrimPrefix(strings.TrimPrefix(host, "v1:"), "v2:")
+		if strings.ContainsAny(addr, ", ") {
+			return fmt.Errorf("invalid seed mon host %q. each seed must be a single address", host)
+		}
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			addr = h
+		}
+		if addr == "" {
+			return fmt.Errorf("invalid seed mon host %q. the address is empty", host)
+		}
+	}
+	return nil
+}
+
+// addSeedMonHosts adds the seed mons to the mon_host of the ceph containers of a new mon so the mon
+// can reach an existing mon directly when the mons cannot be discovered from the stored mon_host.
+// the seeds are not part of the mon config hash, so they stay on the deployment until the mon is
+// updated for another reason, which is after it has joined the quorum.
+func (c *Cluster) addSeedMonHosts(spec *v1.PodSpec) {
+	if len(c.spec.Mon.SeedMonHosts) == 0 {
+		return
+	}
+	seeds := strings.Join(c.spec.Mon.SeedMonHosts, ",")
+	for i := range spec.InitContainers {
+		addSeedMonHostsToArgs(spec.InitContainers[i].Args, seeds)
+	}
+	for i := range spec.Containers {
+		addSeedMonHostsToArgs(spec.Containers[i].Args, seeds)
+	}
+}
+
+// addSeedMonHostsToArgs puts the seeds in front of the mons of the mon_host flag in the args
+func addSeedMonHostsToArgs(args []string, seeds string) {
+	monHostFlag := config.NewFlag("mon-host", "")
+	for i, arg := range args {
+		if strings.HasPrefix(arg, monHostFlag) {
+			args[i] = monHostFlag + seeds + "," + strings.TrimPrefix(arg, monHostFlag)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"sync"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJoiningMonSeedHosts(t *testing.T) {
+	clientset := testop.New(1)
+	c := New(&clusterd.Context{Clientset: clientset}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	c.spec.Mon.SeedMonHosts = []string{"10.0.0.10:6789", "v2:10.0.0.11:3300"}
+	m := testGenMonConfig("a")
+	assert.NoError(t, c.startMon(m, "node0"))
+
+	// the seeds are contacted before the mons of the stored config
+	expected := "--mon-host=10.0.0.10:6789,v2:10.0.0.11:3300,$(ROOK_CEPH_MON_HOST)"
+	d, err := clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, containerHasArg(d.Spec.Template.Spec.Containers, "mon", expected))
+	assert.True(t, containerHasArg(d.Spec.Template.Spec.InitContainers, "init-mon-fs", expected))
+
+	// the seeds do not change a mon that already exists
+	c.spec.Mon.SeedMonHosts = []string{"10.0.0.12"}
+	assert.NoError(t, c.startMon(m, "node0"))
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, containerHasArg(d.Spec.Template.Spec.Containers, "mon", expected))
+
+	// a mon is not given any seeds by default
+	c.spec.Mon.SeedMonHosts = nil
+	d = c.makeDeployment(testGenMonConfig("b"), "node0")
+	c.addSeedMonHosts(&d.Spec.Template.Spec)
+	assert.True(t, containerHasArg(d.Spec.Template.Spec.Containers, "mon", "--mon-host=$(ROOK_CEPH_MON_HOST)"))
+}
+
+func TestValidateSeedMonHosts(t *testing.T) {
+	assert.NoError(t, validateSeedMonHosts(nil))
+	assert.NoError(t, validateSeedMonHosts([]string{"10.0.0.1", "10.0.0.2:6789", "v1:10.0.0.3:6789", "v2:mon-a.example.com:3300", "[::1]:6789"}))
+	assert.Error(t, validateSeedMonHosts([]string{""}))
+	assert.Error(t, validateSeedMonHosts([]string{"v1:"}))
+	assert.Error(t, validateSeedMonHosts([]string{"10.0.0.1,10.0.0.2"}))
+	assert.Error(t, validateSeedMonHosts([]string{"10.0.0.1 10.0.0.2"}))
+}
+
+func containerHasArg(containers []v1.Container, name, arg string) bool {
+	for _, container := range containers {
+		if container.Name != name {
+			continue
+		}
+		for _, a := range container.Args {
+			if a == arg {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -17,17 +17,47 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 
+	cephutil "github.com/rook/rook/pkg/daemon/ceph/util"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (c *Cluster) createService(mon *monConfig) (string, error) {
-	labels := c.getLabels(mon.DaemonName)
+	if err := validateMonServicePorts(context.Background(), c, mon); err != nil {
+		return "", err
+	}
+	svcDef := c.makeService(mon)
+	s, err := k8sutil.CreateOrUpdateService(c.context.Clientset, c.Namespace, svcDef)
+	if err != nil {
+		return "", fmt.Errorf("failed to create service for mon %s. %+v", mon.DaemonName, err)
+	}
+
+	if s == nil {
+		logger.Errorf("service ip not found for mon %s. if this is not a unit test, this is an error", mon.ResourceName)
+		return "", nil
+	}
+
+	// mon endpoint are not actually like, they remain with the mgrs1 format
+	// however it's interesting to show that monitors can be addressed via 2 different ports
+	// in the end the service has msgr1 and msgr2 ports configured so it's not entirely wrong
+	if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
+		logger.Infof("mon %s endpoint are [v2:%s:%s,v1:%s:%d]", mon.DaemonName, s.Spec.ClusterIP, strconv.Itoa(int(DefaultMsgr2Port)), s.Spec.ClusterIP, mon.Port)
+	} else {
+		logger.Infof("mon %s endpoint is %s:%d", mon.DaemonName, s.Spec.ClusterIP, mon.Port)
+	}
+	return s.Spec.ClusterIP, nil
+}
+
+func (c *Cluster) makeService(mon *monConfig) *v1.Service {
+	labels := MonLabels(c.Namespace, mon.DaemonName)
 	svcDef := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   mon.ResourceName,
@@ -56,24 +86,123 @@ func (c *Cluster) createService(mon *monConfig) (string, error) {
 	if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
 		addServicePort(svcDef, "msgr2", DefaultMsgr2Port)
 	}
+	return svcDef
+}
 
-	s, err := k8sutil.CreateOrUpdateService(c.context.Clientset, c.Namespace, svcDef)
+// checkMonServices repairs the services of the mons whose cluster ip is not the mon endpoint. The
+// mon services are listed once and only the services that need a repair are reconciled.
+func (c *Cluster) checkMonServices() {
+	if c.HostNetwork {
+		return
+	}
+
+	services, err := c.context.Clientset.CoreV1().Services(c.Namespace).List(metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()})
 	if err != nil {
-		return "", fmt.Errorf("failed to create service for mon %s. %+v", mon.DaemonName, err)
+		logger.Warningf("failed to list the mon services to check their cluster ips. %+v", err)
+		return
+	}
+	clusterIPs := map[string]string{}
+	for _, svc := range services.Items {
+		clusterIPs[svc.Name] = svc.Spec.ClusterIP
 	}
 
-	if s == nil {
-		logger.Errorf("service ip not found for mon %s. if this is not a unit test, this is an error", mon.ResourceName)
-		return "", nil
+	names := []string{}
+	for name := range c.ClusterInfo.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		endpoint := c.ClusterInfo.Monitors[name].Endpoint
+		if clusterIP, ok := clusterIPs[resourceName(name)]; ok && clusterIP == cephutil.GetIPFromEndpoint(endpoint) {
+			continue
+		}
+		m := &monConfig{
+			ResourceName: resourceName(name),
+			DaemonName:   name,
+			PublicIP:     cephutil.GetIPFromEndpoint(endpoint),
+			Port:         cephutil.GetPortFromEndpoint(endpoint),
+		}
+		if err := reconcileMonService(context.Background(), c, m); err != nil {
+			logger.Warningf("failed to repair the service of mon %s. %+v", name, err)
+		}
 	}
+}
 
-	// mon endpoint are not actually like, they remain with the mgrs1 format
-	// however it's interesting to show that monitors can be addressed via 2 different ports
-	// in the end the service has msgr1 and msgr2 ports configured so it's not entirely wrong
-	if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
-		logger.Infof("mon %s endpoint are [v2:%s:%s,v1:%s:%d]", mon.DaemonName, s.Spec.ClusterIP, strconv.Itoa(int(DefaultMsgr2Port)), s.Spec.ClusterIP, mon.Port)
+// reconcileMonService recreates the service of the mon if its cluster ip is not the public ip of
+// the mon. The mon advertises its public ip in the monmap, so clients could not reach the mon
+// through a service with another cluster ip, e.g. after the service was deleted and created again.
+// The cluster ip of a service cannot be changed, so the service is deleted and created with the
+// public ip of the mon.
+func reconcileMonService(ctx context.Context, cluster *Cluster, m *monConfig) error {
+	// the mon services are headless with host networking
+	if cluster.HostNetwork || m.PublicIP == "" {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	services := cluster.context.Clientset.CoreV1().Services(cluster.Namespace)
+	existing, err := services.Get(m.ResourceName, metav1.GetOptions{})
+	if err == nil {
+		if existing.Spec.ClusterIP == m.PublicIP {
+			return nil
+		}
+		logger.Warningf("service %s has cluster ip %s instead of the mon %s ip %s. recreating the service",
+			m.ResourceName, existing.Spec.ClusterIP, m.DaemonName, m.PublicIP)
+		if err := services.Delete(m.ResourceName, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service %s. %+v", m.ResourceName, err)
+		}
+	} else if errors.IsNotFound(err) {
+		logger.Warningf("service %s of mon %s not found. creating the service with the mon ip %s", m.ResourceName, m.DaemonName, m.PublicIP)
 	} else {
-		logger.Infof("mon %s endpoint is %s:%d", mon.DaemonName, s.Spec.ClusterIP, mon.Port)
+		return fmt.Errorf("failed to get service %s. %+v", m.ResourceName, err)
 	}
-	return s.Spec.ClusterIP, nil
+
+	if err := validateMonServicePorts(ctx, cluster, m); err != nil {
+		return err
+	}
+	svc := cluster.makeService(m)
+	svc.Spec.ClusterIP = m.PublicIP
+	if _, err := services.Create(svc); err != nil {
+		return fmt.Errorf("failed to create service %s with cluster ip %s. %+v", m.ResourceName, m.PublicIP, err)
+	}
+	logger.Infof("recreated service %s of mon %s with cluster ip %s", m.ResourceName, m.DaemonName, m.PublicIP)
+	return nil
+}
+
+// validateMonServicePorts returns an error with the name of the conflicting service if another service
+// in the namespace already uses a port of the mon service. With host networking the mon listens on its
+// ports on the node, which conflicts with a node port of another service. Otherwise the mon service
+// conflicts with another service on the same cluster ip and port.
+func validateMonServicePorts(ctx context.Context, cluster *Cluster, m *monConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	services, err := cluster.context.Clientset.CoreV1().Services(cluster.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the services to check the ports of mon %s. %+v", m.DaemonName, err)
+	}
+
+	ports := cluster.makeService(m).Spec.Ports
+	for _, svc := range services.Items {
+		// the service of the mon is replaced, so its ports are not a conflict
+		if svc.Name == m.ResourceName {
+			continue
+		}
+		for _, existing := range svc.Spec.Ports {
+			for _, port := range ports {
+				if existing.Protocol != port.Protocol {
+					continue
+				}
+				if cluster.HostNetwork && existing.NodePort == port.Port {
+					return fmt.Errorf("port %d of mon %s is already used as a node port by service %s", port.Port, m.DaemonName, svc.Name)
+				}
+				if !cluster.HostNetwork && m.PublicIP != "" && svc.Spec.ClusterIP == m.PublicIP && existing.Port == port.Port {
+					return fmt.Errorf("port %d of mon %s on cluster ip %s is already used by service %s", port.Port, m.DaemonName, m.PublicIP, svc.Name)
+				}
+			}
+		}
+	}
+	return nil
 }
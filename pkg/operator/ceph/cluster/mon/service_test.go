@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileMonService(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	setCommonMonProperties(c, 1, c.spec.Mon, "myversion")
+	m := &monConfig{ResourceName: "rook-ceph-mon-a", DaemonName: "a", Port: DefaultMsgr1Port, PublicIP: "10.0.0.1"}
+
+	svc := c.makeService(m)
+	svc.Spec.ClusterIP = "10.0.0.1"
+	svc.UID = "original"
+	_, err := clientset.CoreV1().Services(c.Namespace).Create(svc)
+	assert.Nil(t, err)
+
+	// the service is kept when its ip matches the mon
+	err = reconcileMonService(context.Background(), c, m)
+	assert.Nil(t, err)
+	s, err := clientset.CoreV1().Services(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", s.Spec.ClusterIP)
+	assert.Equal(t, "original", string(s.UID))
+
+	// the service is recreated with the mon ip when the ips differ
+	m.PublicIP = "10.0.0.2"
+	err = reconcileMonService(context.Background(), c, m)
+	assert.Nil(t, err)
+	s, err = clientset.CoreV1().Services(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", s.Spec.ClusterIP)
+	assert.NotEqual(t, "original", string(s.UID))
+	assert.Equal(t, MonLabels(c.Namespace, "a"), s.Spec.Selector)
+
+	// a missing service is created with the mon ip
+	err = clientset.CoreV1().Services(c.Namespace).Delete(m.ResourceName, &metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	err = reconcileMonService(context.Background(), c, m)
+	assert.Nil(t, err)
+	s, err = clientset.CoreV1().Services(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", s.Spec.ClusterIP)
+
+	// the services are headless with host networking
+	c.HostNetwork = true
+	m.PublicIP = "10.0.0.3"
+	err = reconcileMonService(context.Background(), c, m)
+	assert.Nil(t, err)
+	s, err = clientset.CoreV1().Services(c.Namespace).Get(m.ResourceName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.2", s.Spec.ClusterIP)
+}
+
+func TestValidateMonServicePorts(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	setCommonMonProperties(c, 1, c.spec.Mon, "myversion")
+	m := &monConfig{ResourceName: "rook-ceph-mon-b", DaemonName: "b", Port: DefaultMsgr1Port, PublicIP: "10.0.0.2"}
+	createService := func(name, clusterIP string, port, nodePort int32) {
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.Namespace},
+			Spec: v1.ServiceSpec{
+				ClusterIP: clusterIP,
+				Ports:     []v1.ServicePort{{Port: port, NodePort: nodePort, Protocol: v1.ProtocolTCP}},
+			},
+		}
+		_, err := clientset.CoreV1().Services(c.Namespace).Create(svc)
+		assert.NoError(t, err)
+	}
+
+	// no conflict with services on other ips or ports
+	createService("rook-ceph-mon-a", "10.0.0.1", DefaultMsgr1Port, 0)
+	createService("web", "10.0.0.2", 80, 0)
+	assert.NoError(t, validateMonServicePorts(context.Background(), c, m))
+
+	// the service of the mon itself is not a conflict
+	createService("rook-ceph-mon-b", "10.0.0.2", DefaultMsgr1Port, 0)
+	assert.NoError(t, validateMonServicePorts(context.Background(), c, m))
+
+	// another service on the mon ip and port
+	createService("other", "10.0.0.2", DefaultMsgr1Port, 0)
+	err := validateMonServicePorts(context.Background(), c, m)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service other")
+
+	// the reconcile does not recreate the service with a conflicting ip
+	assert.NoError(t, clientset.CoreV1().Services(c.Namespace).Delete("rook-ceph-mon-b", &metav1.DeleteOptions{}))
+	assert.Error(t, reconcileMonService(context.Background(), c, m))
+
+	// with host networking only the node ports conflict with the mon ports
+	c.HostNetwork = true
+	assert.NoError(t, validateMonServicePorts(context.Background(), c, m))
+	createService("nodeport", "10.0.0.5", 8080, DefaultMsgr1Port)
+	err = validateMonServicePorts(context.Background(), c, m)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service nodeport")
+	_, err = c.createService(m)
+	assert.Error(t, err)
+}
@@ -32,6 +32,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -39,17 +40,43 @@ const (
 	monmaptoolCommand = "/usr/bin/monmaptool"
 	// Full path of the command used to invoke the Ceph mon daemon
 	cephMonCommand = "ceph-mon"
+	// Command used to pin the mon daemon to a cpuset
+	tasksetCommand = "taskset"
+	// Command used to verify the mon store before the mon daemon starts
+	monStoreToolCommand = "ceph-monstore-tool"
+	// Name of the init container that verifies the mon store
+	monStoreCheckContainerName = "check-mon-store"
+	// Name of the init container that compares the fsid of the mon store with the cluster fsid
+	monStoreFSIDCheckContainerName = "check-mon-fsid"
+	// Name of the init container that sets the ownership of the mon data dir
+	chownContainerName = "chown-container-data-dir"
 
 	monmapFile = "monmap"
 )
 
-func (c *Cluster) getLabels(daemonName string) map[string]string {
+// MonLabels returns the labels of the Kubernetes resources of a mon. The resources can be found
+// with the selector from MonLabelSelector.
+func MonLabels(namespace, monName string) map[string]string {
 	// Mons have a service for each mon, so the additional pod data is relevant for its services
 	// Use pod labels to keep "mon: id" for legacy
-	labels := opspec.PodLabels(AppName, c.Namespace, "mon", daemonName)
+	monLabels := opspec.PodLabels(AppName, namespace, monDaemonAttr, monName)
 	// Add "mon_cluster: <namespace>" for legacy
-	labels[monClusterAttr] = c.Namespace
-	return labels
+	monLabels[monClusterAttr] = namespace
+	return monLabels
+}
+
+// MonLabelSelector returns the selector of the Kubernetes resources of a mon, or of all the mons
+// in the namespace if the mon name is empty. Only labels that mons created by older versions also
+// have are selected on.
+func MonLabelSelector(namespace, monName string) labels.Selector {
+	set := labels.Set{
+		k8sutil.AppAttr: AppName,
+		monClusterAttr:  namespace,
+	}
+	if monName != "" {
+		set[monDaemonAttr] = monName
+	}
+	return labels.SelectorFromSet(set)
 }
 
 func (c *Cluster) makeDeployment(monConfig *monConfig, hostname string) *apps.Deployment {
@@ -57,19 +84,20 @@ func (c *Cluster) makeDeployment(monConfig *monConfig, hostname string) *apps.De
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      monConfig.ResourceName,
 			Namespace: c.Namespace,
-			Labels:    c.getLabels(monConfig.DaemonName),
+			Labels:    MonLabels(c.Namespace, monConfig.DaemonName),
 		},
 	}
 	k8sutil.AddRookVersionLabelToDeployment(d)
 	cephv1.GetMonAnnotations(c.spec.Annotations).ApplyToObjectMeta(&d.ObjectMeta)
 	opspec.AddCephVersionLabelToDeployment(c.ClusterInfo.CephVersion, d)
 	k8sutil.SetOwnerRef(&d.ObjectMeta, &c.ownerRef)
+	d.Annotations[monConfigHashAnnotation] = computeMonConfigHash(c, monConfig)
 
 	pod := c.makeMonPod(monConfig, hostname)
 	replicaCount := int32(1)
 	d.Spec = apps.DeploymentSpec{
 		Selector: &metav1.LabelSelector{
-			MatchLabels: c.getLabels(monConfig.DaemonName),
+			MatchLabels: MonLabels(c.Namespace, monConfig.DaemonName),
 		},
 		Template: v1.PodTemplateSpec{
 			ObjectMeta: pod.ObjectMeta,
@@ -91,7 +119,7 @@ func (c *Cluster) makeDeploymentPVC(m *monConfig) (*v1.PersistentVolumeClaim, er
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      m.ResourceName,
 			Namespace: c.Namespace,
-			Labels:    c.getLabels(m.DaemonName),
+			Labels:    MonLabels(c.Namespace, m.DaemonName),
 		},
 		Spec: v1.PersistentVolumeClaimSpec{
 			AccessModes: []v1.PersistentVolumeAccessMode{
@@ -139,6 +167,7 @@ func (c *Cluster) makeMonPod(monConfig *monConfig, hostname string) *v1.Pod {
 	podSpec := v1.PodSpec{
 		InitContainers: []v1.Container{
 			c.makeChownInitContainer(monConfig),
+			c.makeMonStoreFSIDCheckInitContainer(monConfig),
 			c.makeMonFSInitContainer(monConfig),
 		},
 		Containers: []v1.Container{
@@ -149,9 +178,18 @@ func (c *Cluster) makeMonPod(monConfig *monConfig, hostname string) *v1.Pod {
 		Volumes:       opspec.DaemonVolumesBase(monConfig.DataPathMap, keyringStoreName),
 		HostNetwork:   c.HostNetwork,
 	}
+	if c.spec.Mon.CheckStoreOnStart {
+		podSpec.InitContainers = append(podSpec.InitContainers, c.makeMonStoreCheckInitContainer(monConfig))
+	}
 	if c.HostNetwork {
 		podSpec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
+	if c.spec.Mon.DNSPolicy != nil {
+		podSpec.DNSPolicy = *c.spec.Mon.DNSPolicy
+	}
+	if c.spec.Mon.DNSConfig != nil {
+		podSpec.DNSConfig = c.spec.Mon.DNSConfig.DeepCopy()
+	}
 
 	// apply the pod placement if specified in the crd
 	// remove Pod (anti-)affinity because we have our own placement logic
@@ -164,11 +202,18 @@ func (c *Cluster) makeMonPod(monConfig *monConfig, hostname string) *v1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      monConfig.ResourceName,
 			Namespace: c.Namespace,
-			Labels:    c.getLabels(monConfig.DaemonName),
+			Labels:    MonLabels(c.Namespace, monConfig.DaemonName),
 		},
 		Spec: podSpec,
 	}
 	cephv1.GetMonAnnotations(c.spec.Annotations).ApplyToObjectMeta(&pod.ObjectMeta)
+	// the pods are restarted when the mon keyring is rotated
+	if c.monKeyVersion != "" {
+		pod.Labels[monKeyVersionAnnotation] = c.monKeyVersion
+	}
+	if c.spec.Mon.PodSecurityStandard == PodSecurityStandardRestricted {
+		applyRestrictedPodSecurity(pod)
+	}
 
 	return pod
 }
@@ -198,21 +243,21 @@ func (c *Cluster) makeChownInitContainer(monConfig *monConfig) v1.Container {
 	// Lifecycle: &v1.Lifecycle{PostStart: &v1.Handler{Exec: &v1.ExecAction{
 	// On an InitContainer :-(
 	container := v1.Container{
-		Name: "chown-container-data-dir",
+		Name: chownContainerName,
 		Command: []string{
 			"chown",
 		},
 		Args: []string{
 			"--verbose",
 			"--recursive",
-			"ceph:ceph",
+			c.monDataOwner(),
 			monConfig.DataPathMap.ContainerDataDir,
 			config.VarLogCephDir,
 		},
 		Image:           c.spec.CephVersion.Image,
 		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
 		Resources:       cephv1.GetMonResources(c.spec.Resources),
-		SecurityContext: PodSecurityContext(),
+		SecurityContext: c.chownSecurityContext(),
 	}
 	return container
 }
@@ -232,23 +277,47 @@ func (c *Cluster) makeMonFSInitContainer(monConfig *monConfig) v1.Container {
 		),
 		Image:           c.spec.CephVersion.Image,
 		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
-		SecurityContext: PodSecurityContext(),
+		SecurityContext: c.monSecurityContext(),
 		// filesystem creation does not require ports to be exposed
 		Env:       opspec.DaemonEnvVars(c.spec.CephVersion.Image),
 		Resources: cephv1.GetMonResources(c.spec.Resources),
 	}
 }
 
+func (c *Cluster) makeMonStoreCheckInitContainer(monConfig *monConfig) v1.Container {
+	// reading all the keys makes rocksdb verify the checksums of the whole store. the keys are not
+	// interesting and would flood the log.
+	checkCmd := fmt.Sprintf("%s %s dump-keys > /dev/null", monStoreToolCommand, monConfig.DataPathMap.ContainerDataDir)
+	return v1.Container{
+		Name: monStoreCheckContainerName,
+		Command: []string{
+			"/bin/sh",
+		},
+		Args: []string{
+			"-c",
+			checkCmd,
+		},
+		Image:           c.spec.CephVersion.Image,
+		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
+		SecurityContext: c.monSecurityContext(),
+		Resources:       cephv1.GetMonResources(c.spec.Resources),
+	}
+}
+
 func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 	podIPEnvVar := "ROOK_POD_IP"
 	publicAddr := monConfig.PublicIP
 
 	// Handle the non-default port for host networking. If host networking is not being used,
 	// the service created elsewhere will handle the non-default port redirection to the default port inside the container.
+	// On Nautilus the msgr2 port is given as well so that it does not collide with the msgr2 port
+	// of another mon on the node.
 	if c.HostNetwork && monConfig.Port != DefaultMsgr1Port {
-		logger.Warningf("Starting mon %s with host networking on a non-default port %d. The mon must be failed over before enabling msgr2.",
-			monConfig.DaemonName, monConfig.Port)
-		publicAddr = fmt.Sprintf("%s:%d", publicAddr, monConfig.Port)
+		logger.Infof("starting mon %s with host networking on the non-default port %d", monConfig.DaemonName, monConfig.Port)
+		publicAddr = fmt.Sprintf("%s:%d", monConfig.PublicIP, monConfig.Port)
+		if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
+			publicAddr = fmt.Sprintf("[%s%s:%d,%s%s]", msgr2AddrPrefix, monConfig.PublicIP, monMsgr2Port(monConfig.Port), msgr1AddrPrefix, publicAddr)
+		}
 	}
 
 	container := v1.Container{
@@ -262,17 +331,10 @@ func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 			// If the mon is already in the monmap, when the port is left off of --public-addr,
 			// it will still advertise on the previous port b/c monmap is saved to mon database.
 			config.NewFlag("public-addr", publicAddr),
-			// Set '--setuser-match-path' so that existing directory owned by root won't affect the daemon startup.
-			// For existing data store owned by root, the daemon will continue to run as root
-			//
-			// We use 'store.db' here because during an upgrade the init container will set 'ceph:ceph' to monConfig.DataPathMap.ContainerDataDir
-			// but inside the permissions will be 'root:root' AND we don't want to chown recursively on the mon data directory
-			// We want to avoid potential startup time issue if the store is big
-			config.NewFlag("setuser-match-path", path.Join(monConfig.DataPathMap.ContainerDataDir, "store.db")),
 		),
 		Image:           c.spec.CephVersion.Image,
 		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
-		SecurityContext: PodSecurityContext(),
+		SecurityContext: c.monSecurityContext(),
 		Ports: []v1.ContainerPort{
 			{
 				Name:          "client",
@@ -290,6 +352,19 @@ func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 		//Lifecycle: opspec.PodLifeCycle("")
 	}
 
+	// Set '--setuser-match-path' so that existing directory owned by root won't affect the daemon startup.
+	// For existing data store owned by root, the daemon will continue to run as root
+	//
+	// We use 'store.db' here because during an upgrade the init container will set 'ceph:ceph' to monConfig.DataPathMap.ContainerDataDir
+	// but inside the permissions will be 'root:root' AND we don't want to chown recursively on the mon data directory
+	// We want to avoid potential startup time issue if the store is big
+	//
+	// A mon that runs as the ceph user cannot stay root, so the whole data dir is chowned by the init container instead
+	if !monRunAsNonRoot(c.spec.Mon) {
+		container.Args = append(container.Args,
+			config.NewFlag("setuser-match-path", path.Join(monConfig.DataPathMap.ContainerDataDir, "store.db")))
+	}
+
 	// If host networking is enabled, we don't need a bind addr that is different from the public addr
 	if !c.HostNetwork {
 		// Opposite of the above, --public-bind-addr will *not* still advertise on the previous
@@ -300,12 +375,56 @@ func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 
 	// If deploying Nautilus and newer we need a new port of the monitor container
 	if c.ClusterInfo.CephVersion.IsAtLeastNautilus() {
-		addContainerPort(container, "msgr2", 3300)
+		addContainerPort(&container, "msgr2", monMsgr2Port(monConfig.Port))
+	}
+
+	if c.spec.Mon.CPUSet != "" {
+		applyMonCPUSet(&container, c.spec.Mon.CPUSet)
+	}
+
+	if c.spec.Mon.QuorumLivenessTimeoutMinutes > 0 {
+		container.LivenessProbe = makeMonQuorumLivenessProbe(monConfig.DaemonName, c.spec.Mon.QuorumLivenessTimeoutMinutes)
 	}
 
 	return container
 }
 
+// applyMonCPUSet sets the CPU affinity of the mon daemon to the CPUs of the cpuset. The CPU request
+// and limit of the container are set to the number of CPUs in the cpuset so the mon is not given more
+// CPU time than it can use. The affinity is best effort: Kubernetes cannot assign given CPUs to a
+// container, so the cgroup cpuset of the container is only exclusive if the kubelet uses the static
+// CPU manager policy and the pod has the Guaranteed QoS class, and the CPUs it assigns may not be the
+// ones in the cpuset. If the CPUs are not in the cgroup cpuset of the container, or taskset is not in
+// the image, the mon runs without the affinity.
+func applyMonCPUSet(container *v1.Container, cpuset string) {
+	size, err := cpuSetSize(cpuset)
+	if err != nil {
+		// the cpuset is validated when the mons are started
+		logger.Warningf("not pinning mon to cpuset. %+v", err)
+		return
+	}
+
+	// the resources come from the cluster spec, so don't modify them in place
+	container.Resources = *container.Resources.DeepCopy()
+	cpus := *resource.NewQuantity(int64(size), resource.DecimalSI)
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = v1.ResourceList{}
+	}
+	container.Resources.Limits[v1.ResourceCPU] = cpus
+	// equal requests and limits are needed for the static CPU manager to give the container exclusive CPUs
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = v1.ResourceList{}
+	}
+	container.Resources.Requests[v1.ResourceCPU] = cpus
+
+	// the affinity is set on the mon process and applies within the cpuset of the container's cgroup.
+	// the mon command and its args are passed to the script as $0 and $@.
+	script := fmt.Sprintf(`if %[1]s --cpu-list %[2]s true 2>/dev/null; then exec %[1]s --cpu-list %[2]s "$0" "$@"; fi
+echo "cannot set the cpu affinity of the mon to cpus %[2]s, running the mon without it"
+exec "$0" "$@"`, tasksetCommand, cpuset)
+	container.Command = append([]string{"/bin/sh", "-c", script}, container.Command...)
+}
+
 // UpdateCephDeploymentAndWait verifies a deployment can be stopped or continued
 func UpdateCephDeploymentAndWait(context *clusterd.Context, deployment *apps.Deployment, namespace, daemonType, daemonName string, cephVersion cephver.CephVersion) error {
 	callback := func(action string) error {
@@ -30,6 +30,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestPodSpecs(t *testing.T) {
@@ -145,3 +146,177 @@ func TestDeploymentPVCSpec(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, pvc.Spec.Resources.Requests[v1.ResourceStorage], req)
 }
+
+func TestMonCPUSet(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	c.spec.Resources = map[string]v1.ResourceRequirements{
+		"mon": {
+			Limits:   v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(2, resource.DecimalSI)},
+			Requests: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(10, resource.DecimalSI)},
+		},
+	}
+
+	// no pinning by default
+	container := c.makeMonDaemonContainer(testGenMonConfig("a"))
+	assert.Equal(t, []string{cephMonCommand}, container.Command)
+
+	// the cpu limit matches the size of the cpuset
+	c.spec.Mon.CPUSet = "0-3,8-11"
+	container = c.makeMonDaemonContainer(testGenMonConfig("a"))
+	assert.Equal(t, 4, len(container.Command))
+	assert.Equal(t, []string{"/bin/sh", "-c"}, container.Command[:2])
+	assert.Contains(t, container.Command[2], `then exec taskset --cpu-list 0-3,8-11 "$0" "$@"; fi`)
+	// the mon runs without the affinity if it cannot be set
+	assert.Contains(t, container.Command[2], "\nexec \"$0\" \"$@\"")
+	assert.Equal(t, cephMonCommand, container.Command[3])
+	assert.Equal(t, int64(8), container.Resources.Limits.Cpu().Value())
+	assert.Equal(t, int64(8), container.Resources.Requests.Cpu().Value())
+	// the resources in the cluster spec are not modified
+	limit := c.spec.Resources["mon"].Limits[v1.ResourceCPU]
+	assert.Equal(t, int64(2), limit.Value())
+
+	// the cpu request is raised to the limit so the pod can get exclusive cpus
+	c.spec.Resources = map[string]v1.ResourceRequirements{}
+	c.spec.Mon.CPUSet = "2"
+	container = c.makeMonDaemonContainer(testGenMonConfig("a"))
+	assert.Equal(t, int64(1), container.Resources.Limits.Cpu().Value())
+	assert.Equal(t, int64(1), container.Resources.Requests.Cpu().Value())
+
+	// an invalid cpuset is not applied
+	c.spec.Resources = map[string]v1.ResourceRequirements{
+		"mon": {Limits: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(2, resource.DecimalSI)}},
+	}
+	c.spec.Mon.CPUSet = "0-"
+	container = c.makeMonDaemonContainer(testGenMonConfig("a"))
+	assert.Equal(t, []string{cephMonCommand}, container.Command)
+	assert.Equal(t, int64(2), container.Resources.Limits.Cpu().Value())
+}
+
+func TestMonDNS(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// the kubernetes default by default
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Equal(t, v1.DNSPolicy(""), d.Spec.Template.Spec.DNSPolicy)
+	assert.Nil(t, d.Spec.Template.Spec.DNSConfig)
+
+	// host networking needs a dns policy to resolve the cluster services
+	c.HostNetwork = true
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, d.Spec.Template.Spec.DNSPolicy)
+
+	// the dns settings in the mon spec are applied
+	policy := v1.DNSNone
+	c.spec.Mon.DNSPolicy = &policy
+	c.spec.Mon.DNSConfig = &v1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"ceph.example.com"},
+	}
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Equal(t, v1.DNSNone, d.Spec.Template.Spec.DNSPolicy)
+	assert.Equal(t, []string{"10.0.0.10"}, d.Spec.Template.Spec.DNSConfig.Nameservers)
+	assert.Equal(t, []string{"ceph.example.com"}, d.Spec.Template.Spec.DNSConfig.Searches)
+}
+
+func TestMonStoreCheck(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// the store is not checked by default
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Equal(t, 3, len(d.Spec.Template.Spec.InitContainers))
+
+	// the store is checked by the last init container so the mon daemon does not start on a corrupted store
+	c.spec.Mon.CheckStoreOnStart = true
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Equal(t, 4, len(d.Spec.Template.Spec.InitContainers))
+	check := d.Spec.Template.Spec.InitContainers[3]
+	assert.Equal(t, monStoreCheckContainerName, check.Name)
+	assert.Equal(t, []string{"-c", "ceph-monstore-tool /var/lib/ceph/mon/ceph-a dump-keys > /dev/null"}, check.Args)
+}
+
+func TestMonQuorumLiveness(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+
+	// there is no liveness probe by default
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	assert.Nil(t, d.Spec.Template.Spec.Containers[0].LivenessProbe)
+
+	// the probe checks the quorum participation of the mon on its admin socket
+	c.spec.Mon.QuorumLivenessTimeoutMinutes = 5
+	d = c.makeDeployment(testGenMonConfig("a"), "node0")
+	probe := d.Spec.Template.Spec.Containers[0].LivenessProbe
+	assert.NotNil(t, probe)
+	assert.Equal(t, int32(monQuorumLivenessPeriodSeconds), probe.PeriodSeconds)
+	command := probe.Handler.Exec.Command
+	assert.Equal(t, []string{"/bin/sh", "-c"}, command[:2])
+	assert.Contains(t, command[2], "ceph --admin-daemon /var/run/ceph/ceph-mon.a.asok mon_status")
+	assert.Contains(t, command[2], `grep -Eq '"state": "(leader|peon)"'`)
+	assert.Contains(t, command[2], "echo $now > /tmp/mon-last-in-quorum")
+	assert.Contains(t, command[2], "[ $(( now - last )) -lt 300 ]")
+
+	c.spec.Mon.QuorumLivenessTimeoutMinutes = -1
+	assert.Error(t, c.validateSpec())
+}
+
+func TestCPUSetSize(t *testing.T) {
+	for cpuset, expected := range map[string]int{
+		"0":        1,
+		"0-3":      4,
+		"0-3,8-11": 8,
+		"1,1,0-1":  2,
+		" 2, 4-5 ": 3,
+	} {
+		size, err := cpuSetSize(cpuset)
+		assert.NoError(t, err, cpuset)
+		assert.Equal(t, expected, size, cpuset)
+	}
+
+	for _, cpuset := range []string{"", "a", "-1", "3-1", "0-", "0,,1", "0-3-5", "0-100000"} {
+		_, err := cpuSetSize(cpuset)
+		assert.Error(t, err, cpuset)
+	}
+}
+
+func TestMonLabels(t *testing.T) {
+	clientset := testop.New(1)
+	c := New(&clusterd.Context{Clientset: clientset}, "ns", "/var/lib/rook", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{}
+	monConfig := testGenMonConfig("a")
+	expected := MonLabels("ns", "a")
+
+	// all the resources of the mon have the mon labels. the deployment and pvc also have version labels.
+	assertMonLabels := func(actual map[string]string) {
+		for key, value := range expected {
+			assert.Equal(t, value, actual[key], key)
+		}
+		assert.True(t, MonLabelSelector("ns", "a").Matches(labels.Set(actual)))
+	}
+	d := c.makeDeployment(monConfig, "node0")
+	assertMonLabels(d.Labels)
+	assert.Equal(t, expected, d.Spec.Selector.MatchLabels)
+	assertMonLabels(d.Spec.Template.Labels)
+	pvc, err := c.makeDeploymentPVC(monConfig)
+	assert.NoError(t, err)
+	assertMonLabels(pvc.Labels)
+	_, err = c.createService(monConfig)
+	assert.NoError(t, err)
+	svc, err := clientset.CoreV1().Services("ns").Get(monConfig.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, svc.Labels)
+	assert.Equal(t, expected, svc.Spec.Selector)
+
+	// the selectors match the labels of the mon
+	assert.True(t, MonLabelSelector("ns", "a").Matches(labels.Set(expected)))
+	assert.True(t, MonLabelSelector("ns", "").Matches(labels.Set(expected)))
+	assert.False(t, MonLabelSelector("ns", "b").Matches(labels.Set(expected)))
+	assert.False(t, MonLabelSelector("other", "").Matches(labels.Set(expected)))
+
+	// mons created by older versions only had the legacy labels
+	legacy := map[string]string{"app": AppName, "mon_cluster": "ns", "mon": "a"}
+	assert.True(t, MonLabelSelector("ns", "a").Matches(labels.Set(legacy)))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// monStorageClassAccessModesAnnotation can be set on a storage class to declare the access modes
+	// that its volumes support, as a comma separated list such as "ReadWriteOnce,ReadWriteMany"
+	monStorageClassAccessModesAnnotation = "ceph.rook.io/access-modes"
+)
+
+// provisionerAccessModes are the access modes supported by the volumes of well known provisioners.
+// The storage class api has no field for the access modes, so the modes of other provisioners are
+// only known if they are declared with the access modes annotation.
+var provisionerAccessModes = map[string][]v1.PersistentVolumeAccessMode{
+	"kubernetes.io/aws-ebs":        {v1.ReadWriteOnce},
+	"kubernetes.io/azure-disk":     {v1.ReadWriteOnce},
+	"kubernetes.io/azure-file":     {v1.ReadWriteOnce, v1.ReadOnlyMany, v1.ReadWriteMany},
+	"kubernetes.io/cinder":         {v1.ReadWriteOnce},
+	"kubernetes.io/gce-pd":         {v1.ReadWriteOnce, v1.ReadOnlyMany},
+	"kubernetes.io/glusterfs":      {v1.ReadWriteOnce, v1.ReadOnlyMany, v1.ReadWriteMany},
+	"kubernetes.io/no-provisioner": {v1.ReadWriteOnce},
+	"kubernetes.io/rbd":            {v1.ReadWriteOnce, v1.ReadOnlyMany},
+	"kubernetes.io/vsphere-volume": {v1.ReadWriteOnce},
+}
+
+// validateMonStorageClass checks that the volumes of the storage class can be mounted with the
+// ReadWriteOnce access mode of the mon PVCs. A storage class whose access modes are not known is
+// accepted.
+func (c *Cluster) validateMonStorageClass(ctx context.Context, storageClassName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	storageClass, err := c.context.Clientset.StorageV1().StorageClasses().Get(storageClassName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get storage class %s. %+v", storageClassName, err)
+	}
+
+	modes, ok := provisionerAccessModes[storageClass.Provisioner]
+	if declared, found := storageClass.Annotations[monStorageClassAccessModesAnnotation]; found {
+		modes = []v1.PersistentVolumeAccessMode{}
+		for _, mode := range strings.Split(declared, ",") {
+			modes = append(modes, v1.PersistentVolumeAccessMode(strings.TrimSpace(mode)))
+		}
+	} else if !ok {
+		logger.Infof("the access modes of provisioner %s of storage class %s are not known. assuming the mon pvcs are supported", storageClass.Provisioner, storageClassName)
+		return nil
+	}
+
+	for _, mode := range modes {
+		if mode == v1.ReadWriteOnce {
+			return nil
+		}
+	}
+	return fmt.Errorf("storage class %s only supports the access modes %v. the mon pvcs require %s", storageClassName, modes, v1.ReadWriteOnce)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateMonStorageClass(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	createClass := func(name, provisioner string, annotations map[string]string) {
+		class := &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Provisioner: provisioner,
+		}
+		_, err := clientset.StorageV1().StorageClasses().Create(class)
+		assert.NoError(t, err)
+	}
+	ctx := context.Background()
+
+	// a storage class that does not exist
+	assert.Error(t, c.validateMonStorageClass(ctx, "gp2"))
+
+	// a known provisioner with block volumes
+	createClass("gp2", "kubernetes.io/aws-ebs", nil)
+	assert.NoError(t, c.validateMonStorageClass(ctx, "gp2"))
+
+	// a provisioner whose access modes are not known
+	createClass("custom", "example.com/custom", nil)
+	assert.NoError(t, c.validateMonStorageClass(ctx, "custom"))
+
+	// the access modes declared on the class
+	createClass("shared", "example.com/shared", map[string]string{monStorageClassAccessModesAnnotation: "ReadWriteMany, ReadOnlyMany"})
+	err := c.validateMonStorageClass(ctx, "shared")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "storage class shared only supports the access modes [ReadWriteMany ReadOnlyMany]")
+	createClass("both", "example.com/shared", map[string]string{monStorageClassAccessModesAnnotation: "ReadWriteMany,ReadWriteOnce"})
+	assert.NoError(t, c.validateMonStorageClass(ctx, "both"))
+
+	// the declared modes override the modes of a known provisioner
+	createClass("ebs-shared", "kubernetes.io/aws-ebs", map[string]string{monStorageClassAccessModesAnnotation: "ReadWriteMany"})
+	assert.Error(t, c.validateMonStorageClass(ctx, "ebs-shared"))
+
+	// a mon pvc is not created for an incompatible class
+	className := "shared"
+	c.spec.Mon.VolumeClaimTemplate = &v1.PersistentVolumeClaim{Spec: v1.PersistentVolumeClaimSpec{StorageClassName: &className}}
+	c.ClusterInfo = test.CreateConfigDir(1)
+	err = c.startMon(testGenMonConfig("a"), "node0")
+	assert.Error(t, err)
+	_, err = clientset.CoreV1().PersistentVolumeClaims("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// the pvc is created for a compatible class
+	className = "gp2"
+	assert.NoError(t, c.startMon(testGenMonConfig("a"), "node0"))
+	_, err = clientset.CoreV1().PersistentVolumeClaims("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
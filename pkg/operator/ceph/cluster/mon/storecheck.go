@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// checkMonStores reports the mons whose store failed the consistency check of the mon pod. The mon
+// daemon of such a mon is never started, so the mon stays out of quorum until it is failed over. A
+// Warning event is recorded when the corruption of a mon store is first detected.
+func (c *Cluster) checkMonStores(pods []v1.Pod) {
+	if !c.spec.Mon.CheckStoreOnStart {
+		return
+	}
+
+	corrupted := map[string]string{}
+	for _, pod := range pods {
+		name, ok := pod.Labels[monDaemonAttr]
+		if !ok {
+			continue
+		}
+		exitCode, failed := monStoreCheckFailed(pod)
+		if !failed {
+			continue
+		}
+
+		msg := fmt.Sprintf("the store of mon %s failed the consistency check with exit code %d and the mon will not be started. "+
+			"recover the mon from the healthy mons by failing it over", name, exitCode)
+		logger.Errorf(msg)
+		if _, ok := c.corruptedMons[name]; !ok {
+			c.recordMonEvent(name, v1.EventTypeWarning, "MonStoreCorrupted", msg)
+		}
+		corrupted[name] = msg
+	}
+	c.corruptedMons = corrupted
+}
+
+// monStoreCheckFailed returns whether the mon store check init container of the pod has failed,
+// and its exit code
+func monStoreCheckFailed(pod v1.Pod) (int32, bool) {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != monStoreCheckContainerName {
+			continue
+		}
+		if status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode, status.State.Terminated.ExitCode != 0
+		}
+		// the init container is restarted after it fails, so it may be waiting with the failure as
+		// its last state
+		last := status.LastTerminationState.Terminated
+		if status.State.Waiting != nil && last != nil && last.ExitCode != 0 {
+			return last.ExitCode, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+func monPods(t *testing.T, clientset kubernetes.Interface, namespace string) []v1.Pod {
+	pods, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: MonLabelSelector(namespace, "").String()})
+	assert.NoError(t, err)
+	return pods.Items
+}
+
+func TestCheckMonStores(t *testing.T) {
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset}, "ns", false, true, v1.ResourceRequirements{})
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	monPod := func(name string, status v1.ContainerStatus) *v1.Pod {
+		status.Name = monStoreCheckContainerName
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-" + name, Namespace: c.Namespace, Labels: MonLabels(c.Namespace, name)},
+			Status:     v1.PodStatus{InitContainerStatuses: []v1.ContainerStatus{status}},
+		}
+		_, err := clientset.CoreV1().Pods(c.Namespace).Create(pod)
+		assert.Nil(t, err)
+		return pod
+	}
+	failed := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+	// a is healthy, b failed the check, c is waiting to retry the failed check, d is still checking
+	monPod("a", v1.ContainerStatus{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}})
+	monPod("b", v1.ContainerStatus{State: failed})
+	monPod("c", v1.ContainerStatus{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}, LastTerminationState: failed})
+	monPod("d", v1.ContainerStatus{State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}, LastTerminationState: failed})
+
+	// nothing is reported if the check is disabled
+	c.checkMonStores(monPods(t, clientset, c.Namespace))
+	assert.Empty(t, c.corruptedMons)
+
+	c.spec.Mon.CheckStoreOnStart = true
+	c.checkMonStores(monPods(t, clientset, c.Namespace))
+	assert.Equal(t, 2, len(c.corruptedMons))
+	assert.Contains(t, c.corruptedMons, "b")
+	assert.Contains(t, c.corruptedMons, "c")
+	assert.Equal(t, 2, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "MonStoreCorrupted")
+
+	// the event is only recorded once
+	c.checkMonStores(monPods(t, clientset, c.Namespace))
+	assert.Equal(t, 2, len(c.corruptedMons))
+	assert.Equal(t, 1, len(recorder.Events))
+
+	// the mon is forgotten once the pod with the failed check is gone
+	err := clientset.CoreV1().Pods(c.Namespace).Delete("rook-ceph-mon-b", &metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	c.checkMonStores(monPods(t, clientset, c.Namespace))
+	assert.Equal(t, 1, len(c.corruptedMons))
+	assert.Contains(t, c.corruptedMons, "c")
+}
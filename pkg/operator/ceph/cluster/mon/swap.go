@@ -0,0 +1,273 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"time"
+
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	monDataVolumeName        = "ceph-daemon-data"
+	monStorageSwapAppName    = "rook-ceph-mon-storage-swap"
+	monStorageSwapOldVolume  = "old-mon-data"
+	monStorageSwapNewVolume  = "new-mon-data"
+	monStorageSwapOldDataDir = "/var/lib/ceph/mon-old"
+	monStorageSwapNewDataDir = "/var/lib/ceph/mon-new"
+	monStorageSwapTimeout    = 30 * time.Minute
+)
+
+// waitForMonStorageCopy waits for the job that copies the store of a mon to a new volume. It is a
+// variable so the unit tests do not need to wait for a job to run.
+var waitForMonStorageCopy = k8sutil.WaitForJobCompletion
+
+// SwapMonStorage moves the store of a mon to the volume of a new PVC without replacing the mon. The
+// mon is stopped, a job copies the store from the current volume of the mon to the new volume, and
+// the mon is started on the new volume. All the mons must be in quorum before the mon is stopped,
+// and the mon must join the quorum again after it is started. If the store cannot be copied or the
+// mon does not join the quorum on the new volume, the mon is started again on its current volume.
+func (c *Cluster) SwapMonStorage(name, newClaimName string) error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	if _, ok := c.ClusterInfo.Monitors[name]; !ok {
+		return fmt.Errorf("mon %s not found", name)
+	}
+	if _, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(c.Namespace).Get(newClaimName, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get the new pvc %s of mon %s. %+v", newClaimName, name, err)
+	}
+	d, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(resourceName(name), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the deployment of mon %s. %+v", name, err)
+	}
+	oldVolume, ok := monDataVolume(d)
+	if !ok {
+		return fmt.Errorf("the deployment of mon %s has no data volume", name)
+	}
+	if oldVolume.PersistentVolumeClaim != nil && oldVolume.PersistentVolumeClaim.ClaimName == newClaimName {
+		return fmt.Errorf("mon %s already uses pvc %s", name, newClaimName)
+	}
+
+	if _, err := c.waitForFullQuorum(); err != nil {
+		return fmt.Errorf("cannot safely stop mon %s. %+v", name, err)
+	}
+
+	logger.Infof("stopping mon %s to move its store to pvc %s", name, newClaimName)
+	if err := c.scaleMonDeployment(d, 0); err != nil {
+		return fmt.Errorf("failed to stop mon %s. %+v", name, err)
+	}
+
+	if err := c.copyMonStore(name, d, newClaimName); err != nil {
+		logger.Errorf("failed to copy the store of mon %s. starting the mon on its current volume. %+v", name, err)
+		if err := c.scaleMonDeployment(d, 1); err != nil {
+			logger.Errorf("failed to start mon %s again. %+v", name, err)
+		}
+		return fmt.Errorf("failed to copy the store of mon %s to pvc %s. %+v", name, newClaimName, err)
+	}
+
+	logger.Infof("starting mon %s on pvc %s", name, newClaimName)
+	oldPodSpec := d.Spec.Template.Spec.DeepCopy()
+	useMonDataPVC(&d.Spec.Template.Spec, newClaimName, c.HostNetwork)
+	if err := c.scaleMonDeployment(d, 1); err != nil {
+		return fmt.Errorf("failed to start mon %s on pvc %s. %+v", name, newClaimName, err)
+	}
+
+	status, err := c.waitForFullQuorum()
+	if err == nil && !monFoundInQuorum(name, status) {
+		err = fmt.Errorf("mon %s not in quorum", name)
+	}
+	if err != nil {
+		// the store on the current volume was not changed by the copy
+		logger.Errorf("mon %s did not join the quorum on pvc %s. starting the mon on its current volume. %+v", name, newClaimName, err)
+		if err := c.startMonOnPodSpec(d, oldPodSpec); err != nil {
+			logger.Errorf("failed to start mon %s again on its current volume. %+v", name, err)
+		}
+		return fmt.Errorf("quorum not restored after moving the store of mon %s to pvc %s. %+v", name, newClaimName, err)
+	}
+	logger.Infof("moved the store of mon %s to pvc %s", name, newClaimName)
+	return nil
+}
+
+// startMonOnPodSpec stops the mon and starts it again with the pod spec
+func (c *Cluster) startMonOnPodSpec(d *apps.Deployment, podSpec *v1.PodSpec) error {
+	if err := c.scaleMonDeployment(d, 0); err != nil {
+		return err
+	}
+	d.Spec.Template.Spec = *podSpec
+	return c.scaleMonDeployment(d, 1)
+}
+
+// monDataVolume returns the volume of the mon store in the mon deployment
+func monDataVolume(d *apps.Deployment) (v1.VolumeSource, bool) {
+	for _, volume := range d.Spec.Template.Spec.Volumes {
+		if volume.Name == monDataVolumeName {
+			return volume.VolumeSource, true
+		}
+	}
+	return v1.VolumeSource{}, false
+}
+
+// useMonDataPVC replaces the data volume of a mon pod with the pvc. The mon data follows the pod to
+// any node like for a mon created on a pvc.
+func useMonDataPVC(podSpec *v1.PodSpec, claimName string, hostNetwork bool) {
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == monDataVolumeName {
+			podSpec.Volumes[i] = opspec.DaemonVolumesDataPVC(claimName)
+		}
+	}
+	opspec.AddVolumeMountSubPath(podSpec, monDataVolumeName)
+	if !hostNetwork {
+		delete(podSpec.NodeSelector, v1.LabelHostname)
+		if len(podSpec.NodeSelector) == 0 {
+			podSpec.NodeSelector = nil
+		}
+	}
+}
+
+// scaleMonDeployment sets the replicas of the mon deployment and waits for the mon pods to match. The
+// deployment is updated in place.
+func (c *Cluster) scaleMonDeployment(d *apps.Deployment, replicas int32) error {
+	d.Spec.Replicas = &replicas
+	updated, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Update(d)
+	if err != nil {
+		return fmt.Errorf("failed to update deployment %s. %+v", d.Name, err)
+	}
+	*d = *updated
+	if replicas > 0 {
+		// the quorum is checked by the caller once the mon is started
+		return nil
+	}
+
+	selector := MonLabelSelector(c.Namespace, d.Labels[monDaemonAttr]).String()
+	start := time.Now()
+	for {
+		pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			logger.Debugf("failed to list the pods of deployment %s. %+v", d.Name, err)
+		} else if len(pods.Items) == 0 {
+			return nil
+		}
+
+		if time.Since(start) > c.monPodTimeout {
+			return fmt.Errorf("timed out waiting for the pods of deployment %s to stop", d.Name)
+		}
+		<-time.After(c.monPodRetryInterval)
+	}
+}
+
+// copyMonStore runs a job that copies the store of the stopped mon from the data volume of its
+// deployment to the pvc. The job fails rather than overwrite a mon store on the pvc.
+func (c *Cluster) copyMonStore(name string, d *apps.Deployment, claimName string) error {
+	return c.runMonStoreCopyJob(c.makeMonStorageSwapJob(name, d, claimName))
+}
+
+// runMonStoreCopyJob runs the job that copies a mon store and waits for it to complete
+func (c *Cluster) runMonStoreCopyJob(job *batch.Job) error {
+	if err := k8sutil.RunReplaceableJob(c.context.Clientset, job, true); err != nil {
+		return fmt.Errorf("failed to run job %s. %+v", job.Name, err)
+	}
+	if err := waitForMonStorageCopy(c.context.Clientset, job, monStorageSwapTimeout); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteBatchJob(c.context.Clientset, c.Namespace, job.Name, false); err != nil {
+		logger.Warningf("failed to delete job %s. %+v", job.Name, err)
+	}
+	return nil
+}
+
+func (c *Cluster) makeMonStorageSwapJob(name string, d *apps.Deployment, claimName string) *batch.Job {
+	job := c.makeMonStoreCopyJob(monStorageSwapAppName, name, d, v1.VolumeSource{
+		PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+	})
+	opspec.AddVolumeMountSubPath(&job.Spec.Template.Spec, monStorageSwapNewVolume)
+	return job
+}
+
+// makeMonStoreCopyJob makes a job named after the app that copies the store of the stopped mon from
+// the data volume of its deployment to the new volume
+func (c *Cluster) makeMonStoreCopyJob(appName, name string, d *apps.Deployment, newVolume v1.VolumeSource) *batch.Job {
+	oldVolume, _ := monDataVolume(d)
+	// the old store is mounted with the same sub path as in the mon container
+	oldMount := v1.VolumeMount{Name: monStorageSwapOldVolume, MountPath: monStorageSwapOldDataDir}
+	for _, container := range d.Spec.Template.Spec.Containers {
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == monDataVolumeName {
+				oldMount.SubPath = mount.SubPath
+			}
+		}
+	}
+
+	// a store on the host can only be copied on the node of the mon
+	nodeSelector := map[string]string{}
+	for key, value := range d.Spec.Template.Spec.NodeSelector {
+		nodeSelector[key] = value
+	}
+
+	copyCmd := fmt.Sprintf("if [ -e %[2]s/store.db ]; then echo \"a mon store already exists on the new volume\"; exit 1; fi; cp -a %[1]s/. %[2]s/",
+		monStorageSwapOldDataDir, monStorageSwapNewDataDir)
+	podSpec := v1.PodSpec{
+		Containers: []v1.Container{
+			{
+				Name:    "copy-mon-store",
+				Command: []string{"/bin/sh"},
+				Args:    []string{"-c", copyCmd},
+				Image:   c.spec.CephVersion.Image,
+				VolumeMounts: []v1.VolumeMount{
+					oldMount,
+					{Name: monStorageSwapNewVolume, MountPath: monStorageSwapNewDataDir},
+				},
+				SecurityContext: PodSecurityContext(),
+			},
+		},
+		Volumes: []v1.Volume{
+			{Name: monStorageSwapOldVolume, VolumeSource: oldVolume},
+			{Name: monStorageSwapNewVolume, VolumeSource: newVolume},
+		},
+		RestartPolicy: v1.RestartPolicyOnFailure,
+		NodeSelector:  nodeSelector,
+		Tolerations:   d.Spec.Template.Spec.Tolerations,
+	}
+
+	labels := map[string]string{
+		k8sutil.AppAttr:     appName,
+		k8sutil.ClusterAttr: c.Namespace,
+		monDaemonAttr:       name,
+	}
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", appName, name),
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+	k8sutil.AddRookVersionLabelToJob(job)
+	k8sutil.SetOwnerRef(&job.ObjectMeta, &c.ownerRef)
+	return job
+}
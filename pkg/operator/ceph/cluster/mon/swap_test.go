@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestSwapMonStorage(t *testing.T) {
+	defer func() { waitForMonStorageCopy = k8sutil.WaitForJobCompletion }()
+
+	inQuorum := true
+	events := []string{}
+	clientset := test.New(3)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "mon_status" {
+				events = append(events, "mon_status")
+				quorum := "[0,1,2]"
+				if !inQuorum {
+					quorum = "[1,2]"
+				}
+				return fmt.Sprintf(`{"quorum":%s,"monmap":{"mons":[{"name":"a","rank":0},{"name":"b","rank":1},{"name":"c","rank":2}]}}`, quorum), nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	c.ClusterInfo.CephVersion = cephver.Nautilus
+	c.spec.CephVersion.Image = "ceph/ceph:v14"
+	m := testGenMonConfig("a")
+	assert.NoError(t, c.startMon(m, "node0"))
+	_, err := clientset.CoreV1().PersistentVolumeClaims("ns").Create(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "new-a", Namespace: "ns"}})
+	assert.NoError(t, err)
+
+	var copyJob *batch.Job
+	copyErr := error(nil)
+	waitForMonStorageCopy = func(clientset kubernetes.Interface, job *batch.Job, timeout time.Duration) error {
+		// the mon is stopped while the store is copied
+		d, err := clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), *d.Spec.Replicas)
+		events = append(events, "copy")
+		copyJob = job
+		return copyErr
+	}
+
+	// the mon pvc must exist
+	assert.Error(t, c.SwapMonStorage("a", "missing"))
+	assert.Error(t, c.SwapMonStorage("z", "new-a"))
+	assert.Empty(t, events)
+
+	// the store is copied from the host path to the new pvc
+	events = []string{}
+	assert.NoError(t, c.SwapMonStorage("a", "new-a"))
+	assert.Equal(t, []string{"mon_status", "copy", "mon_status"}, events)
+	assert.Equal(t, "rook-ceph-mon-storage-swap-a", copyJob.Name)
+	jobPod := copyJob.Spec.Template.Spec
+	assert.Equal(t, map[string]string{v1.LabelHostname: "node0"}, jobPod.NodeSelector)
+	assert.Equal(t, m.DataPathMap.HostDataDir, jobPod.Volumes[0].HostPath.Path)
+	assert.Equal(t, "new-a", jobPod.Volumes[1].PersistentVolumeClaim.ClaimName)
+	assert.Contains(t, jobPod.Containers[0].Args[1], "cp -a /var/lib/ceph/mon-old/. /var/lib/ceph/mon-new/")
+	assert.Equal(t, "", jobPod.Containers[0].VolumeMounts[0].SubPath)
+	assert.Equal(t, "data", jobPod.Containers[0].VolumeMounts[1].SubPath)
+
+	// the mon is recreated on the new volume
+	d, err := clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	volume, ok := monDataVolume(d)
+	assert.True(t, ok)
+	assert.Nil(t, volume.HostPath)
+	assert.Equal(t, "new-a", volume.PersistentVolumeClaim.ClaimName)
+	assert.Nil(t, d.Spec.Template.Spec.NodeSelector)
+
+	// the mon keeps the new pvc when the mons are reconciled
+	assert.NoError(t, c.startMon(m, "node0"))
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	volume, _ = monDataVolume(d)
+	assert.Equal(t, "new-a", volume.PersistentVolumeClaim.ClaimName)
+
+	// the same pvc is not swapped again
+	assert.Error(t, c.SwapMonStorage("a", "new-a"))
+
+	// the mon is started on its current volume if the copy fails
+	_, err = clientset.CoreV1().PersistentVolumeClaims("ns").Create(&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "new-a2", Namespace: "ns"}})
+	assert.NoError(t, err)
+	copyErr = fmt.Errorf("copy failed")
+	events = []string{}
+	assert.Error(t, c.SwapMonStorage("a", "new-a2"))
+	assert.Equal(t, []string{"mon_status", "copy"}, events)
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	volume, _ = monDataVolume(d)
+	assert.Equal(t, "new-a", volume.PersistentVolumeClaim.ClaimName)
+	// the old store on the pvc is mounted with the sub path of the mon container
+	assert.Equal(t, "new-a", copyJob.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "data", copyJob.Spec.Template.Spec.Containers[0].VolumeMounts[0].SubPath)
+
+	// the mon is started on its current volume if it does not join the quorum on the new volume
+	copyErr = nil
+	c.monPodTimeout = 10 * time.Millisecond
+	waitForMonStorageCopy = func(clientset kubernetes.Interface, job *batch.Job, timeout time.Duration) error {
+		inQuorum = false
+		return nil
+	}
+	assert.Error(t, c.SwapMonStorage("a", "new-a2"))
+	d, err = clientset.AppsV1().Deployments("ns").Get(m.ResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	volume, _ = monDataVolume(d)
+	assert.Equal(t, "new-a", volume.PersistentVolumeClaim.ClaimName)
+
+	// the mon is not stopped without a full quorum
+	events = []string{}
+	assert.Error(t, c.SwapMonStorage("a", "new-a2"))
+	assert.NotContains(t, events, "copy")
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// the taints that kubernetes sets on failed or unreachable nodes. a mon must not tolerate them so
+// that it is failed over instead of staying on a broken node.
+var kubernetesTaintPrefixes = []string{"node.kubernetes.io/", "node.cloudprovider.kubernetes.io/"}
+
+// monTolerationSynchronizer keeps the tolerations of the mon deployments consistent with the taints of
+// the nodes the mons are assigned to. A mon tolerates the taints that are added to its node after the
+// mon was placed, and the tolerations are removed again when the taints are removed.
+type monTolerationSynchronizer struct {
+	cluster *Cluster
+}
+
+// WatchNodeTaints updates the tolerations of the mon deployments when the taints of the nodes with
+// mons change, until the stop channel is closed
+func (c *Cluster) WatchNodeTaints(stopCh chan struct{}) {
+	s := &monTolerationSynchronizer{cluster: c}
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return c.context.Clientset.CoreV1().Nodes().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.context.Clientset.CoreV1().Nodes().Watch(options)
+		},
+	}
+	_, controller := cache.NewInformer(lw, &v1.Node{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.onNodeAdd,
+		UpdateFunc: s.onNodeUpdate,
+	})
+	controller.Run(stopCh)
+}
+
+// onNodeAdd syncs the tolerations of the mons on a node the informer lists, which covers taints that
+// changed while the operator was not running
+func (s *monTolerationSynchronizer) onNodeAdd(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok || !s.hostsMon(node.Name) {
+		return
+	}
+	if _, err := s.sync(); err != nil {
+		logger.Warningf("failed to sync the mon tolerations with the taints of node %s. %+v", node.Name, err)
+	}
+}
+
+func (s *monTolerationSynchronizer) onNodeUpdate(oldObj, newObj interface{}) {
+	oldNode, ok := oldObj.(*v1.Node)
+	if !ok {
+		return
+	}
+	newNode, ok := newObj.(*v1.Node)
+	if !ok {
+		return
+	}
+	if reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) || !s.hostsMon(newNode.Name) {
+		return
+	}
+	logger.Infof("the taints of node %s changed. syncing the mon tolerations", newNode.Name)
+	if _, err := s.sync(); err != nil {
+		logger.Warningf("failed to sync the mon tolerations with the taints of node %s. %+v", newNode.Name, err)
+	}
+}
+
+// hostsMon returns whether a mon is assigned to the node
+func (s *monTolerationSynchronizer) hostsMon(nodeName string) bool {
+	s.cluster.acquireOrchestrationLock()
+	defer s.cluster.releaseOrchestrationLock()
+	for _, info := range s.cluster.mapping.Node {
+		if info != nil && info.Name == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// sync updates the tolerations of each mon deployment to the tolerations of the mon placement and
+// the taints of the node of the mon. The names of the mons whose deployments were updated are
+// returned.
+func (s *monTolerationSynchronizer) sync() ([]string, error) {
+	c := s.cluster
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	// the mons are not known before they are started
+	if c.ClusterInfo == nil {
+		return nil, nil
+	}
+
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: MonLabelSelector(c.Namespace, "").String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the mon deployments. %+v", err)
+	}
+
+	updated := []string{}
+	nodes := map[string]*v1.Node{}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		name := d.Labels[monDaemonAttr]
+		info, ok := c.mapping.Node[name]
+		if !ok || info == nil {
+			continue
+		}
+		node, ok := nodes[info.Name]
+		if !ok {
+			node, err = c.context.Clientset.CoreV1().Nodes().Get(info.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				logger.Warningf("node %s of mon %s not found. not syncing the mon tolerations", info.Name, name)
+				continue
+			} else if err != nil {
+				return updated, fmt.Errorf("failed to get node %s of mon %s. %+v", info.Name, name, err)
+			}
+			nodes[info.Name] = node
+		}
+
+		tolerations := c.monTolerations(node)
+		if tolerationsEqual(d.Spec.Template.Spec.Tolerations, tolerations) {
+			continue
+		}
+		logger.Infof("updating the tolerations of mon %s to match the taints of node %s", name, node.Name)
+		d.Spec.Template.Spec.Tolerations = tolerations
+		if err := updateDeploymentAndWait(c.context, d, c.Namespace, string(config.MonType), name, c.ClusterInfo.CephVersion); err != nil {
+			return updated, fmt.Errorf("failed to update the tolerations of mon %s. %+v", name, err)
+		}
+		updated = append(updated, name)
+	}
+	return updated, nil
+}
+
+// monTolerations returns the tolerations of a mon on the node. These are the tolerations of the mon
+// placement and a toleration for each taint of the node that the placement does not tolerate, except
+// the taints kubernetes sets on failed nodes and the taint of a fenced mon.
+func (c *Cluster) monTolerations(node *v1.Node) []v1.Toleration {
+	tolerations := append([]v1.Toleration{}, cephv1.GetMonPlacement(c.spec.Placement).Tolerations...)
+	placementTolerations := len(tolerations)
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule || isKubernetesTaint(taint.Key) || taint.Key == monFencedTaintKey {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range tolerations[:placementTolerations] {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if tolerated {
+			continue
+		}
+		tolerations = append(tolerations, v1.Toleration{
+			Key:      taint.Key,
+			Operator: v1.TolerationOpEqual,
+			Value:    taint.Value,
+			Effect:   taint.Effect,
+		})
+	}
+	if len(tolerations) == 0 {
+		return nil
+	}
+	return tolerations
+}
+
+func isKubernetesTaint(key string) bool {
+	for _, prefix := range kubernetesTaintPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func tolerationsEqual(a, b []v1.Toleration) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
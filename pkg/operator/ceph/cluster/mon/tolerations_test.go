@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMonTolerations(t *testing.T) {
+	c := newCluster(&clusterd.Context{}, "ns", false, true, v1.ResourceRequirements{})
+	node := &v1.Node{}
+
+	// no taints and no placement tolerations
+	assert.Nil(t, c.monTolerations(node))
+
+	// the kubernetes node lifecycle taints and the soft taints are not tolerated
+	node.Spec.Taints = []v1.Taint{
+		{Key: "node.kubernetes.io/unreachable", Effect: v1.TaintEffectNoExecute},
+		{Key: "node.cloudprovider.kubernetes.io/uninitialized", Value: "true", Effect: v1.TaintEffectNoSchedule},
+		{Key: "soft", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	assert.Nil(t, c.monTolerations(node))
+
+	// a taint added by the admin is tolerated
+	node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: "storage", Value: "ceph", Effect: v1.TaintEffectNoSchedule})
+	assert.Equal(t, []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpEqual, Value: "ceph", Effect: v1.TaintEffectNoSchedule}}, c.monTolerations(node))
+
+	// the taint of a fenced mon is not tolerated
+	tainted := node.DeepCopy()
+	tainted.Spec.Taints = append(tainted.Spec.Taints, v1.Taint{Key: monFencedTaintKey, Value: "a", Effect: v1.TaintEffectNoSchedule})
+	assert.Equal(t, c.monTolerations(node), c.monTolerations(tainted))
+
+	// the placement tolerations are kept and a taint they already tolerate is not added again
+	placement := []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpExists}}
+	c.spec.Placement = rookalpha.PlacementSpec{cephv1.KeyMon: rookalpha.Placement{Tolerations: placement}}
+	assert.Equal(t, placement, c.monTolerations(node))
+}
+
+func TestSyncMonTolerations(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	clientset := test.New(2)
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: &exectest.MockExecutor{}}, "ns", false, true, v1.ResourceRequirements{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	s := &monTolerationSynchronizer{cluster: c}
+
+	// mon a is on node0 and mon b on node1
+	a := c.newMonConfig(0)
+	b := c.newMonConfig(1)
+	c.mapping.Node[a.DaemonName] = &NodeInfo{Name: "node0"}
+	c.mapping.Node[b.DaemonName] = &NodeInfo{Name: "node1"}
+	assert.Nil(t, c.startMon(a, "node0"))
+	assert.Nil(t, c.startMon(b, "node1"))
+	assert.True(t, s.hostsMon("node0"))
+	assert.False(t, s.hostsMon("node2"))
+
+	// nothing is updated while the nodes have no taints
+	updated, err := s.sync()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(updated))
+	assert.Equal(t, 0, len(*deploymentsUpdated))
+
+	// a taint added to node0 is tolerated by mon a only
+	oldNode, err := clientset.CoreV1().Nodes().Get("node0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	newNode := oldNode.DeepCopy()
+	newNode.Spec.Taints = []v1.Taint{{Key: "storage", Value: "ceph", Effect: v1.TaintEffectNoSchedule}}
+	_, err = clientset.CoreV1().Nodes().Update(newNode)
+	assert.Nil(t, err)
+	s.onNodeUpdate(oldNode, newNode)
+	assert.ElementsMatch(t, []string{a.ResourceName}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+	d := (*deploymentsUpdated)[0]
+	assert.Equal(t, []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpEqual, Value: "ceph", Effect: v1.TaintEffectNoSchedule}}, d.Spec.Template.Spec.Tolerations)
+
+	// the stub does not update the clientset
+	_, err = clientset.AppsV1().Deployments(c.Namespace).Update(d)
+	assert.Nil(t, err)
+	*deploymentsUpdated = []*apps.Deployment{}
+
+	// an update without a taint change is ignored
+	s.onNodeUpdate(newNode, newNode)
+	assert.Equal(t, 0, len(*deploymentsUpdated))
+
+	// the toleration is removed when the taint is removed
+	_, err = clientset.CoreV1().Nodes().Update(oldNode)
+	assert.Nil(t, err)
+	s.onNodeUpdate(newNode, oldNode)
+	assert.ElementsMatch(t, []string{a.ResourceName}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
+	assert.Nil(t, (*deploymentsUpdated)[0].Spec.Template.Spec.Tolerations)
+
+	// the mons are not synced before the cluster info is loaded
+	c.ClusterInfo = nil
+	updated, err = s.sync()
+	assert.Nil(t, err)
+	assert.Nil(t, updated)
+}
@@ -25,10 +25,17 @@ import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	maxPerChar = 26
+	// the largest number of CPUs supported by the Linux kernel
+	maxCPUs = 8192
+
+	eventSourceComponent = "rook-ceph-operator"
 )
 
 func monInQuorum(monitor client.MonMapEntry, quorum []int) bool {
@@ -59,6 +66,30 @@ func fullNameToIndex(name string) (int, error) {
 	return id, nil
 }
 
+// cpuSetSize returns the number of CPUs in a cpuset written in the Linux list format, for example
+// "0-3,8-11"
+func cpuSetSize(cpuset string) (int, error) {
+	cpus := map[int]struct{}{}
+	for _, cpuRange := range strings.Split(cpuset, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(cpuRange), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 || first >= maxCPUs {
+			return 0, fmt.Errorf("invalid cpu %q in cpuset %q", bounds[0], cpuset)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first || last >= maxCPUs {
+				return 0, fmt.Errorf("invalid cpu range %q in cpuset %q", cpuRange, cpuset)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus[cpu] = struct{}{}
+		}
+	}
+	return len(cpus), nil
+}
+
 // addServicePort adds a port to a service
 func addServicePort(service *v1.Service, name string, port int32) {
 	if port == 0 {
@@ -73,7 +104,7 @@ func addServicePort(service *v1.Service, name string, port int32) {
 }
 
 // addContainerPort adds a port to a container
-func addContainerPort(container v1.Container, name string, port int32) {
+func addContainerPort(container *v1.Container, name string, port int32) {
 	if port == 0 {
 		return
 	}
@@ -83,3 +114,25 @@ func addContainerPort(container v1.Container, name string, port int32) {
 		Protocol:      v1.ProtocolTCP,
 	})
 }
+
+// recordEvent records a Kubernetes event on the CephCluster that owns the mons. The recorder is
+// created the first time an event is recorded.
+func (c *Cluster) recordEvent(eventType, reason, message string) {
+	if c.recorder == nil {
+		if c.context.Clientset == nil {
+			return
+		}
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.context.Clientset.CoreV1().Events(c.Namespace)})
+		c.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventSourceComponent})
+	}
+
+	ref := &v1.ObjectReference{
+		APIVersion: c.ownerRef.APIVersion,
+		Kind:       c.ownerRef.Kind,
+		Name:       c.ownerRef.Name,
+		UID:        c.ownerRef.UID,
+		Namespace:  c.Namespace,
+	}
+	c.recorder.Event(ref, eventType, reason, message)
+}
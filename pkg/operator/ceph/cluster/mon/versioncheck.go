@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+// monSpecCapability is a mon setting that only has an effect from a ceph version on
+type monSpecCapability struct {
+	// field is the name of the setting in the cluster CR
+	field string
+	// minVersion is the first ceph version that supports the setting
+	minVersion cephver.CephVersion
+	// isSet returns whether the spec uses the setting
+	isSet func(spec cephv1.MonSpec) bool
+}
+
+// monSpecCapabilities are the mon settings that depend on the ceph version. a setting that older
+// versions ignore must be added here so it is rejected instead of doing nothing.
+var monSpecCapabilities = []monSpecCapability{
+	{
+		// the mons only listen on the msgr2 port from nautilus on
+		field:      "seedMonHosts",
+		minVersion: cephver.Nautilus,
+		isSet: func(spec cephv1.MonSpec) bool {
+			for _, host := range spec.SeedMonHosts {
+				if strings.HasPrefix(host, "v2:") {
+					return true
+				}
+			}
+			return false
+		},
+	},
+}
+
+// validateMonSpecCephVersion returns an error for the first mon setting that is used in the spec
+// but is not supported by the ceph version
+func validateMonSpecCephVersion(spec cephv1.MonSpec, cephVersion cephver.CephVersion) error {
+	for _, capability := range monSpecCapabilities {
+		if capability.isSet(spec) && !cephVersion.IsAtLeast(capability.minVersion) {
+			return fmt.Errorf("mon setting %s requires ceph %s or newer, but the ceph version is %s",
+				capability.field, capability.minVersion.ReleaseName(), cephVersion.String())
+		}
+	}
+	return nil
+}
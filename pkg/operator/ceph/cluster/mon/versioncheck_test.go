@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMonSpecCephVersion(t *testing.T) {
+	msgr2Seeds := cephv1.MonSpec{SeedMonHosts: []string{"10.0.0.1:6789", "v2:10.0.0.2:3300"}}
+	msgr1Seeds := cephv1.MonSpec{SeedMonHosts: []string{"10.0.0.1:6789", "v1:10.0.0.2:6789"}}
+	tests := []struct {
+		name        string
+		spec        cephv1.MonSpec
+		cephVersion cephver.CephVersion
+		valid       bool
+	}{
+		{"default spec on mimic", cephv1.MonSpec{Count: 3}, cephver.Mimic, true},
+		{"msgr2 seeds on mimic", msgr2Seeds, cephver.Mimic, false},
+		{"msgr2 seeds on mimic point release", msgr2Seeds, cephver.CephVersion{Major: 13, Minor: 2, Extra: 6}, false},
+		{"msgr1 seeds on mimic", msgr1Seeds, cephver.Mimic, true},
+		{"msgr2 seeds on nautilus", msgr2Seeds, cephver.Nautilus, true},
+		{"msgr2 seeds on octopus", msgr2Seeds, cephver.Octopus, true},
+	}
+	for _, test := range tests {
+		err := validateMonSpecCephVersion(test.spec, test.cephVersion)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the detail of the MON_DISK_LOW and MON_DISK_CRIT health checks, e.g. "mon.a has 22% avail"
+var monDiskAvailRegex = regexp.MustCompile(`^mon\.(\S+) has (\d+)% avail`)
+
+// checkMonPVCSize expands the mon PVCs that are running out of space if enabled in the mon spec
+func (c *Cluster) checkMonPVCSize(ctx context.Context) {
+	if c.spec.Mon.VolumeClaimTemplate == nil || c.spec.Mon.VolumeExpansionMinFreePercent <= 0 {
+		return
+	}
+	if err := reconcileMonPVCSize(ctx, c, c.spec.Mon.VolumeExpansionMinFreePercent); err != nil {
+		logger.Warningf("failed to expand the mon pvcs. %+v", err)
+	}
+}
+
+// reconcileMonPVCSize doubles the requested size of the PVC of each mon with less than minFreePercent
+// of its data volume free. The free space is read from the mon disk health checks, which ceph only
+// raises when the free space drops below mon_data_avail_warn (30% by default).
+func reconcileMonPVCSize(ctx context.Context, cluster *Cluster, minFreePercent int) error {
+	health, err := client.HealthDetail(cluster.context, cluster.ClusterInfo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get the mon disk usage. %+v", err)
+	}
+
+	avail := monDiskAvailPercent(health)
+	names := []string{}
+	for name := range avail {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if avail[name] >= minFreePercent {
+			continue
+		}
+		logger.Infof("mon %s has %d%% free space which is less than %d%%", name, avail[name], minFreePercent)
+		if err := cluster.expandMonPVC(name); err != nil {
+			return fmt.Errorf("failed to expand the pvc of mon %s. %+v", name, err)
+		}
+	}
+	return nil
+}
+
+// monDiskAvailPercent returns the percentage of free space of the data volume of each mon that ceph
+// reports as low on space
+func monDiskAvailPercent(health client.HealthStatus) map[string]int {
+	avail := map[string]int{}
+	for _, check := range []string{"MON_DISK_LOW", "MON_DISK_CRIT"} {
+		for _, detail := range health.Checks[check].Detail {
+			match := monDiskAvailRegex.FindStringSubmatch(detail.Message)
+			if match == nil {
+				continue
+			}
+			percent, err := strconv.Atoi(match[2])
+			if err != nil {
+				continue
+			}
+			avail[match[1]] = percent
+		}
+	}
+	return avail
+}
+
+// expandMonPVC doubles the requested storage of the mon PVC. The PVC is the data volume of the mon
+// deployment, which is not named after the mon if the mon store was moved to another PVC. The storage
+// class of the PVC must allow volume expansion. Nothing is done if the mon does not run on a PVC or if
+// a previous expansion is still in progress.
+func (c *Cluster) expandMonPVC(name string) error {
+	d, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Get(resourceName(name), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Debugf("mon %s has no deployment", name)
+			return nil
+		}
+		return fmt.Errorf("failed to get deployment. %+v", err)
+	}
+	volume, ok := monDataVolume(d)
+	if !ok || volume.PersistentVolumeClaim == nil {
+		logger.Debugf("mon %s does not run on a pvc", name)
+		return nil
+	}
+
+	pvc, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(c.Namespace).Get(volume.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get pvc %s. %+v", volume.PersistentVolumeClaim.ClaimName, err)
+	}
+
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return fmt.Errorf("pvc %s has no storage class", pvc.Name)
+	}
+	storageClass, err := c.context.Clientset.StorageV1().StorageClasses().Get(*pvc.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get storage class %s. %+v", *pvc.Spec.StorageClassName, err)
+	}
+	if storageClass.AllowVolumeExpansion == nil || !*storageClass.AllowVolumeExpansion {
+		return fmt.Errorf("storage class %s of pvc %s does not allow volume expansion", storageClass.Name, pvc.Name)
+	}
+
+	requested := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity, ok := pvc.Status.Capacity[v1.ResourceStorage]
+	if ok && capacity.Cmp(requested) < 0 {
+		logger.Infof("pvc %s of mon %s is already being expanded from %s to %s", pvc.Name, name, capacity.String(), requested.String())
+		return nil
+	}
+
+	expanded := resource.NewQuantity(requested.Value()*2, requested.Format)
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = v1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[v1.ResourceStorage] = *expanded
+	if _, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(c.Namespace).Update(pvc); err != nil {
+		return fmt.Errorf("failed to update pvc %s. %+v", pvc.Name, err)
+	}
+	logger.Infof("expanding pvc %s of mon %s from %s to %s", pvc.Name, name, requested.String(), expanded.String())
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testMonDiskHealthDetail = `{"status":"HEALTH_WARN","checks":{
+	"MON_DISK_LOW":{"severity":"HEALTH_WARN","summary":{"message":"mons a,b are low on available space"},
+		"detail":[{"message":"mon.a has 15% avail"},{"message":"mon.b has 25% avail"}]},
+	"MON_DISK_CRIT":{"severity":"HEALTH_ERR","summary":{"message":"mon c is very low on available space"},
+		"detail":[{"message":"mon.c has 4% avail"}]}}}`
+
+func TestReconcileMonPVCSize(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if len(args) > 1 && args[0] == "health" && args[1] == "detail" {
+				return testMonDiskHealthDetail, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	clientset := test.New(1)
+	c := newCluster(&clusterd.Context{Clientset: clientset, Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+
+	allowExpansion := true
+	storageClass := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "expandable"}, AllowVolumeExpansion: &allowExpansion}
+	_, err := clientset.StorageV1().StorageClasses().Create(storageClass)
+	assert.NoError(t, err)
+	className := "expandable"
+	createPVC := func(claimName string) {
+		pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: "ns"}}
+		pvc.Spec.StorageClassName = &className
+		pvc.Spec.Resources.Requests = v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")}
+		pvc.Status.Capacity = v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")}
+		_, err := clientset.CoreV1().PersistentVolumeClaims("ns").Create(pvc)
+		assert.NoError(t, err)
+	}
+	createDeployment := func(name string, volume v1.Volume) {
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: resourceName(name), Namespace: "ns"}}
+		d.Spec.Template.Spec.Volumes = []v1.Volume{volume}
+		_, err := clientset.AppsV1().Deployments("ns").Create(d)
+		assert.NoError(t, err)
+	}
+	for _, name := range []string{"a", "b"} {
+		createPVC(resourceName(name))
+		createDeployment(name, opspec.DaemonVolumesDataPVC(resourceName(name)))
+	}
+	createDeployment("c", v1.Volume{Name: monDataVolumeName, VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/lib/rook/mon-c"}}})
+	pvcSize := func(claimName string) string {
+		pvc, err := clientset.CoreV1().PersistentVolumeClaims("ns").Get(claimName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		size := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+		return size.String()
+	}
+
+	// only mon a is below the min free space. mon c is not on a pvc.
+	err = reconcileMonPVCSize(context.Background(), c, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, "20Gi", pvcSize(resourceName("a")))
+	assert.Equal(t, "10Gi", pvcSize(resourceName("b")))
+
+	// the pvc is not expanded again while the expansion is in progress
+	err = reconcileMonPVCSize(context.Background(), c, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, "20Gi", pvcSize(resourceName("a")))
+
+	// the pvc the mon store was moved to is expanded instead of the previous pvc of the mon
+	createPVC("rook-ceph-mon-a-swapped")
+	assert.NoError(t, clientset.AppsV1().Deployments("ns").Delete(resourceName("a"), &metav1.DeleteOptions{}))
+	createDeployment("a", opspec.DaemonVolumesDataPVC("rook-ceph-mon-a-swapped"))
+	err = reconcileMonPVCSize(context.Background(), c, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, "20Gi", pvcSize("rook-ceph-mon-a-swapped"))
+	assert.Equal(t, "20Gi", pvcSize(resourceName("a")))
+
+	// the storage class must allow the expansion
+	allowExpansion = false
+	_, err = clientset.StorageV1().StorageClasses().Update(storageClass)
+	assert.NoError(t, err)
+	err = reconcileMonPVCSize(context.Background(), c, 30)
+	assert.Error(t, err)
+	assert.Equal(t, "10Gi", pvcSize(resourceName("b")))
+
+	// nothing is done when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, reconcileMonPVCSize(ctx, c, 30))
+}
+
+func TestMonDiskAvailPercent(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return testMonDiskHealthDetail, nil
+		},
+	}
+	c := newCluster(&clusterd.Context{Executor: executor}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(3)
+	health, err := client.HealthDetail(c.context, c.ClusterInfo.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 15, "b": 25, "c": 4}, monDiskAvailPercent(health))
+}
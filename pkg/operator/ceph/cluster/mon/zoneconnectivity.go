@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	monZoneProbeAppName = "rook-ceph-mon-zone-probe"
+	// the zone connectivity check runs a job for each mon, so it runs less often than the health check
+	monZoneProbeInterval = 15 * time.Minute
+	// the health check waits for the probe jobs, so a job that does not complete quickly is given up
+	// and the check is stopped after a few jobs
+	monZoneProbeJobTimeout = time.Minute
+	monZoneProbeTimeout    = 3 * time.Minute
+)
+
+// ConnectivityIssue is a mon that cannot reach a mon in another zone
+type ConnectivityIssue struct {
+	From     string `json:"from"`
+	FromZone string `json:"fromZone"`
+	To       string `json:"to"`
+	ToZone   string `json:"toZone"`
+}
+
+// probeMonsFromMonNode connects to the mon endpoints from the node of the given mon and returns
+// whether each mon could be reached. It is a variable so the unit tests do not need to run a probe
+// pod.
+var probeMonsFromMonNode = runMonZoneProbe
+
+// checkMonInterZoneConnectivity checks that each mon can reach the mons in the other zones and
+// returns the pairs of mons that cannot reach each other, with a warning event for each pair. The
+// mons cannot be asked to ping each other, so the endpoints of the mons in the other zones are probed
+// from the node of each mon with the network of the mons.
+func checkMonInterZoneConnectivity(ctx context.Context, cluster *Cluster) ([]ConnectivityIssue, error) {
+	zones := map[string]string{}
+	for name := range cluster.ClusterInfo.Monitors {
+		if node, ok := cluster.mapping.Node[name]; ok && node != nil && node.Zone != "" {
+			zones[name] = node.Zone
+		}
+	}
+	names := []string{}
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	issues := []ConnectivityIssue{}
+	for _, from := range names {
+		if err := ctx.Err(); err != nil {
+			return issues, err
+		}
+		endpoints := map[string]string{}
+		for _, to := range names {
+			if zones[to] != zones[from] {
+				endpoints[to] = cluster.ClusterInfo.Monitors[to].Endpoint
+			}
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		reachable, err := probeMonsFromMonNode(cluster, from, endpoints)
+		if err != nil {
+			return issues, fmt.Errorf("failed to probe the mons in the other zones from mon %s. %+v", from, err)
+		}
+		for _, to := range names {
+			if _, ok := endpoints[to]; !ok || reachable[to] {
+				continue
+			}
+			issue := ConnectivityIssue{From: from, FromZone: zones[from], To: to, ToZone: zones[to]}
+			msg := fmt.Sprintf("mon %s in zone %s cannot reach mon %s in zone %s", from, issue.FromZone, to, issue.ToZone)
+			logger.Warningf(msg)
+			cluster.recordEvent(v1.EventTypeWarning, "MonZoneUnreachable", msg)
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// checkMonZoneConnectivity runs the zone connectivity check if it did not run within the interval
+func (c *Cluster) checkMonZoneConnectivity() {
+	if time.Since(c.lastZoneProbe) < monZoneProbeInterval {
+		return
+	}
+	c.lastZoneProbe = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), monZoneProbeTimeout)
+	defer cancel()
+	if _, err := checkMonInterZoneConnectivity(ctx, c); err != nil {
+		logger.Warningf("failed to check the connectivity of the mons between the zones. %+v", err)
+	}
+}
+
+// runMonZoneProbe runs the mon probe job on the node of the mon with the network of the mons
+func runMonZoneProbe(c *Cluster, name string, endpoints map[string]string) (map[string]bool, error) {
+	job, err := c.makeMonZoneProbeJob(name, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return runMonProbeJob(c, job, monZoneProbeJobTimeout)
+}
+
+func (c *Cluster) makeMonZoneProbeJob(name string, endpoints map[string]string) (*batch.Job, error) {
+	node, ok := c.mapping.Node[name]
+	if !ok || node == nil {
+		return nil, fmt.Errorf("mon %s is not assigned to a node", name)
+	}
+	job, err := c.makeMonClientProbeJob(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	job.Name = fmt.Sprintf("%s-%s", monZoneProbeAppName, name)
+	job.Labels[k8sutil.AppAttr] = monZoneProbeAppName
+	job.Spec.Template.Labels[k8sutil.AppAttr] = monZoneProbeAppName
+	podSpec := &job.Spec.Template.Spec
+	podSpec.NodeSelector = map[string]string{v1.LabelHostname: node.Hostname}
+	podSpec.HostNetwork = c.HostNetwork
+	podSpec.Tolerations = cephv1.GetMonPlacement(c.spec.Placement).Tolerations
+	return job, nil
+}
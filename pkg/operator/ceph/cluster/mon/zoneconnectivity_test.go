@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestCheckMonInterZoneConnectivity(t *testing.T) {
+	defer func() { probeMonsFromMonNode = runMonZoneProbe }()
+
+	c := newCluster(&clusterd.Context{Clientset: test.New(3)}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(4)
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0", Hostname: "node0", Zone: "zone0"}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "node1", Zone: "zone0"}
+	c.mapping.Node["c"] = &NodeInfo{Name: "node2", Hostname: "node2", Zone: "zone1"}
+	// mon d is not assigned to a zone
+	c.mapping.Node["d"] = &NodeInfo{Name: "node3", Hostname: "node3"}
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	// each mon probes the mons in the other zones
+	probed := map[string][]string{}
+	probeMonsFromMonNode = func(c *Cluster, from string, endpoints map[string]string) (map[string]bool, error) {
+		reachable := map[string]bool{}
+		for name := range endpoints {
+			probed[from] = append(probed[from], name)
+			reachable[name] = true
+		}
+		return reachable, nil
+	}
+	issues, err := checkMonInterZoneConnectivity(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	assert.Equal(t, map[string][]string{"a": {"c"}, "b": {"c"}, "c": {"a", "b"}}, sortedValues(probed))
+	assert.Empty(t, recorder.Events)
+
+	// zone1 cannot reach mon b
+	probeMonsFromMonNode = func(c *Cluster, from string, endpoints map[string]string) (map[string]bool, error) {
+		reachable := map[string]bool{}
+		for name := range endpoints {
+			reachable[name] = !(from == "c" && name == "b")
+		}
+		return reachable, nil
+	}
+	issues, err = checkMonInterZoneConnectivity(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConnectivityIssue{{From: "c", FromZone: "zone1", To: "b", ToZone: "zone0"}}, issues)
+	assert.Equal(t, 1, len(recorder.Events))
+	assert.Contains(t, <-recorder.Events, "Warning MonZoneUnreachable mon c in zone zone1 cannot reach mon b in zone zone0")
+
+	// a failed probe
+	probeMonsFromMonNode = func(c *Cluster, from string, endpoints map[string]string) (map[string]bool, error) {
+		return nil, fmt.Errorf("job failed")
+	}
+	_, err = checkMonInterZoneConnectivity(context.Background(), c)
+	assert.Error(t, err)
+
+	// the check stops with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = checkMonInterZoneConnectivity(ctx, c)
+	assert.Error(t, err)
+
+	// mons in a single zone are not probed
+	probed = map[string][]string{}
+	probeMonsFromMonNode = func(c *Cluster, from string, endpoints map[string]string) (map[string]bool, error) {
+		probed[from] = []string{}
+		return map[string]bool{}, nil
+	}
+	c.mapping.Node["c"].Zone = "zone0"
+	issues, err = checkMonInterZoneConnectivity(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	assert.Empty(t, probed)
+}
+
+func TestCheckMonZoneConnectivityInterval(t *testing.T) {
+	defer func() { probeMonsFromMonNode = runMonZoneProbe }()
+
+	c := newCluster(&clusterd.Context{Clientset: test.New(3)}, "ns", false, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(2)
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0", Hostname: "node0", Zone: "zone0"}
+	c.mapping.Node["b"] = &NodeInfo{Name: "node1", Hostname: "node1", Zone: "zone1"}
+	probes := 0
+	probeMonsFromMonNode = func(c *Cluster, from string, endpoints map[string]string) (map[string]bool, error) {
+		probes++
+		return map[string]bool{}, nil
+	}
+
+	// the check only runs once within the interval
+	c.checkMonZoneConnectivity()
+	c.checkMonZoneConnectivity()
+	assert.Equal(t, 2, probes)
+
+	c.lastZoneProbe = time.Now().Add(-monZoneProbeInterval)
+	c.checkMonZoneConnectivity()
+	assert.Equal(t, 4, probes)
+}
+
+func TestMakeMonZoneProbeJob(t *testing.T) {
+	c := newCluster(&clusterd.Context{Clientset: test.New(3)}, "ns", true, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(2)
+	c.mapping.Node["a"] = &NodeInfo{Name: "node0", Hostname: "node0-host", Zone: "zone0"}
+
+	// the probe runs on the node of the mon with the network of the mons
+	job, err := c.makeMonZoneProbeJob("a", map[string]string{"b": "1.2.3.2:6789"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rook-ceph-mon-zone-probe-a", job.Name)
+	assert.Equal(t, monZoneProbeAppName, job.Spec.Template.Labels["app"])
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, map[string]string{v1.LabelHostname: "node0-host"}, podSpec.NodeSelector)
+	assert.True(t, podSpec.HostNetwork)
+	assert.Contains(t, podSpec.Containers[0].Args[1], "/dev/tcp/1.2.3.2/6789")
+
+	// the job is not made without a node of the mon
+	_, err = c.makeMonZoneProbeJob("b", map[string]string{"a": "1.2.3.1:6789"})
+	assert.Error(t, err)
+}
+
+// sortedValues returns the map with its values sorted
+func sortedValues(m map[string][]string) map[string][]string {
+	sorted := map[string][]string{}
+	for key, values := range m {
+		sorted[key] = append([]string{}, values...)
+		sort.Strings(sorted[key])
+	}
+	return sorted
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sort"
+)
+
+// trackDisplacedMon remembers the zone the new mon was meant to be placed in when it was placed in
+// another zone, e.g. because the zone was down when the mon was failed over. The mon is moved back
+// to the zone by returnMonToRecoveredZone once the zone recovers.
+func (c *Cluster) trackDisplacedMon(name, zone string) {
+	if !c.spec.Mon.RebalanceOnZoneRecovery || zone == "" {
+		return
+	}
+	if c.monZone(name) == zone {
+		return
+	}
+	logger.Infof("mon %s was placed outside of zone %s and will be moved back when the zone recovers", name, zone)
+	c.displacedMons[name] = zone
+}
+
+// returnMonToRecoveredZone fails over one displaced mon to the zone it was moved out of if the zone
+// has a valid node again and has at least two mons less than the zone the mon is in now. Returns
+// whether a mon was moved.
+func (c *Cluster) returnMonToRecoveredZone() (bool, error) {
+	if !c.spec.Mon.RebalanceOnZoneRecovery || len(c.displacedMons) == 0 {
+		return false, nil
+	}
+
+	nodeZones, err := c.getNodeMonUsage()
+	if err != nil {
+		return false, fmt.Errorf("failed to get node monitor usage. %+v", err)
+	}
+	zoneMonCount := map[string]int{}
+	zoneValid := map[string]bool{}
+	for zi := range nodeZones {
+		for _, nodeUsage := range nodeZones[zi] {
+			zone := nodeUsage.Node.Labels[zoneLabel]
+			zoneMonCount[zone] += nodeUsage.MonCount
+			if nodeUsage.MonValid {
+				zoneValid[zone] = true
+			}
+		}
+	}
+
+	// check the mons in a stable order
+	names := []string{}
+	for name := range c.displacedMons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		zone := c.displacedMons[name]
+		if _, ok := c.mapping.Node[name]; !ok {
+			delete(c.displacedMons, name)
+			continue
+		}
+		if !zoneValid[zone] {
+			logger.Debugf("rebalance: zone %s of mon %s has not recovered yet", zone, name)
+			continue
+		}
+		currentZone := c.monZone(name)
+		if currentZone == zone || zoneMonCount[zone]+1 >= zoneMonCount[currentZone] {
+			// moving the mon back would not spread the mons better
+			logger.Infof("rebalance: zone %s recovered and mon %s does not need to move back", zone, name)
+			delete(c.displacedMons, name)
+			continue
+		}
+
+		logger.Infof("rebalance: zone %s recovered, moving mon %s back from zone %s", zone, name, currentZone)
+		delete(c.displacedMons, name)
+		if _, err := c.failoverMonToZone(name, zone); err != nil {
+			return true, fmt.Errorf("failed to move mon %s back to zone %s. %+v", name, zone, err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
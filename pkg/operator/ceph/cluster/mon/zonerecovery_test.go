@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newZoneRecoveryTestCluster creates a cluster with the mons a, b and c on the nodes node0, node1
+// and node2, each in its own zone
+func newZoneRecoveryTestCluster(t *testing.T, rebalance bool) *Cluster {
+	updateDeploymentAndWait, _ = testopk8s.UpdateDeploymentAndWaitStub()
+
+	clientset := test.New(3)
+	for i := 0; i < 3; i++ {
+		node, err := clientset.CoreV1().Nodes().Get(fmt.Sprintf("node%d", i), metav1.GetOptions{})
+		assert.NoError(t, err)
+		node.Labels = map[string]string{zoneLabel: fmt.Sprintf("zone%d", i)}
+		_, err = clientset.CoreV1().Nodes().Update(node)
+		assert.NoError(t, err)
+	}
+
+	var c *Cluster
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponseFromMons(c.ClusterInfo.Monitors), nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	context := &clusterd.Context{
+		Clientset: clientset,
+		ConfigDir: configDir,
+		Executor:  executor,
+	}
+	c = New(context, "ns", "", false, metav1.OwnerReference{}, &sync.Mutex{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "myversion")
+	c.spec.Mon.RebalanceOnZoneRecovery = rebalance
+	c.waitForStart = false
+	for i, name := range []string{"a", "b", "c"} {
+		nodeName := fmt.Sprintf("node%d", i)
+		c.mapping.Node[name] = &NodeInfo{Name: nodeName, Hostname: nodeName, Zone: fmt.Sprintf("zone%d", i)}
+	}
+	c.maxMonID = 2
+	return c
+}
+
+func setNodeReady(t *testing.T, clientset kubernetes.Interface, name string, ready bool) {
+	node, err := clientset.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: status}}
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.NoError(t, err)
+}
+
+func TestReturnMonToRecoveredZone(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, true)
+	defer os.RemoveAll(c.context.ConfigDir)
+
+	// zone2 goes down and its mon is failed over to another zone
+	setNodeReady(t, c.context.Clientset, "node2", false)
+	err := c.failoverMon("c")
+	assert.NoError(t, err)
+	assert.NotContains(t, c.mapping.Node, "c")
+	assert.NotEqual(t, "zone2", c.monZone("d"))
+	assert.Equal(t, map[string]string{"d": "zone2"}, c.displacedMons)
+
+	// the mon stays while the zone is down
+	moved, err := c.returnMonToRecoveredZone()
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	assert.Contains(t, c.mapping.Node, "d")
+
+	// the mon returns to the zone after it recovered
+	setNodeReady(t, c.context.Clientset, "node2", true)
+	moved, err = c.returnMonToRecoveredZone()
+	assert.NoError(t, err)
+	assert.True(t, moved)
+	assert.NotContains(t, c.mapping.Node, "d")
+	assert.Equal(t, "node2", c.mapping.Node["e"].Name)
+	assert.Contains(t, c.mapping.Node, "a")
+	assert.Contains(t, c.mapping.Node, "b")
+	assert.Empty(t, c.displacedMons)
+
+	// only a single move is made
+	moved, err = c.returnMonToRecoveredZone()
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	assert.Equal(t, 4, c.maxMonID)
+}
+
+func TestReturnMonToRecoveredZoneDisabled(t *testing.T) {
+	c := newZoneRecoveryTestCluster(t, false)
+	defer os.RemoveAll(c.context.ConfigDir)
+
+	setNodeReady(t, c.context.Clientset, "node2", false)
+	err := c.failoverMon("c")
+	assert.NoError(t, err)
+	assert.Empty(t, c.displacedMons)
+
+	setNodeReady(t, c.context.Clientset, "node2", true)
+	moved, err := c.returnMonToRecoveredZone()
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	assert.Contains(t, c.mapping.Node, "d")
+}
@@ -79,6 +79,7 @@ type Cluster struct {
 	resources       v1.ResourceRequirements
 	ownerRef        metav1.OwnerReference
 	kv              *k8sutil.ConfigMapKVStore
+	monHealthGate   mon.MonHealthGate
 }
 
 // New creates an instance of the OSD manager
@@ -95,6 +96,7 @@ func New(
 	hostNetwork bool,
 	resources v1.ResourceRequirements,
 	ownerRef metav1.OwnerReference,
+	monHealthGate mon.MonHealthGate,
 ) *Cluster {
 	return &Cluster{
 		clusterInfo:     clusterInfo,
@@ -110,6 +112,7 @@ func New(
 		resources:       resources,
 		ownerRef:        ownerRef,
 		kv:              k8sutil.NewConfigMapKVStore(namespace, context.Clientset, ownerRef),
+		monHealthGate:   monHealthGate,
 	}
 }
 
@@ -159,6 +162,11 @@ func (c *Cluster) Start() error {
 		return fmt.Errorf("%v", err)
 	}
 
+	// the osds cannot be provisioned until the mons are in quorum
+	if err := mon.WaitForMonHealthGate(c.monHealthGate); err != nil {
+		return fmt.Errorf("failed to wait for the mons before starting the osds. %+v", err)
+	}
+
 	logger.Infof("start running osds in namespace %s", c.Namespace)
 
 	if c.DesiredStorage.UseAllNodes == false && len(c.DesiredStorage.Nodes) == 0 && len(c.DesiredStorage.VolumeSources) == 0 && len(c.DesiredStorage.StorageClassDeviceSets) == 0 {
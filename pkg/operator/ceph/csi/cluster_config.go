@@ -141,6 +141,38 @@ func CreateCsiConfigMap(namespace string, clientset kubernetes.Interface) error
 	return nil
 }
 
+// GetClusterMonitors returns the mon endpoints of the cluster in the config map used to provide
+// ceph-csi with basic cluster configuration. No endpoints are returned if the config map has no
+// entry for the cluster.
+func GetClusterMonitors(clientset kubernetes.Interface, clusterNamespace string) ([]string, error) {
+	// csi is deployed into the same namespace as the operator
+	csiNamespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	if csiNamespace == "" {
+		return nil, fmt.Errorf("namespace value missing (for %+v)",
+			k8sutil.PodNamespaceEnvVar)
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(csiNamespace).Get(
+		ConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current csi config map: %+v", err)
+	}
+	currData := configMap.Data[ConfigKey]
+	if currData == "" {
+		return nil, nil
+	}
+	cc, err := parseCsiClusterConfig(currData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current csi cluster config. %+v", err)
+	}
+	for _, centry := range cc {
+		if centry.ClusterID == clusterNamespace {
+			return centry.Monitors, nil
+		}
+	}
+	return nil, nil
+}
+
 // SaveClusterConfig updates the config map used to provide ceph-csi with
 // basic cluster configuration. The clusterNamespace and clusterInfo are
 // used to determine what "cluster" in the config map will be updated and
@@ -27,6 +27,7 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
@@ -53,6 +54,7 @@ type FilesystemController struct {
 	clusterSpec        *cephv1.ClusterSpec
 	ownerRef           metav1.OwnerReference
 	dataDirHostPath    string
+	monHealthGate      mon.MonHealthGate
 	orchestrationMutex sync.Mutex
 }
 
@@ -65,6 +67,7 @@ func NewFilesystemController(
 	clusterSpec *cephv1.ClusterSpec,
 	ownerRef metav1.OwnerReference,
 	dataDirHostPath string,
+	monHealthGate mon.MonHealthGate,
 ) *FilesystemController {
 	return &FilesystemController{
 		clusterInfo:     clusterInfo,
@@ -74,6 +77,7 @@ func NewFilesystemController(
 		clusterSpec:     clusterSpec,
 		ownerRef:        ownerRef,
 		dataDirHostPath: dataDirHostPath,
+		monHealthGate:   monHealthGate,
 	}
 }
 
@@ -102,6 +106,12 @@ func (c *FilesystemController) onAdd(obj interface{}) {
 	c.acquireOrchestrationLock()
 	defer c.releaseOrchestrationLock()
 
+	// the mds and the filesystem pools cannot be created until the mons are in quorum
+	if err := mon.WaitForMonHealthGate(c.monHealthGate); err != nil {
+		logger.Errorf("failed to wait for the mons before creating filesystem %s. %+v", filesystem.Name, err)
+		return
+	}
+
 	err = createFilesystem(c.clusterInfo, c.context, *filesystem, c.rookVersion, c.clusterSpec, c.filesystemOwners(filesystem), c.clusterSpec.DataDirHostPath)
 	if err != nil {
 		logger.Errorf("failed to create filesystem %s: %+v", filesystem.Name, err)
@@ -128,6 +138,11 @@ func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 	c.acquireOrchestrationLock()
 	defer c.releaseOrchestrationLock()
 
+	if err := mon.WaitForMonHealthGate(c.monHealthGate); err != nil {
+		logger.Errorf("failed to wait for the mons before updating filesystem %s. %+v", newFS.Name, err)
+		return
+	}
+
 	// if the filesystem is modified, allow the filesystem to be created if it wasn't already
 	logger.Infof("updating filesystem %s", newFS.Name)
 	err = createFilesystem(c.clusterInfo, c.context, *newFS, c.rookVersion, c.clusterSpec, c.filesystemOwners(newFS), c.clusterSpec.DataDirHostPath)
@@ -26,6 +26,7 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	daemonconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	cephconfig "github.com/rook/rook/pkg/operator/ceph/config"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -53,6 +54,7 @@ type ObjectStoreController struct {
 	rookImage          string
 	ownerRef           metav1.OwnerReference
 	dataDirHostPath    string
+	monHealthGate      mon.MonHealthGate
 	orchestrationMutex sync.Mutex
 }
 
@@ -65,6 +67,7 @@ func NewObjectStoreController(
 	clusterSpec *cephv1.ClusterSpec,
 	ownerRef metav1.OwnerReference,
 	dataDirHostPath string,
+	monHealthGate mon.MonHealthGate,
 ) *ObjectStoreController {
 	return &ObjectStoreController{
 		clusterInfo:     clusterInfo,
@@ -74,6 +77,7 @@ func NewObjectStoreController(
 		rookImage:       rookImage,
 		ownerRef:        ownerRef,
 		dataDirHostPath: dataDirHostPath,
+		monHealthGate:   monHealthGate,
 	}
 }
 
@@ -129,6 +133,12 @@ func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
 }
 
 func (c *ObjectStoreController) createOrUpdateStore(objectstore *cephv1.CephObjectStore) {
+	// the rgw pools cannot be created until the mons are in quorum
+	if err := mon.WaitForMonHealthGate(c.monHealthGate); err != nil {
+		logger.Errorf("failed to wait for the mons before creating object store %s. %+v", objectstore.Name, err)
+		return
+	}
+
 	logger.Infof("creating object store %s", objectstore.Name)
 	cfg := clusterConfig{
 		clusterInfo: c.clusterInfo,
@@ -38,6 +38,7 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/provisioner"
 	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/util/metrics"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/sig-storage-lib-external-provisioner/controller"
 )
@@ -61,6 +62,8 @@ var (
 	EnableFlexDriver = true
 	// Whether to enable the daemon for device discovery. If true, the rook-ceph-discover daemonset will be started.
 	EnableDiscoveryDaemon = true
+	// The port the prometheus metrics of the operator are served on. The metrics are not served if the port is 0.
+	MetricsPort = 8080
 )
 
 // Operator type for managing storage
@@ -113,6 +116,10 @@ func (o *Operator) Run() error {
 	stopChan := make(chan struct{})
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
+	if err := metrics.Serve(MetricsPort, stopChan); err != nil {
+		return fmt.Errorf("error serving the metrics. %+v", err)
+	}
+
 	// Run volume provisioner for each of the supported configurations
 	for name, vendor := range provisionerConfigs {
 		volumeProvisioner := provisioner.New(o.context, vendor)
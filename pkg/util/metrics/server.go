@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics serves the prometheus metrics of the operator.
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is the path of the http endpoint the metrics are served on
+const Path = "/metrics"
+
+// Handler returns the handler that serves the metrics registered with the default prometheus
+// registry on the metrics path
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+	return mux
+}
+
+// Serve serves the metrics on the port until the stop channel is closed. The metrics are not served
+// when the port is 0.
+func Serve(port int, stopCh chan struct{}) error {
+	if port == 0 {
+		logger.Infof("the metrics are not served")
+		return nil
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d for the metrics. %+v", port, err)
+	}
+	logger.Infof("serving the metrics on port %d", port)
+	serve(listener, stopCh)
+	return nil
+}
+
+func serve(listener net.Listener, stopCh chan struct{}) {
+	server := &http.Server{Handler: Handler()}
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("failed to serve the metrics. %+v", err)
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "rook",
+	Name:      "metrics_test_total",
+	Help:      "A counter of the metrics tests.",
+})
+
+func init() {
+	prometheus.MustRegister(testCounter)
+}
+
+func TestHandler(t *testing.T) {
+	testCounter.Add(3)
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", Path, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "rook_metrics_test_total 3")
+
+	// only the metrics path is served
+	recorder = httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServe(t *testing.T) {
+	// the metrics are not served without a port
+	assert.NoError(t, Serve(0, make(chan struct{})))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	stopCh := make(chan struct{})
+	serve(listener, stopCh)
+	resp, err := http.Get("http://" + listener.Addr().String() + Path)
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "rook_metrics_test_total")
+
+	// the server stops with the stop channel
+	close(stopCh)
+	for i := 0; i < 100; i++ {
+		if _, err = http.Get("http://" + listener.Addr().String() + Path); err != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Error(t, err)
+}